@@ -45,5 +45,5 @@ require (
 	golang.org/x/text v0.19.0 // indirect
 	google.golang.org/grpc v1.69.2
 	google.golang.org/protobuf v1.35.1
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
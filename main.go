@@ -5,13 +5,18 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
+	"github.com/abhivaikar/playpi/services/clock"
 	graphqlInventory "github.com/abhivaikar/playpi/services/graphql/inventory_management"
 	grpcInventory "github.com/abhivaikar/playpi/services/grpc/inventory_management"
 	grpcUserRegistration "github.com/abhivaikar/playpi/services/grpc/user_registration"
 	restfulInventory "github.com/abhivaikar/playpi/services/restful/inventory_management"
 	restfulTaskManagement "github.com/abhivaikar/playpi/services/restful/task_management"
+	"github.com/abhivaikar/playpi/services/restful/webhooks"
+	"github.com/abhivaikar/playpi/services/tenancy"
 	websocketLiveChat "github.com/abhivaikar/playpi/services/websocket/live_chat"
 
 	"github.com/spf13/cobra"
@@ -67,8 +72,125 @@ Available options:
 		},
 	}
 
+	var idempotencyTTL time.Duration
+	startCmd.Flags().DurationVar(&idempotencyTTL, "idempotency-ttl", 24*time.Hour, "How long Idempotency-Key headers are remembered by the RESTful playgrounds")
+	var validateRequests bool
+	startCmd.Flags().BoolVar(&validateRequests, "validate-requests", false, "Validate RESTful requests against the OpenAPI document before handling them")
+	var onDelete string
+	startCmd.Flags().StringVar(&onDelete, "on-delete", restfulInventory.DeleteRestrict, "What deleting a referenced category or supplier does in the RESTful inventory playground: restrict or cascade")
+	var reportDuration time.Duration
+	startCmd.Flags().DurationVar(&reportDuration, "report-duration", 10*time.Second, "How long asynchronous report jobs take in the RESTful inventory playground")
+	var maxTenants int
+	startCmd.Flags().IntVar(&maxTenants, "max-tenants", 100, "How many tenant sandboxes can be active at the same time, besides the default one")
+	var tenantIdleTimeout time.Duration
+	startCmd.Flags().DurationVar(&tenantIdleTimeout, "tenant-idle-timeout", 30*time.Minute, "How long an unused tenant sandbox is kept before it is discarded")
+	var trashRetention time.Duration
+	startCmd.Flags().DurationVar(&trashRetention, "trash-retention", 7*24*time.Hour, "How long deleted items and tasks stay in the trash of the RESTful playgrounds before they are purged")
+	startCmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		policy, err := restfulInventory.ParseDeletePolicy(onDelete)
+		if err != nil {
+			return err
+		}
+		restfulInventory.DeletePolicy = policy
+		restfulInventory.ReportDuration = reportDuration
+		restfulInventory.IdempotencyKeyTTL = idempotencyTTL
+		restfulTaskManagement.IdempotencyKeyTTL = idempotencyTTL
+		restfulInventory.ValidateRequests = validateRequests
+		restfulTaskManagement.ValidateRequests = validateRequests
+		restfulInventory.TrashRetention = trashRetention
+		restfulTaskManagement.TrashRetention = trashRetention
+		tenancy.MaxTenants = maxTenants
+		tenancy.IdleTimeout = tenantIdleTimeout
+		return nil
+	}
+
+	var port, status int
+	var secret string
+	// receiveWebhooksCmd represents the receive-webhooks command
+	var receiveWebhooksCmd = &cobra.Command{
+		Use:   "receive-webhooks",
+		Short: "Run a local receiver for the webhooks of the RESTful playgrounds",
+		Long: `Run a local HTTP server that accepts webhook deliveries on any path, verifies
+their X-PlayPI-Signature header when a secret is given and prints every delivery.
+Answer with a failing status to see the retries of the playgrounds.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			receiver := &webhooks.Receiver{
+				Secret: secret,
+				Status: status,
+				OnReceive: func(received webhooks.Received) {
+					signature := "not verified"
+					if secret != "" && received.SignatureValid {
+						signature = "valid signature"
+					} else if secret != "" {
+						signature = "INVALID signature"
+					}
+					fmt.Printf("%s delivery %s (%s): %s\n", received.Event, received.Delivery, signature, received.Body)
+				},
+			}
+			fmt.Printf("Receiving webhooks on http://localhost:%d\n", port)
+			return http.ListenAndServe(fmt.Sprintf(":%d", port), receiver)
+		},
+	}
+	receiveWebhooksCmd.Flags().IntVar(&port, "port", 9000, "Port to listen on")
+	receiveWebhooksCmd.Flags().StringVar(&secret, "secret", "", "Secret of the webhook subscription, used to verify signatures")
+	receiveWebhooksCmd.Flags().IntVar(&status, "status", http.StatusOK, "Status code to answer every delivery with")
+
+	var clockURL, clockTenant string
+	// clockCmd represents the clock command
+	var clockCmd = &cobra.Command{
+		Use:   "clock [show|freeze|set <time>|advance <duration>|reset]",
+		Short: "Control the clock of a running RESTful playground",
+		Long: `Show, freeze, set, advance or reset the clock of a running RESTful playground,
+to test overdue tasks, expiring reservations and other time-dependent behaviour.
+Times are RFC 3339 timestamps such as 2030-01-15T09:00:00Z or dates such as
+2030-01-15, durations look like 90m or 48h. Every tenant has its own clock.`,
+		Args: cobra.RangeArgs(0, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := &clock.Client{BaseURL: clockURL, Tenant: clockTenant}
+			action := "show"
+			if len(args) > 0 {
+				action = args[0]
+			}
+			needsValue := action == "set" || action == "advance"
+			if needsValue != (len(args) == 2) {
+				return fmt.Errorf("usage: %s", cmd.Use)
+			}
+
+			var state clock.State
+			var err error
+			switch action {
+			case "show":
+				state, err = client.State()
+			case "freeze":
+				state, err = client.Freeze()
+			case "set":
+				state, err = client.Set(args[1])
+			case "advance":
+				state, err = client.Advance(args[1])
+			case "reset":
+				state, err = client.Reset()
+			default:
+				return fmt.Errorf("unknown clock action %s: use show, freeze, set, advance or reset", action)
+			}
+			if err != nil {
+				return err
+			}
+			mode := "running"
+			if state.Frozen {
+				mode = "frozen"
+			}
+			fmt.Printf("%s (%s, %s ahead of the wall clock)\n", state.Now.Format(time.RFC3339), mode, time.Duration(state.OffsetSeconds)*time.Second)
+			return nil
+		},
+	}
+	clockCmd.Flags().StringVar(&clockURL, "url", "http://localhost:8085", "Address of the playground")
+	clockCmd.Flags().StringVar(&clockTenant, "tenant", "", "Tenant whose clock is controlled, the default tenant when empty")
+
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	rootCmd.AddCommand(startCmd)
+	rootCmd.AddCommand(receiveWebhooksCmd)
+	rootCmd.AddCommand(clockCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,91 @@
+package clock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/abhivaikar/playpi/services/tenancy"
+)
+
+// Client controls the clock of a running playground through its /admin/clock endpoints
+type Client struct {
+	// BaseURL is the address of the playground, such as http://localhost:8085
+	BaseURL string
+	// Tenant is the tenant whose clock is controlled. Empty means the default tenant.
+	Tenant string
+	// HTTPClient sends the requests. Nil means http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// State returns the current time of the clock
+func (cl *Client) State() (State, error) {
+	return cl.do(http.MethodGet, "/admin/clock", nil)
+}
+
+// Freeze stops the clock at its current time
+func (cl *Client) Freeze() (State, error) {
+	return cl.do(http.MethodPost, "/admin/clock/freeze", nil)
+}
+
+// Set moves the clock to a time given as an RFC 3339 timestamp or a date
+func (cl *Client) Set(value string) (State, error) {
+	return cl.do(http.MethodPost, "/admin/clock/set", SetRequest{Now: value})
+}
+
+// Advance moves the clock forward by a duration such as 48h
+func (cl *Client) Advance(value string) (State, error) {
+	return cl.do(http.MethodPost, "/admin/clock/advance", AdvanceRequest{Duration: value})
+}
+
+// Reset makes the clock follow the wall clock again
+func (cl *Client) Reset() (State, error) {
+	return cl.do(http.MethodPost, "/admin/clock/reset", nil)
+}
+
+func (cl *Client) do(method string, path string, payload interface{}) (State, error) {
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return State{}, err
+		}
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(cl.BaseURL, "/")+path, &body)
+	if err != nil {
+		return State{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if cl.Tenant != "" {
+		req.Header.Set(tenancy.HeaderTenantID, cl.Tenant)
+	}
+
+	httpClient := cl.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return State{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&failure) == nil && failure.Error != "" {
+			return State{}, fmt.Errorf("%s: %s", resp.Status, failure.Error)
+		}
+		return State{}, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	var state State
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return State{}, err
+	}
+	return state, nil
+}
@@ -0,0 +1,152 @@
+// Package clock gives every sandbox of a playground a clock that can be
+// frozen, set and advanced, so that time-dependent behaviour such as overdue
+// tasks or expiring reservations can be tested deterministically. Clocks
+// follow the wall clock until they are changed, and a single request can
+// pretend to happen at another time with the X-PlayPI-Now header.
+package clock
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// HeaderNow overrides the current time of a single request
+const HeaderNow = "X-PlayPI-Now"
+
+var (
+	ErrInvalidTime     = errors.New("now must be an RFC 3339 timestamp such as 2030-01-15T09:00:00Z or a date such as 2030-01-15")
+	ErrInvalidDuration = errors.New("duration must be a positive duration such as 90m or 48h")
+)
+
+// State describes a clock
+type State struct {
+	Now    time.Time `json:"now" xml:"now"`
+	Frozen bool      `json:"frozen" xml:"frozen"`
+	// OffsetSeconds is how far the clock is ahead of the wall clock, negative when it is behind
+	OffsetSeconds int64 `json:"offset_seconds" xml:"offset_seconds"`
+}
+
+// Clock is the clock of a sandbox. The zero value is not usable, create clocks with New.
+type Clock struct {
+	mu sync.Mutex
+	// offset is added to the wall clock while the clock runs
+	offset time.Duration
+	// frozen stops the clock at frozenAt. It is kept apart from frozenAt, as
+	// a frozen clock can be set to the zero time.
+	frozen   bool
+	frozenAt time.Time
+	wall     func() time.Time
+}
+
+// New creates a clock that follows the wall clock
+func New() *Clock {
+	return &Clock{wall: time.Now}
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now()
+}
+
+// State returns the current time of the clock and whether it is frozen
+func (c *Clock) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state()
+}
+
+// Freeze stops the clock at its current time
+func (c *Clock) Freeze() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.frozen {
+		c.frozenAt = c.now()
+		c.frozen = true
+	}
+	return c.state()
+}
+
+// Set moves the clock to a time. A frozen clock stays frozen at that time,
+// a running clock keeps running from it.
+func (c *Clock) Set(t time.Time) State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen {
+		c.frozenAt = t
+	} else {
+		c.offset = t.Sub(c.wall())
+	}
+	return c.state()
+}
+
+// Advance moves the clock forward
+func (c *Clock) Advance(d time.Duration) (State, error) {
+	if d <= 0 {
+		return State{}, ErrInvalidDuration
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen {
+		c.frozenAt = c.frozenAt.Add(d)
+	} else {
+		c.offset += d
+	}
+	return c.state(), nil
+}
+
+// Reset makes the clock follow the wall clock again
+func (c *Clock) Reset() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.offset = 0
+	c.frozen = false
+	c.frozenAt = time.Time{}
+	return c.state()
+}
+
+// now returns the time of the clock. The caller must hold c.mu.
+func (c *Clock) now() time.Time {
+	if c.frozen {
+		return c.frozenAt
+	}
+	return c.wall().Add(c.offset)
+}
+
+// state describes the clock. The caller must hold c.mu.
+func (c *Clock) state() State {
+	now := c.now()
+	return State{
+		Now:           now.UTC(),
+		Frozen:        c.frozen,
+		OffsetSeconds: int64(now.Sub(c.wall()).Round(time.Second) / time.Second),
+	}
+}
+
+// ParseTime parses the times accepted by Set and the X-PlayPI-Now header: RFC
+// 3339 timestamps, or dates that stand for midnight UTC
+func ParseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, ErrInvalidTime
+}
+
+// ParseDuration parses the durations accepted by Advance
+func ParseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, ErrInvalidDuration
+	}
+	return d, nil
+}
@@ -0,0 +1,101 @@
+package clock
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/negotiation"
+	"github.com/gin-gonic/gin"
+)
+
+// overrideKey is the key of the time requested with X-PlayPI-Now in the gin context
+const overrideKey = "clock.now"
+
+var errInvalidHeader = errors.New(HeaderNow + " must be an RFC 3339 timestamp such as 2030-01-15T09:00:00Z or a date such as 2030-01-15")
+
+// SetRequest is the payload of POST /admin/clock/set
+type SetRequest struct {
+	Now string `json:"now" xml:"now"`
+}
+
+// AdvanceRequest is the payload of POST /admin/clock/advance
+type AdvanceRequest struct {
+	Duration string `json:"duration" xml:"duration"`
+}
+
+// Middleware validates the X-PlayPI-Now header of every request. Handlers
+// read the requested time with Override.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value := c.GetHeader(HeaderNow); value != "" {
+			t, err := ParseTime(value)
+			if err != nil {
+				c.Abort()
+				negotiation.Render(c, http.StatusBadRequest, gin.H{"error": errInvalidHeader.Error()})
+				return
+			}
+			c.Set(overrideKey, t)
+		}
+		c.Next()
+	}
+}
+
+// Override returns the time a request asked to happen at with the X-PlayPI-Now header
+func Override(c *gin.Context) (time.Time, bool) {
+	value, ok := c.Get(overrideKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	return value.(time.Time), true
+}
+
+// Register adds the /admin/clock endpoints to the router. clockOf returns
+// the clock serving a request, so that every tenant controls its own clock.
+func Register(r *gin.Engine, clockOf func(c *gin.Context) *Clock) {
+	// GET /admin/clock - Get the current time of the clock
+	r.GET("/admin/clock", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, clockOf(c).State())
+	})
+
+	// POST /admin/clock/freeze - Stop the clock at its current time
+	r.POST("/admin/clock/freeze", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, clockOf(c).Freeze())
+	})
+
+	// POST /admin/clock/set - Move the clock to a time
+	r.POST("/admin/clock/set", func(c *gin.Context) {
+		var request SetRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+		t, err := ParseTime(request.Now)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, clockOf(c).Set(t))
+	})
+
+	// POST /admin/clock/advance - Move the clock forward
+	r.POST("/admin/clock/advance", func(c *gin.Context) {
+		var request AdvanceRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+		d, err := ParseDuration(request.Duration)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		state, _ := clockOf(c).Advance(d)
+		negotiation.Render(c, http.StatusOK, state)
+	})
+
+	// POST /admin/clock/reset - Follow the wall clock again
+	r.POST("/admin/clock/reset", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, clockOf(c).Reset())
+	})
+}
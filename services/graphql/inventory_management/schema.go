@@ -18,28 +18,30 @@ var inventoryItemType = graphql.NewObject(graphql.ObjectConfig{
 	},
 })
 
-// Inventory data (mock data)
-var inventory = []map[string]interface{}{
-	{"id": 1, "name": "Laptop", "description": "High-performance laptop", "price": 1500.99, "quantity": 10},
-	{"id": 2, "name": "Smartphone", "description": "Latest model smartphone", "price": 899.99, "quantity": 20},
-	{"id": 3, "name": "Tablet", "description": "Portable and powerful tablet", "price": 499.99, "quantity": 15},
-	{"id": 4, "name": "Smartwatch", "description": "Stylish smartwatch", "price": 199.99, "quantity": 25},
-	{"id": 5, "name": "Headphones", "description": "Noise-canceling headphones", "price": 99.99, "quantity": 30},
-	{"id": 6, "name": "Monitor", "description": "4K Ultra HD monitor", "price": 399.99, "quantity": 12},
-	{"id": 7, "name": "Keyboard", "description": "Mechanical keyboard", "price": 79.99, "quantity": 50},
-	{"id": 8, "name": "Mouse", "description": "Wireless ergonomic mouse", "price": 29.99, "quantity": 40},
-	{"id": 9, "name": "Printer", "description": "Multi-function printer", "price": 249.99, "quantity": 8},
-	{"id": 10, "name": "Camera", "description": "DSLR camera with lens kit", "price": 1199.99, "quantity": 5},
-	{"id": 11, "name": "External Hard Drive", "description": "1TB external storage", "price": 59.99, "quantity": 30},
-	{"id": 12, "name": "Gaming Console", "description": "Next-gen gaming console", "price": 499.99, "quantity": 10},
-	{"id": 13, "name": "Router", "description": "High-speed wireless router", "price": 89.99, "quantity": 25},
-	{"id": 14, "name": "Speaker", "description": "Bluetooth portable speaker", "price": 49.99, "quantity": 35},
-	{"id": 15, "name": "Power Bank", "description": "Fast-charging power bank", "price": 19.99, "quantity": 50},
-	{"id": 16, "name": "Projector", "description": "1080p home theater projector", "price": 299.99, "quantity": 7},
-	{"id": 17, "name": "Smart Bulb", "description": "Color-changing smart bulb", "price": 14.99, "quantity": 60},
-	{"id": 18, "name": "Fitness Tracker", "description": "Waterproof fitness tracker", "price": 49.99, "quantity": 20},
-	{"id": 19, "name": "Electric Scooter", "description": "Lightweight and portable", "price": 699.99, "quantity": 3},
-	{"id": 20, "name": "Drone", "description": "Quadcopter with camera", "price": 999.99, "quantity": 5},
+// mockInventory returns the items every sandbox starts with
+func mockInventory() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"id": 1, "name": "Laptop", "description": "High-performance laptop", "price": 1500.99, "quantity": 10},
+		{"id": 2, "name": "Smartphone", "description": "Latest model smartphone", "price": 899.99, "quantity": 20},
+		{"id": 3, "name": "Tablet", "description": "Portable and powerful tablet", "price": 499.99, "quantity": 15},
+		{"id": 4, "name": "Smartwatch", "description": "Stylish smartwatch", "price": 199.99, "quantity": 25},
+		{"id": 5, "name": "Headphones", "description": "Noise-canceling headphones", "price": 99.99, "quantity": 30},
+		{"id": 6, "name": "Monitor", "description": "4K Ultra HD monitor", "price": 399.99, "quantity": 12},
+		{"id": 7, "name": "Keyboard", "description": "Mechanical keyboard", "price": 79.99, "quantity": 50},
+		{"id": 8, "name": "Mouse", "description": "Wireless ergonomic mouse", "price": 29.99, "quantity": 40},
+		{"id": 9, "name": "Printer", "description": "Multi-function printer", "price": 249.99, "quantity": 8},
+		{"id": 10, "name": "Camera", "description": "DSLR camera with lens kit", "price": 1199.99, "quantity": 5},
+		{"id": 11, "name": "External Hard Drive", "description": "1TB external storage", "price": 59.99, "quantity": 30},
+		{"id": 12, "name": "Gaming Console", "description": "Next-gen gaming console", "price": 499.99, "quantity": 10},
+		{"id": 13, "name": "Router", "description": "High-speed wireless router", "price": 89.99, "quantity": 25},
+		{"id": 14, "name": "Speaker", "description": "Bluetooth portable speaker", "price": 49.99, "quantity": 35},
+		{"id": 15, "name": "Power Bank", "description": "Fast-charging power bank", "price": 19.99, "quantity": 50},
+		{"id": 16, "name": "Projector", "description": "1080p home theater projector", "price": 299.99, "quantity": 7},
+		{"id": 17, "name": "Smart Bulb", "description": "Color-changing smart bulb", "price": 14.99, "quantity": 60},
+		{"id": 18, "name": "Fitness Tracker", "description": "Waterproof fitness tracker", "price": 49.99, "quantity": 20},
+		{"id": 19, "name": "Electric Scooter", "description": "Lightweight and portable", "price": 699.99, "quantity": 3},
+		{"id": 20, "name": "Drone", "description": "Quadcopter with camera", "price": 999.99, "quantity": 5},
+	}
 }
 
 var rootQuery = graphql.NewObject(graphql.ObjectConfig{
@@ -48,8 +50,11 @@ var rootQuery = graphql.NewObject(graphql.ObjectConfig{
 		"items": &graphql.Field{
 			Type: graphql.NewList(inventoryItemType),
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				sb := sandboxFrom(p.Context)
+				sb.mu.Lock()
+				defer sb.mu.Unlock()
 				// Return the current state of the inventory
-				return inventory, nil
+				return sb.inventory, nil
 			},
 		},
 		"item": &graphql.Field{
@@ -58,6 +63,9 @@ var rootQuery = graphql.NewObject(graphql.ObjectConfig{
 				"id": &graphql.ArgumentConfig{Type: graphql.Int},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				sb := sandboxFrom(p.Context)
+				sb.mu.Lock()
+				defer sb.mu.Unlock()
 				// Validate if the ID argument is provided
 				id, ok := p.Args["id"].(int)
 				if !ok {
@@ -65,7 +73,7 @@ var rootQuery = graphql.NewObject(graphql.ObjectConfig{
 				}
 
 				// Search for the item with the given ID
-				for _, item := range inventory {
+				for _, item := range sb.inventory {
 					if item["id"] == id {
 						return item, nil
 					}
@@ -91,6 +99,9 @@ var rootMutation = graphql.NewObject(graphql.ObjectConfig{
 				"quantity":    &graphql.ArgumentConfig{Type: graphql.Int},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				sb := sandboxFrom(p.Context)
+				sb.mu.Lock()
+				defer sb.mu.Unlock()
 
 				name := p.Args["name"].(string)
 				if len(name) < 3 || len(name) > 50 {
@@ -110,7 +121,7 @@ var rootMutation = graphql.NewObject(graphql.ObjectConfig{
 				}
 
 				// Prevent duplicate names
-				for _, item := range inventory {
+				for _, item := range sb.inventory {
 					if item["name"] == name {
 						return nil, errors.New("an item with this name already exists")
 					}
@@ -118,13 +129,13 @@ var rootMutation = graphql.NewObject(graphql.ObjectConfig{
 
 				// Add the new item
 				newItem := map[string]interface{}{
-					"id":          len(inventory) + 1,
+					"id":          len(sb.inventory) + 1,
 					"name":        name,
 					"description": description,
 					"price":       price,
 					"quantity":    quantity,
 				}
-				inventory = append(inventory, newItem)
+				sb.inventory = append(sb.inventory, newItem)
 				return newItem, nil
 			},
 		},
@@ -138,10 +149,13 @@ var rootMutation = graphql.NewObject(graphql.ObjectConfig{
 				"quantity":    &graphql.ArgumentConfig{Type: graphql.Int},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				sb := sandboxFrom(p.Context)
+				sb.mu.Lock()
+				defer sb.mu.Unlock()
 				id := p.Args["id"].(int)
 				var item map[string]interface{}
 				var found bool
-				for _, i := range inventory {
+				for _, i := range sb.inventory {
 					if i["id"] == id {
 						item = i
 						found = true
@@ -185,10 +199,13 @@ var rootMutation = graphql.NewObject(graphql.ObjectConfig{
 				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				sb := sandboxFrom(p.Context)
+				sb.mu.Lock()
+				defer sb.mu.Unlock()
 				id := p.Args["id"].(int)
 				var index int
 				var found bool
-				for i, item := range inventory {
+				for i, item := range sb.inventory {
 					if item["id"] == id {
 						if item["quantity"].(int) > 0 {
 							return nil, errors.New("cannot delete an item with stock remaining")
@@ -203,7 +220,7 @@ var rootMutation = graphql.NewObject(graphql.ObjectConfig{
 					return nil, errors.New("item not found")
 				}
 
-				inventory = append(inventory[:index], inventory[index+1:]...)
+				sb.inventory = append(sb.inventory[:index], sb.inventory[index+1:]...)
 				return "Item deleted successfully", nil
 			},
 		},
@@ -1,15 +1,57 @@
 package graphql
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"sync"
 
+	"github.com/abhivaikar/playpi/services/tenancy"
 	"github.com/graphql-go/graphql"
 )
 
-func StartServer() {
-	http.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+// sandbox is the inventory of a tenant
+type sandbox struct {
+	mu        sync.Mutex
+	inventory []map[string]interface{}
+}
+
+// sandboxes holds the sandbox of every tenant
+var sandboxes = tenancy.NewRegistry(func() *sandbox {
+	return &sandbox{inventory: mockInventory()}
+}, nil)
+
+// sandboxKey is the context key of the sandbox of a request
+type sandboxKey struct{}
+
+// sandboxFrom returns the sandbox stored in the context of a request, or the
+// default sandbox when the schema is executed without one
+func sandboxFrom(ctx context.Context) *sandbox {
+	if ctx != nil {
+		if sb, ok := ctx.Value(sandboxKey{}).(*sandbox); ok {
+			return sb
+		}
+	}
+	sb, _ := sandboxes.Get(tenancy.DefaultTenant)
+	return sb
+}
+
+// handler executes GraphQL queries against the sandbox of the tenant named by
+// the X-Tenant-ID or X-API-Key header of the request
+func handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, err := tenancy.FromRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), tenancy.StatusForError(err))
+			return
+		}
+		sb, err := sandboxes.Get(tenant)
+		if err != nil {
+			http.Error(w, err.Error(), tenancy.StatusForError(err))
+			return
+		}
+
 		var params struct {
 			Query string `json:"query"`
 		}
@@ -21,6 +63,7 @@ func StartServer() {
 		result := graphql.Do(graphql.Params{
 			Schema:        Schema,
 			RequestString: params.Query,
+			Context:       context.WithValue(r.Context(), sandboxKey{}, sb),
 		})
 
 		if len(result.Errors) > 0 {
@@ -28,8 +71,13 @@ func StartServer() {
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(tenancy.HeaderTenantID, tenant)
 		json.NewEncoder(w).Encode(result)
 	})
+}
+
+func StartServer() {
+	http.Handle("/graphql", handler())
 
 	log.Println("GraphQL API is running on http://localhost:8081/graphql")
 	log.Fatal(http.ListenAndServe(":8081", nil))
@@ -1,6 +1,7 @@
 package graphql
 
 import (
+	"context"
 	"net/http/httptest"
 	"testing"
 )
@@ -11,7 +12,8 @@ func TestDeleteItem(t *testing.T) {
 
 	t.Run("Successful Deletion", func(t *testing.T) {
 		// Set up an item with quantity = 0 for this test
-		inventory = append(inventory, map[string]interface{}{
+		sb := sandboxFrom(context.Background())
+		sb.inventory = append(sb.inventory, map[string]interface{}{
 			"id":          99,
 			"name":        "Item to Delete",
 			"description": "This item will be deleted",
@@ -58,7 +60,8 @@ func TestDeleteItem(t *testing.T) {
 
 	t.Run("Cannot Delete Item with Stock Remaining", func(t *testing.T) {
 		// Set up an item with quantity > 0 for this test
-		inventory = append(inventory, map[string]interface{}{
+		sb := sandboxFrom(context.Background())
+		sb.inventory = append(sb.inventory, map[string]interface{}{
 			"id":          100,
 			"name":        "Item with Stock",
 			"description": "This item has stock remaining",
@@ -0,0 +1,57 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abhivaikar/playpi/services/tenancy"
+)
+
+// sendTenantGraphQLRequest sends a query on behalf of a tenant and returns the response
+func sendTenantGraphQLRequest(t *testing.T, ts *httptest.Server, tenant string, query string) *http.Response {
+	jsonPayload, _ := json.Marshal(map[string]interface{}{"query": query})
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, bytes.NewBuffer(jsonPayload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(tenancy.HeaderTenantID, tenant)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	return resp
+}
+
+func TestTenantIsolation(t *testing.T) {
+	ts := httptest.NewServer(handler())
+	defer ts.Close()
+
+	t.Run("Tenants do not see each other's items", func(t *testing.T) {
+		resp := sendTenantGraphQLRequest(t, ts, "alice", `mutation { addItem(name: "Alice Item", description: "", price: 10, quantity: 1) { id } }`)
+		resp.Body.Close()
+		if resp.Header.Get(tenancy.HeaderTenantID) != "alice" {
+			t.Errorf("Expected tenant 'alice', got %v", resp.Header.Get(tenancy.HeaderTenantID))
+		}
+
+		resp = sendTenantGraphQLRequest(t, ts, "bob", `{ item(id: 21) { name } }`)
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		errors, ok := result["errors"].([]interface{})
+		if !ok || errors[0].(map[string]interface{})["message"] != "item not found" {
+			t.Errorf("Expected item not found for another tenant, got %v", result)
+		}
+	})
+
+	t.Run("Invalid tenant ID", func(t *testing.T) {
+		resp := sendTenantGraphQLRequest(t, ts, "alice bob", `{ items { id } }`)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+}
@@ -5,20 +5,54 @@ import (
 	"errors"
 	"log"
 	"net"
+	"sync"
 
 	pb "github.com/abhivaikar/playpi/services/grpc/inventory_management/pb"
+	"github.com/abhivaikar/playpi/services/tenancy"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type server struct {
 	pb.UnimplementedInventoryServiceServer
+	sandboxes *tenancy.Registry[*sandbox]
+}
+
+// sandbox is the inventory of a tenant
+type sandbox struct {
+	mu        sync.Mutex
 	inventory []pb.Item
 }
 
+// newServer creates a server giving every tenant its own inventory, seeded with the mock data
+func newServer() *server {
+	return &server{sandboxes: tenancy.NewRegistry(func() *sandbox {
+		sb := &sandbox{}
+		sb.loadMockData()
+		return sb
+	}, nil)}
+}
+
+// lockSandbox locks and returns the inventory of the tenant named by the
+// x-tenant-id or x-api-key metadata of a call. The caller must unlock sb.mu.
+func (s *server) lockSandbox(ctx context.Context) (*sandbox, error) {
+	tenant, err := tenancy.FromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	sb, err := s.sandboxes.Get(tenant)
+	if err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	sb.mu.Lock()
+	return sb, nil
+}
+
 // Load mock data into the inventory
-func (s *server) loadMockData() {
-	s.inventory = []pb.Item{
+func (sb *sandbox) loadMockData() {
+	sb.inventory = []pb.Item{
 		{Id: 1, Name: "Laptop", Description: "A high-performance laptop", Price: 999.99, Quantity: 10},
 		{Id: 2, Name: "Smartphone", Description: "A powerful smartphone", Price: 699.99, Quantity: 20},
 		{Id: 3, Name: "Headphones", Description: "Noise-cancelling headphones", Price: 199.99, Quantity: 15},
@@ -34,9 +68,15 @@ func (s *server) loadMockData() {
 
 // GetItem fetches an item by ID
 func (s *server) GetItem(ctx context.Context, req *pb.GetItemRequest) (*pb.GetItemResponse, error) {
-	for i := range s.inventory {
-		if s.inventory[i].Id == req.Id {
-			return &pb.GetItemResponse{Item: &s.inventory[i]}, nil
+	sb, err := s.lockSandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.mu.Unlock()
+
+	for i := range sb.inventory {
+		if sb.inventory[i].Id == req.Id {
+			return &pb.GetItemResponse{Item: &sb.inventory[i]}, nil
 		}
 	}
 	return nil, errors.New("item not found")
@@ -44,15 +84,27 @@ func (s *server) GetItem(ctx context.Context, req *pb.GetItemRequest) (*pb.GetIt
 
 // ListItems returns all items in the inventory
 func (s *server) ListItems(ctx context.Context, req *pb.ListItemsRequest) (*pb.ListItemsResponse, error) {
+	sb, err := s.lockSandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.mu.Unlock()
+
 	var items []*pb.Item
-	for i := range s.inventory {
-		items = append(items, &s.inventory[i])
+	for i := range sb.inventory {
+		items = append(items, &sb.inventory[i])
 	}
 	return &pb.ListItemsResponse{Items: items}, nil
 }
 
 // AddItem adds a new item to the inventory
 func (s *server) AddItem(ctx context.Context, req *pb.AddItemRequest) (*pb.AddItemResponse, error) {
+	sb, err := s.lockSandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.mu.Unlock()
+
 	if len(req.Name) < 3 || len(req.Name) > 50 {
 		return nil, errors.New("name must be between 3 and 50 characters")
 	}
@@ -67,20 +119,26 @@ func (s *server) AddItem(ctx context.Context, req *pb.AddItemRequest) (*pb.AddIt
 	}
 
 	newItem := pb.Item{
-		Id:          int32(len(s.inventory) + 1),
+		Id:          int32(len(sb.inventory) + 1),
 		Name:        req.Name,
 		Description: req.Description,
 		Price:       req.Price,
 		Quantity:    req.Quantity,
 	}
-	s.inventory = append(s.inventory, newItem)
+	sb.inventory = append(sb.inventory, newItem)
 	return &pb.AddItemResponse{Item: &newItem}, nil
 }
 
 // UpdateItem updates an existing item by ID
 func (s *server) UpdateItem(ctx context.Context, req *pb.UpdateItemRequest) (*pb.UpdateItemResponse, error) {
-	for i := range s.inventory {
-		if s.inventory[i].Id == req.Id {
+	sb, err := s.lockSandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.mu.Unlock()
+
+	for i := range sb.inventory {
+		if sb.inventory[i].Id == req.Id {
 			if req.Name != "" && (len(req.Name) < 3 || len(req.Name) > 50) {
 				return nil, errors.New("name must be between 3 and 50 characters")
 			}
@@ -96,18 +154,18 @@ func (s *server) UpdateItem(ctx context.Context, req *pb.UpdateItemRequest) (*pb
 
 			// Update the item
 			if req.Name != "" {
-				s.inventory[i].Name = req.Name
+				sb.inventory[i].Name = req.Name
 			}
 			if req.Description != "" {
-				s.inventory[i].Description = req.Description
+				sb.inventory[i].Description = req.Description
 			}
 			if req.Price != 0 {
-				s.inventory[i].Price = req.Price
+				sb.inventory[i].Price = req.Price
 			}
 			if req.Quantity != 0 {
-				s.inventory[i].Quantity = req.Quantity
+				sb.inventory[i].Quantity = req.Quantity
 			}
-			return &pb.UpdateItemResponse{Item: &s.inventory[i]}, nil
+			return &pb.UpdateItemResponse{Item: &sb.inventory[i]}, nil
 		}
 	}
 	return nil, errors.New("item not found")
@@ -115,12 +173,18 @@ func (s *server) UpdateItem(ctx context.Context, req *pb.UpdateItemRequest) (*pb
 
 // DeleteItem removes an item by ID
 func (s *server) DeleteItem(ctx context.Context, req *pb.DeleteItemRequest) (*pb.DeleteItemResponse, error) {
-	for i := range s.inventory {
-		if s.inventory[i].Id == req.Id {
-			if s.inventory[i].Quantity > 0 {
+	sb, err := s.lockSandbox(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.mu.Unlock()
+
+	for i := range sb.inventory {
+		if sb.inventory[i].Id == req.Id {
+			if sb.inventory[i].Quantity > 0 {
 				return nil, errors.New("cannot delete an item with stock remaining")
 			}
-			s.inventory = append(s.inventory[:i], s.inventory[i+1:]...)
+			sb.inventory = append(sb.inventory[:i], sb.inventory[i+1:]...)
 			return &pb.DeleteItemResponse{Success: true}, nil
 		}
 	}
@@ -133,8 +197,7 @@ func StartServer() {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	s := &server{}
-	s.loadMockData() // Load mock data into the inventory
+	s := newServer()
 
 	grpcServer := grpc.NewServer()
 	pb.RegisterInventoryServiceServer(grpcServer, s)
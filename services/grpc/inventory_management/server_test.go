@@ -5,14 +5,16 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/abhivaikar/playpi/services/grpc/inventory_management/pb"
+	"github.com/abhivaikar/playpi/services/tenancy"
 )
 
 func setupTestServer() *server {
-	s := &server{}
-	s.loadMockData() // Load mock data into the inventory
-	return s
+	return newServer()
 }
 
 func TestGetItem(t *testing.T) {
@@ -54,7 +56,8 @@ func TestAddItem(t *testing.T) {
 		})
 		require.NoError(t, err)
 		require.Equal(t, "New Item", resp.Item.Name)
-		require.Equal(t, 11, len(s.inventory)) // Ensure the item was added
+		sb, _ := s.sandboxes.Get(tenancy.DefaultTenant)
+		require.Equal(t, 11, len(sb.inventory)) // Ensure the item was added
 	})
 
 	t.Run("Validation Error - Invalid Name", func(t *testing.T) {
@@ -188,11 +191,12 @@ func TestDeleteItem(t *testing.T) {
 	s := setupTestServer()
 
 	t.Run("Successful Deletion", func(t *testing.T) {
-		s.inventory[0].Quantity = 0 // Ensure quantity is 0
+		sb, _ := s.sandboxes.Get(tenancy.DefaultTenant)
+		sb.inventory[0].Quantity = 0 // Ensure quantity is 0
 		resp, err := s.DeleteItem(context.Background(), &pb.DeleteItemRequest{Id: 1})
 		require.NoError(t, err)
 		require.True(t, resp.Success)
-		require.Equal(t, 9, len(s.inventory)) // Ensure the item was removed
+		require.Equal(t, 9, len(sb.inventory)) // Ensure the item was removed
 	})
 
 	t.Run("Cannot Delete Item with Stock Remaining", func(t *testing.T) {
@@ -207,3 +211,33 @@ func TestDeleteItem(t *testing.T) {
 		require.Equal(t, "item not found", err.Error())
 	})
 }
+
+func TestTenantIsolation(t *testing.T) {
+	s := setupTestServer()
+	alice := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant-id", "alice"))
+	bob := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "bob-key"))
+
+	t.Run("Tenants do not see each other's items", func(t *testing.T) {
+		_, err := s.AddItem(alice, &pb.AddItemRequest{Name: "Alice Item", Price: 10, Quantity: 1})
+		require.NoError(t, err)
+
+		resp, err := s.ListItems(alice, &pb.ListItemsRequest{})
+		require.NoError(t, err)
+		require.Len(t, resp.Items, 11)
+
+		resp, err = s.ListItems(bob, &pb.ListItemsRequest{})
+		require.NoError(t, err)
+		require.Len(t, resp.Items, 10)
+
+		resp, err = s.ListItems(context.Background(), &pb.ListItemsRequest{})
+		require.NoError(t, err)
+		require.Len(t, resp.Items, 10)
+	})
+
+	t.Run("Invalid tenant ID", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-tenant-id", "alice/bob"))
+		_, err := s.ListItems(ctx, &pb.ListItemsRequest{})
+
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -0,0 +1,617 @@
+// Package negotiation implements HTTP content negotiation shared by the
+// RESTful playgrounds and the packages they build on, such as the clock and
+// tenancy endpoints. Responses can be rendered as JSON, XML, YAML or, for
+// collections, CSV depending on the request's Accept header, and request
+// bodies are decoded according to their Content-Type.
+package negotiation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported media types
+const (
+	MIMEJSON = "application/json"
+	MIMEXML  = "application/xml"
+	MIMEYAML = "application/yaml"
+	MIMECSV  = "text/csv"
+)
+
+// aliases maps alternative media type names onto the canonical ones above
+var aliases = map[string]string{
+	"text/json":          MIMEJSON,
+	"text/xml":           MIMEXML,
+	"application/x-yaml": MIMEYAML,
+	"text/yaml":          MIMEYAML,
+	"text/x-yaml":        MIMEYAML,
+	"application/csv":    MIMECSV,
+}
+
+// ErrNotAcceptable is returned when none of the offered media types match the Accept header
+var ErrNotAcceptable = errors.New("not acceptable")
+
+// ErrUnsupportedMediaType is returned when a request body uses a Content-Type that cannot be decoded
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// acceptRange is a single entry of an Accept header
+type acceptRange struct {
+	mediaType string
+	q         float64
+	order     int
+}
+
+// Middleware rejects requests that cannot possibly be served. Requests whose
+// Accept header matches none of the supported response formats get a
+// 406 Not Acceptable and requests carrying a body in an unsupported format get
+// a 415 Unsupported Media Type, before any handler runs. Routes listed in
+// binaryRoutes (e.g. "/items/:id/images") exchange other media types and
+// check the headers themselves.
+func Middleware(binaryRoutes ...string) gin.HandlerFunc {
+	exempt := map[string]bool{}
+	for _, route := range binaryRoutes {
+		exempt[route] = true
+	}
+	return func(c *gin.Context) {
+		if exempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		offers := []string{MIMEJSON, MIMEXML, MIMEYAML}
+		if c.Request.Method == http.MethodGet {
+			// CSV is only produced for collections, which are always fetched with GET
+			offers = append(offers, MIMECSV)
+		}
+		if _, err := Negotiate(c.GetHeader("Accept"), offers...); err != nil {
+			c.AbortWithStatusJSON(http.StatusNotAcceptable, notAcceptableBody(offers))
+			return
+		}
+
+		if hasBody(c.Request) {
+			if _, err := RequestFormat(c); err != nil {
+				c.Abort()
+				Render(c, http.StatusUnsupportedMediaType, gin.H{
+					"error":     "unsupported media type",
+					"supported": []string{MIMEJSON, MIMEXML, MIMEYAML},
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Negotiate picks the best of the offered media types for the given Accept
+// header. An empty header accepts the first offer.
+func Negotiate(accept string, offers ...string) (string, error) {
+	if len(offers) == 0 {
+		return "", ErrNotAcceptable
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], nil
+	}
+
+	ranges := parseAccept(accept)
+	for _, r := range ranges {
+		if r.q <= 0 {
+			continue
+		}
+		for _, offer := range offers {
+			if matches(r.mediaType, offer) && !excluded(ranges, offer) {
+				return offer, nil
+			}
+		}
+	}
+	return "", ErrNotAcceptable
+}
+
+// Render writes data with the given status in the format negotiated from the
+// request's Accept header. CSV is only offered when data is a slice. Error
+// responses that cannot be represented in an acceptable format fall back to
+// JSON rather than masking the original status with a 406.
+func Render(c *gin.Context, status int, data interface{}) {
+	offers := []string{MIMEJSON, MIMEXML, MIMEYAML}
+	if isCollection(data) {
+		offers = append(offers, MIMECSV)
+	}
+
+	format, err := Negotiate(c.GetHeader("Accept"), offers...)
+	if err != nil {
+		if status >= http.StatusBadRequest {
+			c.JSON(status, data)
+			return
+		}
+		c.JSON(http.StatusNotAcceptable, notAcceptableBody(offers))
+		return
+	}
+	c.Header("Vary", "Accept")
+
+	switch format {
+	case MIMEXML:
+		body, err := marshalXML(data)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(status, MIMEXML+"; charset=utf-8", append([]byte(xml.Header), body...))
+	case MIMEYAML:
+		body, err := marshalYAML(data)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(status, MIMEYAML+"; charset=utf-8", body)
+	case MIMECSV:
+		body, err := marshalCSV(data)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(status, MIMECSV+"; charset=utf-8", body)
+	default:
+		c.JSON(status, data)
+	}
+}
+
+// RenderEmpty answers a request for a collection that has nothing to list with
+// body, such as {"message": "no tasks created"}. Clients that only accept CSV
+// get the header row of empty instead of a 406, as for any other collection.
+func RenderEmpty(c *gin.Context, status int, empty interface{}, body interface{}) {
+	format, err := Negotiate(c.GetHeader("Accept"), MIMEJSON, MIMEXML, MIMEYAML, MIMECSV)
+	if err == nil && format == MIMECSV {
+		Render(c, status, empty)
+		return
+	}
+	Render(c, status, body)
+}
+
+// Bind decodes the request body into obj according to its Content-Type.
+// A missing Content-Type is treated as JSON. obj may point to a struct, a slice
+// or a map[string]interface{}. The values of XML documents decoded into a map
+// are left as strings; use BindPartial to type them after a struct.
+func Bind(c *gin.Context, obj interface{}) error {
+	return bind(c, obj, nil)
+}
+
+// BindPartial decodes a partial update, such as the body of a PATCH, into a
+// map. The values of XML documents are typed after the field of model with the
+// same JSON name, so that callers see the same types as with JSON: numbers are
+// float64, booleans are bool, empty numbers are nil, nested structs are maps
+// and everything else, including text that looks like a number, is a string.
+func BindPartial(c *gin.Context, updates *map[string]interface{}, model interface{}) error {
+	return bind(c, updates, reflect.TypeOf(model))
+}
+
+func bind(c *gin.Context, obj interface{}, model reflect.Type) error {
+	format, err := RequestFormat(c)
+	if err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case MIMEXML:
+		if m, ok := obj.(*map[string]interface{}); ok {
+			return unmarshalXMLMap(body, m, model)
+		}
+		if target := reflect.ValueOf(obj); target.Kind() == reflect.Ptr && target.Elem().Kind() == reflect.Slice {
+			return unmarshalXMLList(body, target.Elem())
+		}
+		return xml.Unmarshal(body, obj)
+	case MIMEYAML:
+		var generic interface{}
+		if err := yaml.Unmarshal(body, &generic); err != nil {
+			return err
+		}
+		// Round-trip through JSON so that struct tags and number types behave exactly as for JSON bodies
+		converted, err := json.Marshal(generic)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(converted, obj)
+	default:
+		return json.Unmarshal(body, obj)
+	}
+}
+
+// RequestFormat returns the canonical media type of the request body. Requests
+// without a Content-Type are read as JSON.
+func RequestFormat(c *gin.Context) (string, error) {
+	contentType := c.GetHeader("Content-Type")
+	if contentType == "" {
+		return MIMEJSON, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ErrUnsupportedMediaType
+	}
+	mediaType = canonical(mediaType)
+	switch mediaType {
+	case MIMEJSON, MIMEXML, MIMEYAML:
+		return mediaType, nil
+	}
+	return "", ErrUnsupportedMediaType
+}
+
+// hasBody reports whether the request carries a body that handlers will decode
+func hasBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return r.ContentLength != 0
+	}
+	return false
+}
+
+func notAcceptableBody(offers []string) gin.H {
+	return gin.H{"error": "none of the requested media types are supported", "supported": offers}
+}
+
+func canonical(mediaType string) string {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if alias, ok := aliases[mediaType]; ok {
+		return alias
+	}
+	return mediaType
+}
+
+// parseAccept splits an Accept header into media ranges ordered by preference
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for i, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: canonical(mediaType), q: q, order: i})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		// More specific ranges win over wildcards with the same quality
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+	return ranges
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+func matches(mediaRange, offer string) bool {
+	if mediaRange == "*/*" || mediaRange == offer {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(offer, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
+
+// excluded reports whether the offer is explicitly refused with q=0
+func excluded(ranges []acceptRange, offer string) bool {
+	for _, r := range ranges {
+		if r.mediaType == offer && r.q <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isCollection(data interface{}) bool {
+	if data == nil {
+		return false
+	}
+	kind := reflect.TypeOf(data).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+// marshalXML encodes data as XML. Collections are wrapped in a root element
+// named after their element type, e.g. <TaskList><Task>...</Task></TaskList>.
+func marshalXML(data interface{}) ([]byte, error) {
+	if !isCollection(data) {
+		return xml.MarshalIndent(data, "", "  ")
+	}
+
+	elemType := reflect.TypeOf(data).Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	name := elemType.Name()
+	if name == "" {
+		name = "item"
+	}
+
+	var sb strings.Builder
+	encoder := xml.NewEncoder(&sb)
+	encoder.Indent("", "  ")
+	root := xml.StartElement{Name: xml.Name{Local: name + "List"}}
+	if err := encoder.EncodeToken(root); err != nil {
+		return nil, err
+	}
+	value := reflect.ValueOf(data)
+	for i := 0; i < value.Len(); i++ {
+		element := xml.StartElement{Name: xml.Name{Local: name}}
+		if err := encoder.EncodeElement(value.Index(i).Interface(), element); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+// marshalYAML encodes data as YAML using the same field names as the JSON representation
+func marshalYAML(data interface{}) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := yaml.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+// marshalCSV encodes a slice of structs as CSV with a header row taken from the JSON field names.
+// Fields tagged csv:"-" are left out, and the fields of embedded structs are
+// flattened into their own columns as in the JSON representation.
+func marshalCSV(data interface{}) ([]byte, error) {
+	value := reflect.ValueOf(data)
+	elemType := value.Type().Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot encode %s as CSV", value.Type())
+	}
+
+	header, fields := csvColumns(elemType, nil)
+
+	var sb strings.Builder
+	writer := csv.NewWriter(&sb)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	for i := 0; i < value.Len(); i++ {
+		elem := reflect.Indirect(value.Index(i))
+		record := make([]string, len(fields))
+		for j, index := range fields {
+			record[j] = csvValue(elem.FieldByIndex(index))
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return []byte(sb.String()), writer.Error()
+}
+
+// csvColumns returns the column names of a struct and the index of the field of each column
+func csvColumns(structType reflect.Type, parent []int) (header []string, fields [][]int) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		index := append(append([]int(nil), parent...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			embeddedHeader, embeddedFields := csvColumns(field.Type, index)
+			header = append(header, embeddedHeader...)
+			fields = append(fields, embeddedFields...)
+			continue
+		}
+		if !field.IsExported() || field.Tag.Get("csv") == "-" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		header = append(header, name)
+		fields = append(fields, index)
+	}
+	return header, fields
+}
+
+func csvValue(v reflect.Value) string {
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+		return csvValue(v.Elem())
+	case reflect.Struct, reflect.Map, reflect.Slice:
+		raw, _ := json.Marshal(v.Interface())
+		return string(raw)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// unmarshalXMLList decodes every child of the root element into a new slice
+// element, mirroring the <TaskList><Task>...</Task></TaskList> layout produced by Render.
+func unmarshalXMLList(body []byte, list reflect.Value) error {
+	decoder := xml.NewDecoder(strings.NewReader(string(body)))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				depth++
+				continue
+			}
+			elem := reflect.New(list.Type().Elem())
+			if err := decoder.DecodeElement(elem.Interface(), &t); err != nil {
+				return err
+			}
+			list.Set(reflect.Append(list, elem.Elem()))
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if depth != 0 || len(body) == 0 {
+		return errors.New("malformed XML document")
+	}
+	return nil
+}
+
+// unmarshalXMLMap decodes an XML document such as
+// <item><quantity>5</quantity></item> into a map, typing the values after the
+// fields of model. Without a model every value is a string.
+func unmarshalXMLMap(body []byte, m *map[string]interface{}, model reflect.Type) error {
+	decoder := xml.NewDecoder(strings.NewReader(string(body)))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return errors.New("malformed XML document")
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			break
+		}
+	}
+	result, err := unmarshalXMLFields(decoder, model)
+	if err != nil {
+		return err
+	}
+	*m = result
+	return nil
+}
+
+// unmarshalXMLFields decodes the children of the current element up to its end
+func unmarshalXMLFields(decoder *xml.Decoder, model reflect.Type) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, errors.New("malformed XML document")
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			fieldType := xmlFieldType(model, t.Name.Local)
+			if fieldType != nil && fieldType.Kind() == reflect.Struct && fieldType != reflect.TypeOf(time.Time{}) {
+				nested, err := unmarshalXMLFields(decoder, fieldType)
+				if err != nil {
+					return nil, err
+				}
+				result[t.Name.Local] = nested
+				continue
+			}
+			var text string
+			if err := decoder.DecodeElement(&text, &t); err != nil {
+				return nil, err
+			}
+			result[t.Name.Local] = xmlValue(text, fieldType)
+		case xml.EndElement:
+			return result, nil
+		}
+	}
+}
+
+// xmlFieldType returns the type of the field of model named name in JSON, or
+// nil when model has no such field. Pointers are dereferenced.
+func xmlFieldType(model reflect.Type, name string) reflect.Type {
+	for model != nil && model.Kind() == reflect.Ptr {
+		model = model.Elem()
+	}
+	if model == nil || model.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < model.NumField(); i++ {
+		field := model.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.Anonymous && tag == "" {
+			if fieldType := xmlFieldType(field.Type, name); fieldType != nil {
+				return fieldType
+			}
+			continue
+		}
+		if tag == name || (tag == "" && field.Name == name) {
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			return fieldType
+		}
+	}
+	return nil
+}
+
+// xmlValue converts the text of an element to the JSON type of a field of
+// fieldType. Text that does not parse is kept as a string for the caller's
+// validation to reject.
+func xmlValue(raw string, fieldType reflect.Type) interface{} {
+	if fieldType == nil {
+		return raw
+	}
+	trimmed := strings.TrimSpace(raw)
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if trimmed == "" {
+			return nil
+		}
+		if f, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return f
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(trimmed); err == nil {
+			return b
+		}
+	}
+	return raw
+}
@@ -0,0 +1,193 @@
+// Package idempotency implements the Idempotency-Key header for the RESTful
+// playgrounds. The first response produced for a key is stored and replayed
+// for every retry carrying the same key and payload, so that retried creates
+// do not produce duplicates.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/negotiation"
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderKey is the request header carrying the client-generated key
+const HeaderKey = "Idempotency-Key"
+
+// HeaderReplayed is set on responses that were replayed from the store
+const HeaderReplayed = "Idempotent-Replayed"
+
+// MaxKeyLength is the maximum accepted length of an idempotency key
+const MaxKeyLength = 255
+
+// DefaultTTL is how long keys are remembered unless configured otherwise
+const DefaultTTL = 24 * time.Hour
+
+// entry is a stored response for a single key
+type entry struct {
+	fingerprint string
+	inFlight    bool
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+}
+
+// Store keeps the responses of idempotent requests until they expire
+type Store struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]*entry
+
+	// Scope, when set, partitions the keys, for instance by tenant, so that
+	// clients in different scopes never see each other's responses
+	Scope func(c *gin.Context) string
+}
+
+// NewStore creates a store that remembers keys for ttl. A ttl of zero or less uses DefaultTTL.
+func NewStore(ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*entry),
+	}
+}
+
+// recorder captures the response body written by the handler
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Middleware makes the route idempotent for requests that carry an
+// Idempotency-Key header. Requests without the header are passed through.
+//
+//   - A retry with the same key and payload replays the stored response.
+//   - Reusing a key with a different method, path, payload, Content-Type or
+//     negotiated response format returns 422.
+//   - A retry while the original request is still running returns 409.
+//
+// Server errors (5xx) are not stored so that the client can retry them.
+func (s *Store) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > MaxKeyLength {
+			c.Abort()
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "idempotency key cannot exceed 255 characters"})
+			return
+		}
+		if s.Scope != nil {
+			key = s.Scope(c) + "\x00" + key
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Abort()
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := fingerprintOf(c, body)
+
+		s.mu.Lock()
+		s.sweep()
+		if existing, ok := s.entries[key]; ok {
+			s.mu.Unlock()
+			switch {
+			case existing.fingerprint != fingerprint:
+				c.Abort()
+				negotiation.Render(c, http.StatusUnprocessableEntity, gin.H{"error": "idempotency key has already been used for a different request"})
+			case existing.inFlight:
+				c.Abort()
+				negotiation.Render(c, http.StatusConflict, gin.H{"error": "a request with this idempotency key is still being processed"})
+			default:
+				c.Header(HeaderReplayed, "true")
+				c.Data(existing.status, existing.contentType, existing.body)
+				c.Abort()
+			}
+			return
+		}
+		s.entries[key] = &entry{fingerprint: fingerprint, inFlight: true, expiresAt: s.now().Add(s.ttl)}
+		s.mu.Unlock()
+
+		rec := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		completed := false
+		defer func() {
+			// Release the key if the handler panicked so that the client can retry
+			if !completed {
+				s.mu.Lock()
+				delete(s.entries, key)
+				s.mu.Unlock()
+			}
+		}()
+		c.Next()
+		completed = true
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if rec.Status() >= http.StatusInternalServerError {
+			delete(s.entries, key)
+			return
+		}
+		s.entries[key] = &entry{
+			fingerprint: fingerprint,
+			status:      rec.Status(),
+			contentType: rec.Header().Get("Content-Type"),
+			body:        rec.body.Bytes(),
+			expiresAt:   s.now().Add(s.ttl),
+		}
+	}
+}
+
+// sweep removes expired keys. The caller must hold s.mu.
+func (s *Store) sweep() {
+	now := s.now()
+	for key, e := range s.entries {
+		if !e.inFlight && now.After(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// fingerprintOf identifies a request by method, path, query and payload, and
+// by the media types of the payload and of the response: the same bytes mean
+// something else in another format, and a replay must be in the format the
+// client asked for.
+func fingerprintOf(c *gin.Context, body []byte) string {
+	r := c.Request
+	requestFormat := ""
+	if len(body) > 0 {
+		requestFormat, _ = negotiation.RequestFormat(c)
+	}
+	responseFormat, _ := negotiation.Negotiate(c.GetHeader("Accept"), negotiation.MIMEJSON, negotiation.MIMEXML, negotiation.MIMEYAML, negotiation.MIMECSV)
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write([]byte(requestFormat + " " + responseFormat + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
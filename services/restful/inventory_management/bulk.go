@@ -0,0 +1,161 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// MaxBulkSize is the maximum number of elements accepted by a bulk operation
+const MaxBulkSize = 100
+
+// BulkResult is the outcome of a single element of a bulk operation
+type BulkResult struct {
+	Index  int            `json:"index" xml:"index"`
+	ID     int            `json:"id,omitempty" xml:"id,omitempty"`
+	Status int            `json:"status" xml:"status"`
+	Item   *InventoryItem `json:"item,omitempty" xml:"item,omitempty"`
+	Error  string         `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// BulkResponse summarises a bulk operation
+type BulkResponse struct {
+	Atomic     bool         `json:"atomic" xml:"atomic"`
+	RolledBack bool         `json:"rolled_back" xml:"rolled_back"`
+	Succeeded  int          `json:"succeeded" xml:"succeeded"`
+	Failed     int          `json:"failed" xml:"failed"`
+	Results    []BulkResult `json:"results" xml:"result"`
+}
+
+func validateBatchSize(size int) error {
+	if size == 0 {
+		return errors.New("batch must contain at least one element")
+	}
+	if size > MaxBulkSize {
+		return fmt.Errorf("batch cannot exceed %d elements", MaxBulkSize)
+	}
+	return nil
+}
+
+// BulkAddItems adds every item of the batch. When atomic is true a single
+// failure rolls back the items already added in this batch.
+func (sb *sandbox) BulkAddItems(items []InventoryItem, atomic bool) (*BulkResponse, error) {
+	return sb.bulkAddItems(len(items), atomic, func(i int) (InventoryItem, error) { return items[i], nil })
+}
+
+// BulkUpdateItems replaces every item of the batch, identified by its ID
+func (sb *sandbox) BulkUpdateItems(items []InventoryItem, atomic bool) (*BulkResponse, error) {
+	return sb.bulkUpdateItems(len(items), atomic, func(i int) (InventoryItem, error) { return items[i], nil })
+}
+
+// bulkAddItems adds the items returned by item for every element of the
+// batch. An element that item cannot convert fails like an invalid item.
+func (sb *sandbox) bulkAddItems(size int, atomic bool, item func(i int) (InventoryItem, error)) (*BulkResponse, error) {
+	if err := validateBatchSize(size); err != nil {
+		return nil, err
+	}
+	return sb.applyBulk(size, atomic, func(i int) BulkResult {
+		newItem, err := item(i)
+		if err != nil {
+			return BulkResult{Status: http.StatusBadRequest, Error: err.Error()}
+		}
+		added, err := sb.addItem(newItem)
+		if err != nil {
+			return BulkResult{Status: http.StatusBadRequest, Error: err.Error()}
+		}
+		return BulkResult{ID: added.ID, Status: http.StatusCreated, Item: added}
+	}), nil
+}
+
+// bulkUpdateItems replaces the items returned by item for every element of the batch
+func (sb *sandbox) bulkUpdateItems(size int, atomic bool, item func(i int) (InventoryItem, error)) (*BulkResponse, error) {
+	if err := validateBatchSize(size); err != nil {
+		return nil, err
+	}
+	return sb.applyBulk(size, atomic, func(i int) BulkResult {
+		updatedData, err := item(i)
+		if updatedData.ID == 0 {
+			return BulkResult{Status: http.StatusBadRequest, Error: "id is required"}
+		}
+		if err != nil {
+			return BulkResult{ID: updatedData.ID, Status: http.StatusBadRequest, Error: err.Error()}
+		}
+		updated, err := sb.updateItem(updatedData.ID, updatedData)
+		if err != nil {
+			return BulkResult{ID: updatedData.ID, Status: statusForError(err), Error: err.Error()}
+		}
+		return BulkResult{ID: updated.ID, Status: http.StatusOK, Item: updated}
+	}), nil
+}
+
+// BulkDeleteItems deletes every item whose ID is listed in the batch
+func (sb *sandbox) BulkDeleteItems(ids []int, atomic bool) (*BulkResponse, error) {
+	if err := validateBatchSize(len(ids)); err != nil {
+		return nil, err
+	}
+	return sb.applyBulk(len(ids), atomic, func(i int) BulkResult {
+		if err := sb.deleteItem(ids[i]); err != nil {
+			return BulkResult{ID: ids[i], Status: statusForError(err), Error: err.Error()}
+		}
+		return BulkResult{ID: ids[i], Status: http.StatusOK}
+	}), nil
+}
+
+// applyBulk runs op for every element of the batch and collects the results.
+// In atomic mode the inventory is restored to its previous state as soon as
+// one element fails, and the elements that had succeeded are reported with
+// 424 Failed Dependency, as are the elements that were never attempted.
+func (sb *sandbox) applyBulk(size int, atomic bool, op func(i int) BulkResult) *BulkResponse {
+	sb.mu.Lock()
+	defer sb.unlock()
+	before := sb.state.snapshot()
+
+	response := &BulkResponse{Atomic: atomic, Results: make([]BulkResult, 0, size)}
+	for i := 0; i < size; i++ {
+		result := op(i)
+		result.Index = i
+		response.Results = append(response.Results, result)
+		if result.Error != "" {
+			response.Failed++
+			if atomic {
+				break
+			}
+		} else {
+			response.Succeeded++
+		}
+	}
+
+	if atomic && response.Failed > 0 {
+		sb.state = before
+		response.RolledBack = true
+		response.Succeeded = 0
+		for i := range response.Results {
+			if response.Results[i].Error == "" {
+				response.Results[i] = BulkResult{
+					Index:  i,
+					Status: http.StatusFailedDependency,
+					Error:  "rolled back because another element of the batch failed",
+				}
+			}
+		}
+		for i := len(response.Results); i < size; i++ {
+			response.Results = append(response.Results, BulkResult{
+				Index:  i,
+				Status: http.StatusFailedDependency,
+				Error:  "not processed because another element of the batch failed",
+			})
+		}
+	}
+	return response
+}
+
+// bulkStatus returns the HTTP status for a completed bulk operation
+func bulkStatus(response *BulkResponse, successStatus int) int {
+	switch {
+	case response.RolledBack:
+		return http.StatusBadRequest
+	case response.Failed > 0:
+		return http.StatusMultiStatus
+	}
+	return successStatus
+}
@@ -0,0 +1,374 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// Policies applied when deleting a category or supplier that items still reference
+const (
+	DeleteRestrict = "restrict"
+	DeleteCascade  = "cascade"
+)
+
+// DeletePolicy is used when a delete request does not specify on_delete
+var DeletePolicy = DeleteRestrict
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrSupplierNotFound = errors.New("supplier not found")
+	ErrDuplicateName    = errors.New("name is already taken")
+	ErrStillReferenced  = errors.New("still referenced")
+	errInvalidPolicy    = errors.New("on_delete must be one of: restrict, cascade")
+	errInvalidExpand    = errors.New("expand must be a comma separated list of: category, supplier")
+)
+
+// Category groups inventory items
+type Category struct {
+	ID          int    `json:"id" xml:"id"`
+	Name        string `json:"name" xml:"name"`
+	Description string `json:"description" xml:"description"`
+}
+
+// Supplier provides inventory items
+type Supplier struct {
+	ID    int    `json:"id" xml:"id"`
+	Name  string `json:"name" xml:"name"`
+	Email string `json:"email" xml:"email"`
+	Phone string `json:"phone" xml:"phone"`
+}
+
+// DeleteResult reports what a delete of a category or supplier removed
+type DeleteResult struct {
+	Message      string `json:"message" xml:"message"`
+	DeletedItems []int  `json:"deleted_items" xml:"deleted_items>id"`
+}
+
+func validateCategory(category Category) error {
+	if len(category.Name) < 3 || len(category.Name) > 50 {
+		return errors.New("name must be between 3 and 50 characters")
+	}
+	if len(category.Description) > 200 {
+		return errors.New("description cannot exceed 200 characters")
+	}
+	return nil
+}
+
+func validateSupplier(supplier Supplier) error {
+	if len(supplier.Name) < 3 || len(supplier.Name) > 50 {
+		return errors.New("name must be between 3 and 50 characters")
+	}
+	if address, err := mail.ParseAddress(supplier.Email); err != nil || address.Address != supplier.Email {
+		return errors.New("email must be a valid email address")
+	}
+	if len(supplier.Phone) > 20 {
+		return errors.New("phone cannot exceed 20 characters")
+	}
+	return nil
+}
+
+// ParseDeletePolicy returns the policy requested by on_delete, or DeletePolicy when empty
+func ParseDeletePolicy(policy string) (string, error) {
+	switch policy {
+	case "":
+		return DeletePolicy, nil
+	case DeleteRestrict, DeleteCascade:
+		return policy, nil
+	}
+	return "", errInvalidPolicy
+}
+
+// ParseExpand validates the expand query parameter of item listings
+func ParseExpand(expand string) (category bool, supplier bool, err error) {
+	if expand == "" {
+		return false, false, nil
+	}
+	for _, relation := range strings.Split(expand, ",") {
+		switch strings.TrimSpace(relation) {
+		case "category":
+			category = true
+		case "supplier":
+			supplier = true
+		default:
+			return false, false, errInvalidExpand
+		}
+	}
+	return category, supplier, nil
+}
+
+// ExpandItems embeds the category and/or supplier of every item
+func (sb *sandbox) ExpandItems(items []InventoryItem, category bool, supplier bool) []InventoryItem {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	for i := range items {
+		if category && items[i].CategoryID != 0 {
+			if index, err := sb.findCategoryIndex(items[i].CategoryID); err == nil {
+				embedded := sb.categories[index]
+				items[i].Category = &embedded
+			}
+		}
+		if supplier && items[i].SupplierID != 0 {
+			if index, err := sb.findSupplierIndex(items[i].SupplierID); err == nil {
+				embedded := sb.suppliers[index]
+				items[i].Supplier = &embedded
+			}
+		}
+	}
+	return items
+}
+
+// Category service functions
+func (sb *sandbox) GetAllCategories() []Category {
+	sb.mu.Lock()
+	defer sb.unlock()
+	return append([]Category(nil), sb.categories...)
+}
+
+func (sb *sandbox) GetCategoryByID(id int) (*Category, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findCategoryIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	category := sb.categories[index]
+	return &category, nil
+}
+
+func (sb *sandbox) AddCategory(newCategory Category) (*Category, error) {
+	if err := validateCategory(newCategory); err != nil {
+		return nil, err
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if err := sb.checkCategoryName(0, newCategory.Name); err != nil {
+		return nil, err
+	}
+	newCategory.ID = sb.nextCategoryID
+	sb.nextCategoryID++
+	sb.categories = append(sb.categories, newCategory)
+	return &newCategory, nil
+}
+
+func (sb *sandbox) UpdateCategory(id int, updatedData Category) (*Category, error) {
+	if err := validateCategory(updatedData); err != nil {
+		return nil, err
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findCategoryIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := sb.checkCategoryName(id, updatedData.Name); err != nil {
+		return nil, err
+	}
+	updatedData.ID = id
+	sb.categories[index] = updatedData
+	return &updatedData, nil
+}
+
+// DeleteCategory deletes a category. With the restrict policy a category that
+// items still belong to cannot be deleted, with cascade those items are moved
+// to the trash too, and come back without the category if they are restored.
+func (sb *sandbox) DeleteCategory(id int, policy string) (*DeleteResult, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findCategoryIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := sb.deleteReferencingItems(policy, func(item InventoryItem) bool { return item.CategoryID == id })
+	if err != nil {
+		return nil, fmt.Errorf("category is %w", err)
+	}
+	sb.categories = append(sb.categories[:index], sb.categories[index+1:]...)
+	return &DeleteResult{Message: "category deleted", DeletedItems: deleted}, nil
+}
+
+// GetCategoryItems returns the items that belong to a category
+func (sb *sandbox) GetCategoryItems(id int) ([]InventoryItem, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.findCategoryIndex(id); err != nil {
+		return nil, err
+	}
+	return sb.filterItems(func(item InventoryItem) bool { return item.CategoryID == id }), nil
+}
+
+// Supplier service functions
+func (sb *sandbox) GetAllSuppliers() []Supplier {
+	sb.mu.Lock()
+	defer sb.unlock()
+	return append([]Supplier(nil), sb.suppliers...)
+}
+
+func (sb *sandbox) GetSupplierByID(id int) (*Supplier, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findSupplierIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	supplier := sb.suppliers[index]
+	return &supplier, nil
+}
+
+func (sb *sandbox) AddSupplier(newSupplier Supplier) (*Supplier, error) {
+	if err := validateSupplier(newSupplier); err != nil {
+		return nil, err
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if err := sb.checkSupplierName(0, newSupplier.Name); err != nil {
+		return nil, err
+	}
+	newSupplier.ID = sb.nextSupplierID
+	sb.nextSupplierID++
+	sb.suppliers = append(sb.suppliers, newSupplier)
+	return &newSupplier, nil
+}
+
+func (sb *sandbox) UpdateSupplier(id int, updatedData Supplier) (*Supplier, error) {
+	if err := validateSupplier(updatedData); err != nil {
+		return nil, err
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findSupplierIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := sb.checkSupplierName(id, updatedData.Name); err != nil {
+		return nil, err
+	}
+	updatedData.ID = id
+	sb.suppliers[index] = updatedData
+	return &updatedData, nil
+}
+
+// DeleteSupplier deletes a supplier following the same rules as DeleteCategory
+func (sb *sandbox) DeleteSupplier(id int, policy string) (*DeleteResult, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findSupplierIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := sb.deleteReferencingItems(policy, func(item InventoryItem) bool { return item.SupplierID == id })
+	if err != nil {
+		return nil, fmt.Errorf("supplier is %w", err)
+	}
+	sb.suppliers = append(sb.suppliers[:index], sb.suppliers[index+1:]...)
+	return &DeleteResult{Message: "supplier deleted", DeletedItems: deleted}, nil
+}
+
+// GetSupplierItems returns the items provided by a supplier
+func (sb *sandbox) GetSupplierItems(id int) ([]InventoryItem, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.findSupplierIndex(id); err != nil {
+		return nil, err
+	}
+	return sb.filterItems(func(item InventoryItem) bool { return item.SupplierID == id }), nil
+}
+
+// validateReferences checks that the category and supplier of an item exist.
+// The caller must hold sb.mu.
+func (sb *sandbox) validateReferences(item InventoryItem) error {
+	if item.CategoryID != 0 {
+		if _, err := sb.findCategoryIndex(item.CategoryID); err != nil {
+			return fmt.Errorf("category %d does not exist", item.CategoryID)
+		}
+	}
+	if item.SupplierID != 0 {
+		if _, err := sb.findSupplierIndex(item.SupplierID); err != nil {
+			return fmt.Errorf("supplier %d does not exist", item.SupplierID)
+		}
+	}
+	return nil
+}
+
+// deleteReferencingItems applies the delete policy to the items matching
+// references and returns the IDs of the items it deleted. The caller must hold sb.mu.
+func (sb *sandbox) deleteReferencingItems(policy string, references func(InventoryItem) bool) ([]int, error) {
+	referencing := sb.filterItems(references)
+	deleted := []int{}
+	if len(referencing) == 0 {
+		return deleted, nil
+	}
+	if policy != DeleteCascade {
+		return nil, fmt.Errorf("%w by %d items", ErrStillReferenced, len(referencing))
+	}
+	for _, item := range referencing {
+		if err := sb.deleteItem(item.ID); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, item.ID)
+	}
+	return deleted, nil
+}
+
+// filterItems returns the items matching keep. The caller must hold sb.mu.
+func (sb *sandbox) filterItems(keep func(InventoryItem) bool) []InventoryItem {
+	result := []InventoryItem{}
+	for _, item := range sb.inventory {
+		if keep(item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func (sb *sandbox) checkCategoryName(id int, name string) error {
+	for _, category := range sb.categories {
+		if category.ID != id && strings.EqualFold(category.Name, name) {
+			return fmt.Errorf("category %w: %s", ErrDuplicateName, name)
+		}
+	}
+	return nil
+}
+
+func (sb *sandbox) checkSupplierName(id int, name string) error {
+	for _, supplier := range sb.suppliers {
+		if supplier.ID != id && strings.EqualFold(supplier.Name, name) {
+			return fmt.Errorf("supplier %w: %s", ErrDuplicateName, name)
+		}
+	}
+	return nil
+}
+
+func (sb *sandbox) findCategoryIndex(id int) (int, error) {
+	for i, category := range sb.categories {
+		if category.ID == id {
+			return i, nil
+		}
+	}
+	return -1, ErrCategoryNotFound
+}
+
+func (sb *sandbox) findSupplierIndex(id int) (int, error) {
+	for i, supplier := range sb.suppliers {
+		if supplier.ID == id {
+			return i, nil
+		}
+	}
+	return -1, ErrSupplierNotFound
+}
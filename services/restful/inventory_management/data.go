@@ -3,25 +3,47 @@ package restful
 // GetMockInventory returns the initial mock inventory items
 func GetMockInventory() []InventoryItem {
 	return []InventoryItem{
-		{ID: 1, Name: "Laptop", Description: "High-performance laptop", Price: 1500.0, Quantity: 10},
-		{ID: 2, Name: "Smartphone", Description: "Latest model smartphone", Price: 800.0, Quantity: 20},
-		{ID: 3, Name: "Tablet", Description: "Portable tablet", Price: 600.0, Quantity: 15},
-		{ID: 4, Name: "Headphones", Description: "Noise-cancelling headphones", Price: 200.0, Quantity: 25},
-		{ID: 5, Name: "Smartwatch", Description: "Fitness tracking smartwatch", Price: 300.0, Quantity: 12},
-		{ID: 6, Name: "Gaming Console", Description: "Next-gen gaming console", Price: 500.0, Quantity: 5},
-		{ID: 7, Name: "Monitor", Description: "4K Ultra HD monitor", Price: 400.0, Quantity: 8},
-		{ID: 8, Name: "Keyboard", Description: "Mechanical keyboard", Price: 100.0, Quantity: 30},
-		{ID: 9, Name: "Mouse", Description: "Wireless ergonomic mouse", Price: 50.0, Quantity: 40},
-		{ID: 10, Name: "External Hard Drive", Description: "1TB external hard drive", Price: 120.0, Quantity: 18},
-		{ID: 11, Name: "Webcam", Description: "1080p HD webcam", Price: 80.0, Quantity: 22},
-		{ID: 12, Name: "Microphone", Description: "Studio-quality microphone", Price: 150.0, Quantity: 7},
-		{ID: 13, Name: "Router", Description: "Dual-band Wi-Fi router", Price: 90.0, Quantity: 10},
-		{ID: 14, Name: "Printer", Description: "All-in-one printer", Price: 250.0, Quantity: 6},
-		{ID: 15, Name: "Projector", Description: "Portable mini projector", Price: 350.0, Quantity: 4},
-		{ID: 16, Name: "Power Bank", Description: "20,000mAh power bank", Price: 50.0, Quantity: 35},
-		{ID: 17, Name: "Drone", Description: "4K camera drone", Price: 800.0, Quantity: 3},
-		{ID: 18, Name: "VR Headset", Description: "Virtual reality headset", Price: 600.0, Quantity: 5},
-		{ID: 19, Name: "Smart Home Hub", Description: "Voice-controlled smart home hub", Price: 150.0, Quantity: 20},
-		{ID: 20, Name: "Fitness Tracker", Description: "Health and fitness tracker", Price: 100.0, Quantity: 25},
+		{ID: 1, Name: "Laptop", Description: "High-performance laptop", Price: 1500.0, Quantity: 10, CategoryID: 1, SupplierID: 1},
+		{ID: 2, Name: "Smartphone", Description: "Latest model smartphone", Price: 800.0, Quantity: 20, CategoryID: 2, SupplierID: 2},
+		{ID: 3, Name: "Tablet", Description: "Portable tablet", Price: 600.0, Quantity: 15, CategoryID: 2, SupplierID: 2},
+		{ID: 4, Name: "Headphones", Description: "Noise-cancelling headphones", Price: 200.0, Quantity: 25, CategoryID: 3, SupplierID: 3},
+		{ID: 5, Name: "Smartwatch", Description: "Fitness tracking smartwatch", Price: 300.0, Quantity: 12, CategoryID: 2, SupplierID: 2},
+		{ID: 6, Name: "Gaming Console", Description: "Next-gen gaming console", Price: 500.0, Quantity: 5, CategoryID: 6, SupplierID: 4},
+		{ID: 7, Name: "Monitor", Description: "4K Ultra HD monitor", Price: 400.0, Quantity: 8, CategoryID: 1, SupplierID: 1},
+		{ID: 8, Name: "Keyboard", Description: "Mechanical keyboard", Price: 100.0, Quantity: 30, CategoryID: 4, SupplierID: 1},
+		{ID: 9, Name: "Mouse", Description: "Wireless ergonomic mouse", Price: 50.0, Quantity: 40, CategoryID: 4, SupplierID: 1},
+		{ID: 10, Name: "External Hard Drive", Description: "1TB external hard drive", Price: 120.0, Quantity: 18, CategoryID: 4, SupplierID: 1},
+		{ID: 11, Name: "Webcam", Description: "1080p HD webcam", Price: 80.0, Quantity: 22, CategoryID: 4, SupplierID: 3},
+		{ID: 12, Name: "Microphone", Description: "Studio-quality microphone", Price: 150.0, Quantity: 7, CategoryID: 3, SupplierID: 3},
+		{ID: 13, Name: "Router", Description: "Dual-band Wi-Fi router", Price: 90.0, Quantity: 10, CategoryID: 1, SupplierID: 4},
+		{ID: 14, Name: "Printer", Description: "All-in-one printer", Price: 250.0, Quantity: 6, CategoryID: 1, SupplierID: 1},
+		{ID: 15, Name: "Projector", Description: "Portable mini projector", Price: 350.0, Quantity: 4, CategoryID: 6, SupplierID: 4},
+		{ID: 16, Name: "Power Bank", Description: "20,000mAh power bank", Price: 50.0, Quantity: 35, CategoryID: 4, SupplierID: 2},
+		{ID: 17, Name: "Drone", Description: "4K camera drone", Price: 800.0, Quantity: 3, CategoryID: 6, SupplierID: 4},
+		{ID: 18, Name: "VR Headset", Description: "Virtual reality headset", Price: 600.0, Quantity: 5, CategoryID: 6, SupplierID: 4},
+		{ID: 19, Name: "Smart Home Hub", Description: "Voice-controlled smart home hub", Price: 150.0, Quantity: 20, CategoryID: 5, SupplierID: 3},
+		{ID: 20, Name: "Fitness Tracker", Description: "Health and fitness tracker", Price: 100.0, Quantity: 25, CategoryID: 2, SupplierID: 2},
+	}
+}
+
+// GetMockCategories returns the initial categories of the mock inventory
+func GetMockCategories() []Category {
+	return []Category{
+		{ID: 1, Name: "Computers", Description: "Computers, monitors, printers and networking"},
+		{ID: 2, Name: "Mobile Devices", Description: "Phones, tablets and wearables"},
+		{ID: 3, Name: "Audio", Description: "Headphones and microphones"},
+		{ID: 4, Name: "Accessories", Description: "Peripherals, storage and power"},
+		{ID: 5, Name: "Smart Home", Description: "Connected devices for the home"},
+		{ID: 6, Name: "Entertainment", Description: "Gaming, video and drones"},
+	}
+}
+
+// GetMockSuppliers returns the initial suppliers of the mock inventory
+func GetMockSuppliers() []Supplier {
+	return []Supplier{
+		{ID: 1, Name: "TechSource Ltd", Email: "orders@techsource.example", Phone: "+1-555-0100"},
+		{ID: 2, Name: "MobileWorld Distribution", Email: "sales@mobileworld.example", Phone: "+1-555-0101"},
+		{ID: 3, Name: "SoundWave Supplies", Email: "hello@soundwave.example", Phone: "+1-555-0102"},
+		{ID: 4, Name: "FunTech Imports", Email: "contact@funtech.example", Phone: "+1-555-0103"},
 	}
 }
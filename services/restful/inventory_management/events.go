@@ -0,0 +1,53 @@
+package restful
+
+// Webhook event types
+const (
+	EventItemCreated    = "item.created"
+	EventItemUpdated    = "item.updated"
+	EventItemDeleted    = "item.deleted"
+	EventItemRestored   = "item.restored"
+	EventItemLowStock   = "item.low_stock"
+	EventOrderPlaced    = "order.placed"
+	EventOrderShipped   = "order.shipped"
+	EventOrderCancelled = "order.cancelled"
+	EventOrderRefunded  = "order.refunded"
+)
+
+// LowStockThreshold is the quantity at or below which an item is low on stock
+var LowStockThreshold = 5
+
+// webhookEvents are the events webhooks can subscribe to
+var webhookEvents = []string{
+	EventItemCreated, EventItemUpdated, EventItemDeleted, EventItemRestored, EventItemLowStock,
+	EventOrderPlaced, EventOrderShipped, EventOrderCancelled, EventOrderRefunded,
+}
+
+// feedEvents are the events that are also streamed on the item change feed
+var feedEvents = map[string]bool{EventItemCreated: true, EventItemUpdated: true, EventItemDeleted: true, EventItemRestored: true}
+
+// event is a webhook event waiting for the lock of its tenant to be released
+type event struct {
+	name string
+	data interface{}
+}
+
+// emit records an event to publish when sb.mu is released. Events are only
+// published once the operation that raised them has completed, so that rolled
+// back changes never reach a webhook or the change feed. The caller must hold sb.mu.
+func (sb *tenant) emit(name string, data interface{}) {
+	sb.pendingEvents = append(sb.pendingEvents, event{name: name, data: data})
+}
+
+// unlock publishes the events raised while sb.mu was held and releases it.
+// Publishing never blocks, and doing it before unlocking keeps the events in
+// the order of the changes.
+func (sb *tenant) unlock() {
+	for _, e := range sb.pendingEvents {
+		sb.dispatcher.Publish(e.name, e.data)
+		if feedEvents[e.name] {
+			sb.feed.Publish(e.name, e.data)
+		}
+	}
+	sb.pendingEvents = nil
+	sb.mu.Unlock()
+}
@@ -0,0 +1,188 @@
+package restful
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MaxImageSize is the largest image, in bytes, that can be uploaded
+var MaxImageSize int64 = 5 << 20
+
+// MaxImagesPerItem is the maximum number of images an item can have
+const MaxImagesPerItem = 10
+
+// supportedImageTypes are the media types accepted for uploads, as detected from the file content
+var supportedImageTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var (
+	ErrImageNotFound        = errors.New("image not found")
+	ErrImageTooLarge        = errors.New("image is too large")
+	ErrUnsupportedImageType = errors.New("file must be a PNG, JPEG, GIF or WebP image")
+	ErrNotMultipart         = errors.New("payload must be multipart/form-data with the image in the file field")
+)
+
+// ItemImage is an image attached to an inventory item. The content itself is
+// only served by GET /items/:id/images/:image_id, which url points to under
+// the prefix of the request, such as /v2.
+type ItemImage struct {
+	ID          int       `json:"id" xml:"id"`
+	ItemID      int       `json:"item_id" xml:"item_id"`
+	Filename    string    `json:"filename" xml:"filename"`
+	ContentType string    `json:"content_type" xml:"content_type"`
+	Size        int       `json:"size" xml:"size"`
+	ETag        string    `json:"etag" xml:"etag"`
+	URL         string    `json:"url" xml:"url"`
+	CreatedAt   time.Time `json:"created_at" xml:"created_at"`
+	Data        []byte    `json:"-" xml:"-" csv:"-"`
+}
+
+// AddItemImage stores an image for an item. The content type is sniffed from
+// data, so neither the filename nor the declared type of the upload matter.
+func (sb *sandbox) AddItemImage(itemID int, filename string, data []byte) (*ItemImage, error) {
+	if len(data) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	if int64(len(data)) > MaxImageSize {
+		return nil, imageTooLarge()
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := supportedImageTypes[contentType]
+	if !ok {
+		return nil, ErrUnsupportedImageType
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.getItemByID(itemID); err != nil {
+		return nil, err
+	}
+	if len(sb.filterImages(itemID)) >= MaxImagesPerItem {
+		return nil, fmt.Errorf("item cannot have more than %d images", MaxImagesPerItem)
+	}
+
+	image := ItemImage{
+		ID:          sb.nextImageID,
+		ItemID:      itemID,
+		Filename:    imageFilename(filename, sb.nextImageID, extension),
+		ContentType: contentType,
+		Size:        len(data),
+		ETag:        fmt.Sprintf(`"%x"`, sha256.Sum256(data)),
+		CreatedAt:   sb.now().UTC(),
+		Data:        data,
+	}
+	sb.nextImageID++
+	sb.images = append(sb.images, image)
+	return &image, nil
+}
+
+// linkImages fills in the url of images under a route prefix such as /v1, or
+// "" for the unprefixed routes
+func linkImages(prefix string, images ...ItemImage) []ItemImage {
+	for i := range images {
+		images[i].URL = fmt.Sprintf("%s/items/%d/images/%d", prefix, images[i].ItemID, images[i].ID)
+	}
+	return images
+}
+
+func imageTooLarge() error {
+	return fmt.Errorf("%w: the limit is %d bytes", ErrImageTooLarge, MaxImageSize)
+}
+
+// GetItemImages returns the images of an item, without their content
+func (sb *sandbox) GetItemImages(itemID int) ([]ItemImage, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.getItemByID(itemID); err != nil {
+		return nil, err
+	}
+	return sb.filterImages(itemID), nil
+}
+
+// GetItemImage returns an image of an item including its content
+func (sb *sandbox) GetItemImage(itemID int, imageID int) (*ItemImage, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findImageIndex(itemID, imageID)
+	if err != nil {
+		return nil, err
+	}
+	image := sb.images[index]
+	return &image, nil
+}
+
+// DeleteItemImage removes an image of an item
+func (sb *sandbox) DeleteItemImage(itemID int, imageID int) error {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findImageIndex(itemID, imageID)
+	if err != nil {
+		return err
+	}
+	sb.images = append(sb.images[:index], sb.images[index+1:]...)
+	return nil
+}
+
+// deleteImagesForItem removes the images of a purged item. The caller must hold sb.mu.
+func (sb *tenant) deleteImagesForItem(itemID int) {
+	kept := sb.images[:0]
+	for _, image := range sb.images {
+		if image.ItemID != itemID {
+			kept = append(kept, image)
+		}
+	}
+	sb.images = kept
+}
+
+// filterImages returns the images of an item. The caller must hold sb.mu.
+func (sb *sandbox) filterImages(itemID int) []ItemImage {
+	result := []ItemImage{}
+	for _, image := range sb.images {
+		if image.ItemID == itemID {
+			result = append(result, image)
+		}
+	}
+	return result
+}
+
+func (sb *sandbox) findImageIndex(itemID int, imageID int) (int, error) {
+	if _, err := sb.getItemByID(itemID); err != nil {
+		return -1, err
+	}
+	for i, image := range sb.images {
+		if image.ID == imageID && image.ItemID == itemID {
+			return i, nil
+		}
+	}
+	return -1, ErrImageNotFound
+}
+
+// imageFilename keeps the base name of an uploaded file, falling back to a
+// generated name, and makes sure it ends with the extension of the detected type
+func imageFilename(filename string, id int, extension string) string {
+	name := filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		name = fmt.Sprintf("image-%d", id)
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	current := strings.ToLower(filepath.Ext(name))
+	if current == extension || (extension == ".jpg" && current == ".jpeg") {
+		return name
+	}
+	return name + extension
+}
@@ -0,0 +1,224 @@
+package restful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// MaxActiveJobs is the number of jobs that can be queued or running at the same time
+const MaxActiveJobs = 5
+
+// jobSteps is the number of progress updates a job goes through
+const jobSteps = 10
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobNotFinished = errors.New("job has not finished yet")
+	ErrJobFinished    = errors.New("job has already finished")
+	ErrJobNoResult    = errors.New("job has no result")
+	ErrTooManyJobs    = fmt.Errorf("too many jobs: at most %d can run at the same time", MaxActiveJobs)
+)
+
+// Job tracks a long running operation started by an asynchronous endpoint
+type Job struct {
+	ID          int        `json:"id" xml:"id"`
+	Type        string     `json:"type" xml:"type"`
+	Status      string     `json:"status" xml:"status"`
+	Progress    int        `json:"progress" xml:"progress"`
+	CreatedAt   time.Time  `json:"created_at" xml:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty" xml:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" xml:"completed_at,omitempty"`
+	EstimatedAt time.Time  `json:"estimated_completion_at" xml:"estimated_completion_at"`
+	ResultURL   string     `json:"result_url,omitempty" xml:"result_url,omitempty"`
+	Error       string     `json:"error,omitempty" xml:"error,omitempty"`
+
+	result interface{}
+	cancel context.CancelFunc
+}
+
+// stopJobs cancels the jobs that are still queued or running
+func (sb *tenant) stopJobs() {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	for _, job := range sb.jobs {
+		if job.Status == JobQueued || job.Status == JobRunning {
+			job.cancel()
+			job.Status = JobCancelled
+		}
+	}
+}
+
+// startJob queues a job that waits for duration, reporting its progress, and
+// then stores the outcome of work. The caller must hold sb.mu; work is
+// called with sb.mu held.
+func (sb *sandbox) startJob(jobType string, duration time.Duration, work func() (interface{}, error)) (*Job, error) {
+	active := 0
+	for _, job := range sb.jobs {
+		if job.Status == JobQueued || job.Status == JobRunning {
+			active++
+		}
+	}
+	if active >= MaxActiveJobs {
+		return nil, ErrTooManyJobs
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	createdAt := sb.now()
+	job := &Job{
+		ID:          sb.nextJobID,
+		Type:        jobType,
+		Status:      JobQueued,
+		CreatedAt:   createdAt,
+		EstimatedAt: createdAt.Add(duration),
+		cancel:      cancel,
+	}
+	sb.nextJobID++
+	sb.jobs = append(sb.jobs, job)
+
+	go sb.runJob(ctx, job, duration, work)
+	copied := *job
+	return &copied, nil
+}
+
+// runJob runs a job in the background. It records its times with the tenant's
+// clock, as the request that created the job may have overridden the time.
+func (sb *tenant) runJob(ctx context.Context, job *Job, duration time.Duration, work func() (interface{}, error)) {
+	sb.mu.Lock()
+	if job.Status != JobQueued {
+		sb.mu.Unlock()
+		return
+	}
+	startedAt := sb.clock.Now()
+	job.Status = JobRunning
+	job.StartedAt = &startedAt
+	sb.mu.Unlock()
+
+	step := duration / jobSteps
+	for i := 1; i <= jobSteps; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(step):
+		}
+		sb.mu.Lock()
+		if job.Status != JobRunning {
+			sb.mu.Unlock()
+			return
+		}
+		if i < jobSteps {
+			job.Progress = i * 100 / jobSteps
+		} else {
+			finishJob(job, sb.clock.Now(), work)
+		}
+		sb.mu.Unlock()
+	}
+}
+
+// finishJob runs the work of a job and records its outcome. The caller must hold sb.mu.
+func finishJob(job *Job, completedAt time.Time, work func() (interface{}, error)) {
+	job.CompletedAt = &completedAt
+	result, err := work()
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+		return
+	}
+	job.Status = JobSucceeded
+	job.Progress = 100
+	job.result = result
+	job.ResultURL = fmt.Sprintf("/jobs/%d/result", job.ID)
+}
+
+// GetJobs returns every job, most recent last
+func (sb *sandbox) GetJobs() []Job {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	result := make([]Job, 0, len(sb.jobs))
+	for _, job := range sb.jobs {
+		result = append(result, *job)
+	}
+	return result
+}
+
+// GetJob returns a single job
+func (sb *sandbox) GetJob(id int) (*Job, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	job, err := sb.findJob(id)
+	if err != nil {
+		return nil, err
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// GetJobResult returns the result of a job that succeeded
+func (sb *sandbox) GetJobResult(id int) (interface{}, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	job, err := sb.findJob(id)
+	if err != nil {
+		return nil, err
+	}
+	switch job.Status {
+	case JobQueued, JobRunning:
+		return nil, ErrJobNotFinished
+	case JobSucceeded:
+		return job.result, nil
+	}
+	return nil, fmt.Errorf("%w: it is %s", ErrJobNoResult, job.Status)
+}
+
+// CancelJob stops a job that is queued or running
+func (sb *sandbox) CancelJob(id int) (*Job, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	job, err := sb.findJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != JobQueued && job.Status != JobRunning {
+		return nil, fmt.Errorf("%w: it is %s", ErrJobFinished, job.Status)
+	}
+	job.cancel()
+	completedAt := sb.now()
+	job.Status = JobCancelled
+	job.CompletedAt = &completedAt
+	copied := *job
+	return &copied, nil
+}
+
+// RetryAfter estimates how many seconds a client polling at a time should wait before polling an unfinished job again
+func (job Job) RetryAfter(now time.Time) int {
+	remaining := job.EstimatedAt.Sub(now)
+	seconds := int((remaining + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+func (sb *sandbox) findJob(id int) (*Job, error) {
+	for _, job := range sb.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, ErrJobNotFound
+}
@@ -0,0 +1,211 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Order statuses
+const (
+	OrderPlaced    = "placed"
+	OrderShipped   = "shipped"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+)
+
+// MovementReturn is recorded when a cancelled or refunded order puts stock back
+const MovementReturn = "return"
+
+// MaxOrderLines is the maximum number of lines an order may contain
+const MaxOrderLines = 50
+
+var (
+	ErrOrderNotFound          = errors.New("order not found")
+	ErrInvalidOrderTransition = errors.New("invalid order transition")
+)
+
+// OrderLineRequest asks for a quantity of an inventory item
+type OrderLineRequest struct {
+	ItemID   int `json:"item_id" xml:"item_id"`
+	Quantity int `json:"quantity" xml:"quantity"`
+}
+
+// OrderRequest is the payload used to place an order
+type OrderRequest struct {
+	Lines []OrderLineRequest `json:"lines" xml:"lines>line"`
+}
+
+// OrderLine is a line of a placed order, priced when the order was placed
+type OrderLine struct {
+	ItemID    int     `json:"item_id" xml:"item_id"`
+	Name      string  `json:"name" xml:"name"`
+	Quantity  int     `json:"quantity" xml:"quantity"`
+	UnitPrice float64 `json:"unit_price" xml:"unit_price"`
+	LineTotal float64 `json:"line_total" xml:"line_total"`
+}
+
+// Order consumes inventory when placed and gives it back when cancelled or refunded
+type Order struct {
+	ID        int         `json:"id" xml:"id"`
+	Status    string      `json:"status" xml:"status"`
+	Lines     []OrderLine `json:"lines" xml:"lines>line"`
+	Total     float64     `json:"total" xml:"total"`
+	CreatedAt time.Time   `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" xml:"updated_at"`
+}
+
+// orderTransitions lists the statuses an order can move to from each status
+var orderTransitions = map[string][]string{
+	OrderPlaced:  {OrderShipped, OrderCancelled},
+	OrderShipped: {OrderRefunded},
+}
+
+// PlaceOrder checks that every line can be fulfilled and then takes the stock
+// of all lines at once. Either every line is fulfilled or nothing changes.
+func (sb *sandbox) PlaceOrder(request OrderRequest) (*Order, error) {
+	if len(request.Lines) == 0 {
+		return nil, errors.New("order must contain at least one line")
+	}
+	if len(request.Lines) > MaxOrderLines {
+		return nil, fmt.Errorf("order cannot exceed %d lines", MaxOrderLines)
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	lines := make([]OrderLine, 0, len(request.Lines))
+	indexes := make([]int, 0, len(request.Lines))
+	seen := map[int]bool{}
+	for i, line := range request.Lines {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("lines[%d]: %w", i, errInvalidStockQuantity)
+		}
+		if seen[line.ItemID] {
+			return nil, fmt.Errorf("lines[%d]: item %d appears more than once", i, line.ItemID)
+		}
+		seen[line.ItemID] = true
+
+		index, err := sb.findItemIndex(line.ItemID)
+		if err != nil {
+			return nil, fmt.Errorf("lines[%d]: item %d does not exist", i, line.ItemID)
+		}
+		item := sb.inventory[index]
+		if available := item.Quantity - sb.reservedQuantity(item.ID); line.Quantity > available {
+			return nil, fmt.Errorf("lines[%d]: %w: only %d of item %d available", i, ErrInsufficientStock, available, item.ID)
+		}
+		lines = append(lines, OrderLine{
+			ItemID:    item.ID,
+			Name:      item.Name,
+			Quantity:  line.Quantity,
+			UnitPrice: item.Price,
+			LineTotal: item.Price * float64(line.Quantity),
+		})
+		indexes = append(indexes, index)
+	}
+
+	createdAt := sb.now()
+	order := Order{ID: sb.nextOrderID, Status: OrderPlaced, Lines: lines, CreatedAt: createdAt, UpdatedAt: createdAt}
+	sb.nextOrderID++
+	for i, line := range lines {
+		sb.inventory[indexes[i]].Quantity -= line.Quantity
+		sb.recordMovement(line.ItemID, MovementSell, -line.Quantity, sb.inventory[indexes[i]].Quantity,
+			fmt.Sprintf("order %d placed", order.ID))
+		order.Total += line.LineTotal
+	}
+	sb.orders = append(sb.orders, order)
+	sb.emit(EventOrderPlaced, order)
+	return &order, nil
+}
+
+// GetOrders returns every order, optionally filtered by status
+func (sb *sandbox) GetOrders(status string) []Order {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	result := []Order{}
+	for _, order := range sb.orders {
+		if status == "" || order.Status == status {
+			result = append(result, order)
+		}
+	}
+	return result
+}
+
+// GetOrder returns a single order
+func (sb *sandbox) GetOrder(id int) (*Order, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findOrderIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	order := sb.orders[index]
+	return &order, nil
+}
+
+// ShipOrder marks a placed order as shipped, after which it can only be refunded
+func (sb *sandbox) ShipOrder(id int) (*Order, error) {
+	return sb.transitionOrder(id, OrderShipped)
+}
+
+// CancelOrder cancels an order that has not been shipped and puts its stock back
+func (sb *sandbox) CancelOrder(id int) (*Order, error) {
+	return sb.transitionOrder(id, OrderCancelled)
+}
+
+// RefundOrder refunds a shipped order and puts its stock back
+func (sb *sandbox) RefundOrder(id int) (*Order, error) {
+	return sb.transitionOrder(id, OrderRefunded)
+}
+
+func (sb *sandbox) transitionOrder(id int, status string) (*Order, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findOrderIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	order := &sb.orders[index]
+	if !canTransition(order.Status, status) {
+		return nil, fmt.Errorf("%w: order is %s and cannot be %s", ErrInvalidOrderTransition, order.Status, status)
+	}
+
+	if status == OrderCancelled || status == OrderRefunded {
+		// Items deleted since the order was placed have no stock to give back
+		for _, line := range order.Lines {
+			itemIndex, err := sb.findItemIndex(line.ItemID)
+			if err != nil {
+				continue
+			}
+			sb.inventory[itemIndex].Quantity += line.Quantity
+			sb.recordMovement(line.ItemID, MovementReturn, line.Quantity, sb.inventory[itemIndex].Quantity,
+				fmt.Sprintf("order %d %s", order.ID, status))
+		}
+	}
+	order.Status = status
+	order.UpdatedAt = sb.now()
+	updated := *order
+	sb.emit("order."+status, updated)
+	return &updated, nil
+}
+
+func canTransition(from, to string) bool {
+	for _, status := range orderTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (sb *sandbox) findOrderIndex(id int) (int, error) {
+	for i, order := range sb.orders {
+		if order.ID == id {
+			return i, nil
+		}
+	}
+	return -1, ErrOrderNotFound
+}
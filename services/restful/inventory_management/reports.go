@@ -0,0 +1,112 @@
+package restful
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ReportDuration is how long report jobs take when the request does not specify duration_seconds
+var ReportDuration = 10 * time.Second
+
+// MaxReportDuration is the longest artificial duration a report request can ask for
+const MaxReportDuration = 300 * time.Second
+
+// JobInventoryValuation is the type of the jobs started by POST /reports/inventory-valuation
+const JobInventoryValuation = "inventory-valuation"
+
+var errInvalidDuration = errors.New("duration_seconds must be between 0 and 300")
+
+// ReportRequest configures a report job
+type ReportRequest struct {
+	// DurationSeconds overrides ReportDuration when set
+	DurationSeconds *int `json:"duration_seconds" xml:"duration_seconds"`
+}
+
+// CategoryValuation is the value of the stock of a category
+type CategoryValuation struct {
+	CategoryID int     `json:"category_id" xml:"category_id"`
+	Name       string  `json:"name" xml:"name"`
+	ItemCount  int     `json:"item_count" xml:"item_count"`
+	Quantity   int     `json:"quantity" xml:"quantity"`
+	Value      float64 `json:"value" xml:"value"`
+}
+
+// ItemValuation is the value of the stock of an item
+type ItemValuation struct {
+	ItemID   int     `json:"item_id" xml:"item_id"`
+	Name     string  `json:"name" xml:"name"`
+	Quantity int     `json:"quantity" xml:"quantity"`
+	Price    float64 `json:"price" xml:"price"`
+	Value    float64 `json:"value" xml:"value"`
+}
+
+// InventoryValuation values the stock on hand at the time the report job completes
+type InventoryValuation struct {
+	GeneratedAt   time.Time           `json:"generated_at" xml:"generated_at"`
+	ItemCount     int                 `json:"item_count" xml:"item_count"`
+	TotalQuantity int                 `json:"total_quantity" xml:"total_quantity"`
+	TotalValue    float64             `json:"total_value" xml:"total_value"`
+	Categories    []CategoryValuation `json:"categories" xml:"categories>category"`
+	Items         []ItemValuation     `json:"items" xml:"items>item"`
+}
+
+// StartInventoryValuation queues a job producing an InventoryValuation
+func (sb *sandbox) StartInventoryValuation(request ReportRequest) (*Job, error) {
+	duration := ReportDuration
+	if request.DurationSeconds != nil {
+		duration = time.Duration(*request.DurationSeconds) * time.Second
+		if duration < 0 || duration > MaxReportDuration {
+			return nil, errInvalidDuration
+		}
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+	return sb.startJob(JobInventoryValuation, duration, func() (interface{}, error) {
+		// The report is generated in the background, at the time of the tenant's clock
+		return sb.tenant.sandbox().valueInventory(), nil
+	})
+}
+
+// valueInventory computes the valuation report. The caller must hold sb.mu.
+func (sb *sandbox) valueInventory() *InventoryValuation {
+	report := &InventoryValuation{
+		GeneratedAt: sb.now(),
+		Categories:  []CategoryValuation{},
+		Items:       []ItemValuation{},
+	}
+	byCategory := map[int]*CategoryValuation{}
+	for _, item := range sb.inventory {
+		value := item.Price * float64(item.Quantity)
+		report.ItemCount++
+		report.TotalQuantity += item.Quantity
+		report.TotalValue += value
+		report.Items = append(report.Items, ItemValuation{
+			ItemID:   item.ID,
+			Name:     item.Name,
+			Quantity: item.Quantity,
+			Price:    item.Price,
+			Value:    value,
+		})
+
+		category, ok := byCategory[item.CategoryID]
+		if !ok {
+			category = &CategoryValuation{CategoryID: item.CategoryID, Name: "Uncategorized"}
+			if index, err := sb.findCategoryIndex(item.CategoryID); err == nil {
+				category.Name = sb.categories[index].Name
+			}
+			byCategory[item.CategoryID] = category
+		}
+		category.ItemCount++
+		category.Quantity += item.Quantity
+		category.Value += value
+	}
+	for _, category := range byCategory {
+		report.Categories = append(report.Categories, *category)
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		return report.Categories[i].CategoryID < report.Categories[j].CategoryID
+	})
+	return report
+}
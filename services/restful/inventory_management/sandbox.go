@@ -0,0 +1,127 @@
+package restful
+
+import (
+	"sync"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/clock"
+	"github.com/abhivaikar/playpi/services/restful/sse"
+	"github.com/abhivaikar/playpi/services/restful/webhooks"
+	"github.com/abhivaikar/playpi/services/tenancy"
+	"github.com/gin-gonic/gin"
+)
+
+// state is the inventory of a tenant and every record derived from it
+// (trash, stock movements, reservations, orders, categories, suppliers, images),
+// along with the events raised by the operation in progress
+type state struct {
+	inventory         []InventoryItem
+	nextID            int
+	trash             []InventoryItem
+	movements         []StockMovement
+	nextMovementID    int
+	reservations      []Reservation
+	nextReservationID int
+	orders            []Order
+	nextOrderID       int
+	categories        []Category
+	nextCategoryID    int
+	suppliers         []Supplier
+	nextSupplierID    int
+	images            []ItemImage
+	nextImageID       int
+	pendingEvents     []event
+}
+
+// snapshot copies the state so that a failed multi-step operation can be rolled back
+func (s state) snapshot() state {
+	s.inventory = append([]InventoryItem(nil), s.inventory...)
+	s.trash = append([]InventoryItem(nil), s.trash...)
+	s.movements = append([]StockMovement(nil), s.movements...)
+	s.reservations = append([]Reservation(nil), s.reservations...)
+	s.orders = append([]Order(nil), s.orders...)
+	s.categories = append([]Category(nil), s.categories...)
+	s.suppliers = append([]Supplier(nil), s.suppliers...)
+	s.images = append([]ItemImage(nil), s.images...)
+	s.pendingEvents = append([]event(nil), s.pendingEvents...)
+	return s
+}
+
+// tenant is everything a tenant owns: its state, its report jobs, its
+// webhook subscriptions, its change feed and its clock
+type tenant struct {
+	// mu guards the state and the jobs. Exported methods take the lock and
+	// release it with unlock, their unexported counterparts expect the caller to hold it.
+	mu sync.Mutex
+	state
+	jobs       []*Job
+	nextJobID  int
+	dispatcher *webhooks.Dispatcher
+	feed       *sse.Feed
+	clock      *clock.Clock
+}
+
+// sandbox is a tenant as seen by a request. now is the time the request
+// happens at: the time of the tenant's clock, unless the request overrides it.
+type sandbox struct {
+	*tenant
+	now func() time.Time
+}
+
+// sandboxes holds every tenant
+var sandboxes = tenancy.NewRegistry(newTenant, (*tenant).close)
+
+// newTenant creates a tenant seeded with the mock inventory and catalog
+func newTenant() *tenant {
+	inventory := GetMockInventory()
+	categories := GetMockCategories()
+	suppliers := GetMockSuppliers()
+	sb := &tenant{
+		state: state{
+			inventory:         inventory,
+			nextID:            len(inventory) + 1,
+			trash:             []InventoryItem{},
+			movements:         []StockMovement{},
+			nextMovementID:    1,
+			reservations:      []Reservation{},
+			nextReservationID: 1,
+			orders:            []Order{},
+			nextOrderID:       1,
+			categories:        categories,
+			nextCategoryID:    len(categories) + 1,
+			suppliers:         suppliers,
+			nextSupplierID:    len(suppliers) + 1,
+			images:            []ItemImage{},
+			nextImageID:       1,
+		},
+		jobs:       []*Job{},
+		nextJobID:  1,
+		dispatcher: webhooks.NewDispatcher(webhookEvents...),
+		feed:       sse.NewFeed(),
+		clock:      clock.New(),
+	}
+	sb.dispatcher.SetClock(sb.clock.Now)
+	return sb
+}
+
+// close stops the background work of a discarded tenant and disconnects its clients
+func (sb *tenant) close() {
+	sb.stopJobs()
+	sb.dispatcher.Reset()
+	sb.feed.Reset()
+}
+
+// sandbox returns a view of the tenant at the time of its clock
+func (sb *tenant) sandbox() *sandbox {
+	return &sandbox{tenant: sb, now: sb.clock.Now}
+}
+
+// sandboxOf returns the sandbox of the tenant of a request, at the time set
+// by its X-PlayPI-Now header if there is one
+func sandboxOf(c *gin.Context) *sandbox {
+	t := tenancy.From[*tenant](c)
+	if override, ok := clock.Override(c); ok {
+		return &sandbox{tenant: t, now: func() time.Time { return override }}
+	}
+	return t.sandbox()
+}
@@ -0,0 +1,350 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MaxSearchQueryLength is the longest search query accepted, in characters
+const MaxSearchQueryLength = 200
+
+// Relevance of a match in the name and in the description of an item. A
+// term that only matches the beginning of a word counts for half.
+const (
+	nameWeight        = 3.0
+	descriptionWeight = 1.0
+)
+
+// snippetWords is the number of words of the description shown around its first match
+const snippetWords = 12
+
+// Highlighted matches are wrapped in these tags
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+var (
+	errMissingQuery       = errors.New("q is required")
+	errQueryTooLong       = fmt.Errorf("q cannot exceed %d characters", MaxSearchQueryLength)
+	errEmptyQuery         = errors.New("q must contain at least one word, phrase or filter")
+	errUnterminatedPhrase = errors.New("q contains a quoted phrase without its closing quote")
+)
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// SearchQuery is a parsed search query. Every term must match the name or the
+// description of an item, no excluded term may match, and every filter must hold.
+type SearchQuery struct {
+	terms    [][]string
+	excluded [][]string
+	filters  []searchFilter
+}
+
+// searchFilter compares a numeric field of items, as in price:<100
+type searchFilter struct {
+	field    string
+	operator string
+	value    float64
+	negated  bool
+}
+
+// SearchHighlights are the matched fields of an item with every match wrapped
+// in <mark> tags. Long descriptions are cut down to a snippet around the first match.
+type SearchHighlights struct {
+	Name        string `json:"name,omitempty" xml:"name,omitempty"`
+	Description string `json:"description,omitempty" xml:"description,omitempty"`
+}
+
+// SearchResult is an item matching a search query along with its relevance
+type SearchResult struct {
+	InventoryItem
+	Score      float64          `json:"score" xml:"score"`
+	Highlights SearchHighlights `json:"highlights" xml:"highlights"`
+}
+
+// ParseSearchQuery parses the q parameter of GET /items/search. Words are
+// matched case-insensitively against the beginning of the words of items,
+// "quoted phrases" must match whole consecutive words, a leading - excludes
+// the items matching a word or phrase, and price: and quantity: filters
+// compare numbers with <, <=, >, >= or equality.
+func ParseSearchQuery(q string) (*SearchQuery, error) {
+	if strings.TrimSpace(q) == "" {
+		return nil, errMissingQuery
+	}
+	if len([]rune(q)) > MaxSearchQueryLength {
+		return nil, errQueryTooLong
+	}
+
+	query := &SearchQuery{}
+	for rest := strings.TrimLeftFunc(q, unicode.IsSpace); rest != ""; rest = strings.TrimLeftFunc(rest, unicode.IsSpace) {
+		negated := false
+		if len(rest) > 1 && rest[0] == '-' {
+			negated = true
+			rest = rest[1:]
+		}
+
+		var words []string
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return nil, errUnterminatedPhrase
+			}
+			words = tokenize(rest[1 : end+1])
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				end = len(rest)
+			}
+			word := rest[:end]
+			rest = rest[end:]
+			if field, value, ok := strings.Cut(word, ":"); ok {
+				filter, err := parseSearchFilter(strings.ToLower(field), value)
+				if err != nil {
+					return nil, err
+				}
+				filter.negated = negated
+				query.filters = append(query.filters, filter)
+				continue
+			}
+			words = tokenize(word)
+		}
+
+		if len(words) == 0 {
+			continue
+		}
+		if negated {
+			query.excluded = append(query.excluded, words)
+		} else {
+			query.terms = append(query.terms, words)
+		}
+	}
+
+	if len(query.terms) == 0 && len(query.excluded) == 0 && len(query.filters) == 0 {
+		return nil, errEmptyQuery
+	}
+	return query, nil
+}
+
+func parseSearchFilter(field string, value string) (searchFilter, error) {
+	if field != "price" && field != "quantity" {
+		return searchFilter{}, fmt.Errorf("unknown filter %s: filters can use price or quantity", field)
+	}
+	filter := searchFilter{field: field, operator: "="}
+	for _, operator := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(value, operator) {
+			filter.operator = operator
+			value = value[len(operator):]
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return searchFilter{}, fmt.Errorf("%s filter must be a number, optionally preceded by <, <=, > or >=", field)
+	}
+	filter.value = number
+	return filter, nil
+}
+
+// SearchItems returns the items matching a query, the most relevant first.
+// Items with the same score, such as every result of a query made only of
+// filters, are ordered by ID.
+func (sb *sandbox) SearchItems(query *SearchQuery) []SearchResult {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	results := []SearchResult{}
+	for _, item := range sb.inventory {
+		if result, ok := query.match(item); ok {
+			results = append(results, result)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results
+}
+
+// token is a lowercase word of a field along with its position in the original text
+type token struct {
+	word       string
+	start, end int
+}
+
+// searchField is a field of an item split into words
+type searchField struct {
+	text    string
+	tokens  []token
+	weight  float64
+	matches [][2]int
+}
+
+func (query *SearchQuery) match(item InventoryItem) (SearchResult, bool) {
+	for _, filter := range query.filters {
+		if filter.holds(item) == filter.negated {
+			return SearchResult{}, false
+		}
+	}
+
+	name := newSearchField(item.Name, nameWeight)
+	description := newSearchField(item.Description, descriptionWeight)
+	fields := []*searchField{name, description}
+	for _, words := range query.excluded {
+		for _, field := range fields {
+			if score, _ := field.find(words); score > 0 {
+				return SearchResult{}, false
+			}
+		}
+	}
+
+	result := SearchResult{InventoryItem: item}
+	for _, words := range query.terms {
+		termScore := 0.0
+		for _, field := range fields {
+			score, spans := field.find(words)
+			termScore += score
+			field.matches = append(field.matches, spans...)
+		}
+		if termScore == 0 {
+			return SearchResult{}, false
+		}
+		result.Score += termScore
+	}
+	result.Highlights = SearchHighlights{
+		Name:        name.highlight(0),
+		Description: description.highlight(snippetWords),
+	}
+	return result, true
+}
+
+func (filter searchFilter) holds(item InventoryItem) bool {
+	actual := item.Price
+	if filter.field == "quantity" {
+		actual = float64(item.Quantity)
+	}
+	switch filter.operator {
+	case "<":
+		return actual < filter.value
+	case "<=":
+		return actual <= filter.value
+	case ">":
+		return actual > filter.value
+	case ">=":
+		return actual >= filter.value
+	}
+	return actual == filter.value
+}
+
+func tokenize(text string) []string {
+	words := []string{}
+	for _, t := range tokenizeField(text) {
+		words = append(words, t.word)
+	}
+	return words
+}
+
+func tokenizeField(text string) []token {
+	tokens := []token{}
+	for _, span := range wordPattern.FindAllStringIndex(text, -1) {
+		tokens = append(tokens, token{word: strings.ToLower(text[span[0]:span[1]]), start: span[0], end: span[1]})
+	}
+	return tokens
+}
+
+func newSearchField(text string, weight float64) *searchField {
+	return &searchField{text: text, tokens: tokenizeField(text), weight: weight}
+}
+
+// find returns the score of the matches of a word or phrase in the field and
+// the spans of text they cover. A single word also matches the beginning of
+// longer words, for half the score; phrases only match whole words.
+func (field *searchField) find(words []string) (float64, [][2]int) {
+	score := 0.0
+	var spans [][2]int
+	for i := 0; i+len(words) <= len(field.tokens); i++ {
+		if len(words) == 1 {
+			switch {
+			case field.tokens[i].word == words[0]:
+				score += field.weight
+			case strings.HasPrefix(field.tokens[i].word, words[0]):
+				score += field.weight / 2
+			default:
+				continue
+			}
+			spans = append(spans, [2]int{field.tokens[i].start, field.tokens[i].end})
+			continue
+		}
+
+		matched := true
+		for j, word := range words {
+			if field.tokens[i+j].word != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			score += field.weight * float64(len(words))
+			spans = append(spans, [2]int{field.tokens[i].start, field.tokens[i+len(words)-1].end})
+		}
+	}
+	return score, spans
+}
+
+// highlight wraps the matches of the field in <mark> tags. When maxWords is
+// positive and the field is longer, only maxWords words around the first
+// match are kept. Fields without matches are not highlighted.
+func (field *searchField) highlight(maxWords int) string {
+	if len(field.matches) == 0 {
+		return ""
+	}
+	sort.Slice(field.matches, func(i, j int) bool { return field.matches[i][0] < field.matches[j][0] })
+
+	from, to := 0, len(field.text)
+	prefix, suffix := "", ""
+	if maxWords > 0 && len(field.tokens) > maxWords {
+		first := 0
+		for first < len(field.tokens) && field.tokens[first].end <= field.matches[0][0] {
+			first++
+		}
+		start := first - maxWords/3
+		if start < 0 {
+			start = 0
+		}
+		if start+maxWords > len(field.tokens) {
+			start = len(field.tokens) - maxWords
+		}
+		from, to = field.tokens[start].start, field.tokens[start+maxWords-1].end
+		if start > 0 {
+			prefix = "…"
+		}
+		if start+maxWords < len(field.tokens) {
+			suffix = "…"
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString(prefix)
+	position := from
+	for _, span := range field.matches {
+		start, end := span[0], span[1]
+		if start < position {
+			start = position // Overlaps the previous match
+		}
+		if end > to {
+			end = to
+		}
+		if start >= end {
+			continue
+		}
+		builder.WriteString(field.text[position:start])
+		builder.WriteString(highlightStart + field.text[start:end] + highlightEnd)
+		position = end
+	}
+	builder.WriteString(field.text[position:to])
+	builder.WriteString(suffix)
+	return builder.String()
+}
@@ -1,17 +1,32 @@
 package restful
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/abhivaikar/playpi/services/clock"
+	"github.com/abhivaikar/playpi/services/negotiation"
+	"github.com/abhivaikar/playpi/services/restful/idempotency"
+	"github.com/abhivaikar/playpi/services/restful/openapi"
+	"github.com/abhivaikar/playpi/services/restful/pagination"
+	"github.com/abhivaikar/playpi/services/restful/webhooks"
+	"github.com/abhivaikar/playpi/services/tenancy"
 	"github.com/gin-gonic/gin"
 )
 
+// IdempotencyKeyTTL is how long Idempotency-Key headers on creates are remembered
+var IdempotencyKeyTTL = idempotency.DefaultTTL
+
 // StartServer initializes and starts the RESTful API server
 func StartServer() {
-	inventory = GetMockInventory() // Use mock inventory for production
-	nextID = len(inventory) + 1
+	sandboxes.Reset()
 	r := setupRouter()
 	fmt.Println("RESTful API is running on http://localhost:8080")
 	log.Fatal(r.Run(":8080"))
@@ -19,97 +34,966 @@ func StartServer() {
 
 // StartServerForTesting initializes the router for testing
 func StartServerForTesting() *gin.Engine {
-	inventory = GetMockInventory() // Use mock inventory for production
-	nextID = len(inventory) + 1
+	sandboxes.Reset()
 	return setupRouter()
 }
 
+// versionPrefixes are the prefixes the API is mounted under besides the unprefixed aliases of version 1
+var versionPrefixes = []string{"/v1", "/v2"}
+
 func setupRouter() *gin.Engine {
 
 	r := gin.Default()
+	r.Use(selectVersion(r))
+	r.Use(negotiation.Middleware(append(versionedRoutes("/items/events", "/items/:id/images", "/items/:id/images/:image_id"), openapi.AssetsRoute)...))
+	if ValidateRequests {
+		r.Use(openapi.ValidationMiddleware(openAPISpec, versionPrefixes...))
+	}
+	r.Use(tenancy.Middleware(sandboxes))
+	r.Use(clock.Middleware())
+	openapi.Register(r, openAPISpec)
+	idempotencyKeys := idempotency.NewStore(IdempotencyKeyTTL)
+	idempotencyKeys.Scope = tenancy.ID
+
+	// The API is served under /v1 and, as deprecated aliases, without a prefix.
+	// /v2 changes the representation of items and serves everything else unchanged.
+	registerRoutes(r.Group("/v1"), idempotencyKeys, 1)
+	registerRoutes(r.Group(""), idempotencyKeys, 1)
+	registerRoutes(r.Group("/v2"), idempotencyKeys, 2)
+
+	// /webhooks - Subscriptions to item and order events, with their delivery logs
+	webhooks.Register(r, idempotencyKeys, func(c *gin.Context) *webhooks.Dispatcher {
+		return sandboxOf(c).dispatcher
+	})
+
+	// /admin/clock - The clock of the tenant, which can be frozen, set and advanced
+	clock.Register(r, func(c *gin.Context) *clock.Clock {
+		return sandboxOf(c).clock
+	})
+
+	return r
+}
+
+// registerRoutes adds the versioned routes of the API to a group
+func registerRoutes(api *gin.RouterGroup, idempotencyKeys *idempotency.Store, version int) {
+	api.Use(versionHeaders(version))
+	prefix := strings.TrimSuffix(api.BasePath(), "/")
+
+	// GET /items - Get all items, optionally along with the items in the trash
+	api.GET("/items", func(c *gin.Context) {
+		category, supplier, err := ParseExpand(c.Query("expand"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		includeDeleted, err := parseIncludeDeletedParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		page, err := pagination.Parse(c.Query("limit"), c.Query("offset"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		items := sandboxOf(c).GetAllItems()
+		if includeDeleted {
+			items = sandboxOf(c).GetItemsIncludingDeleted()
+		}
+		renderItems(c, version, sandboxOf(c).ExpandItems(pagination.Apply(c, items, page), category, supplier))
+	})
+
+	// GET /items/search - Search the names and descriptions of items, the most relevant first
+	api.GET("/items/search", func(c *gin.Context) {
+		query, err := ParseSearchQuery(c.Query("q"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	// GET /items - Get all items
-	r.GET("/items", func(c *gin.Context) {
-		c.JSON(http.StatusOK, GetAllItems())
+		page, err := pagination.Parse(c.Query("limit"), c.Query("offset"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		results := pagination.Apply(c, sandboxOf(c).SearchItems(query), page)
+		if version == 2 {
+			negotiation.Render(c, http.StatusOK, sandboxOf(c).ToSearchResultsV2(results))
+			return
+		}
+		negotiation.Render(c, http.StatusOK, results)
+	})
+
+	// GET /items/trash - Get the deleted items that have not been purged yet
+	api.GET("/items/trash", func(c *gin.Context) {
+		renderItems(c, version, sandboxOf(c).GetTrash())
+	})
+
+	// POST /items/:id/restore - Move a deleted item back to the inventory
+	api.POST("/items/:id/restore", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		item, err := sandboxOf(c).RestoreItem(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		renderItem(c, http.StatusOK, version, item)
+	})
+
+	// GET /items/events - Stream the changes to items as Server-Sent Events
+	api.GET("/items/events", func(c *gin.Context) {
+		sandboxOf(c).feed.Handler()(c)
 	})
 
 	// POST /items - Add a new item
-	r.POST("/items", func(c *gin.Context) {
-		var newItem InventoryItem
-		if err := c.ShouldBindJSON(&newItem); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input format"})
+	api.POST("/items", idempotencyKeys.Middleware(), func(c *gin.Context) {
+		newItem, err := bindItem(c, version)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		item, err := AddItem(newItem)
+		item, err := sandboxOf(c).AddItem(newItem)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusCreated, item)
+		renderItem(c, http.StatusCreated, version, item)
 	})
 
 	// PUT /items/:id - Update an existing item
-	r.PUT("/items/:id", func(c *gin.Context) {
-		var updatedData InventoryItem
-		if err := c.ShouldBindJSON(&updatedData); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input format"})
+	api.PUT("/items/:id", func(c *gin.Context) {
+		updatedData, err := bindItem(c, version)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		id, err := parseIDParam(c)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
 			return
 		}
 
-		item, err := UpdateItem(id, updatedData)
+		item, err := sandboxOf(c).UpdateItem(id, updatedData)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, item)
+		renderItem(c, http.StatusOK, version, item)
 	})
 
 	// PATCH /items/:id - Partially update an item
-	r.PATCH("/items/:id", func(c *gin.Context) {
+	api.PATCH("/items/:id", func(c *gin.Context) {
 		id, err := parseIDParam(c)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
 			return
 		}
 
+		var model interface{} = InventoryItem{}
+		if version == 2 {
+			model = ItemV2{}
+		}
 		var updates map[string]interface{}
-		if err := c.ShouldBindJSON(&updates); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input format"})
+		if err := negotiation.BindPartial(c, &updates, model); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
 			return
 		}
+		if version == 2 {
+			if updates, err = PatchFromV2(updates); err != nil {
+				negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
 
-		item, err := PatchItem(id, updates)
+		item, err := sandboxOf(c).PatchItem(id, updates)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, item)
+		renderItem(c, http.StatusOK, version, item)
 	})
 
-	// DELETE /items/:id - Delete an item
-	r.DELETE("/items/:id", func(c *gin.Context) {
+	// DELETE /items/:id - Move an item to the trash
+	api.DELETE("/items/:id", func(c *gin.Context) {
 		id, err := parseIDParam(c)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
 			return
 		}
 
-		err = DeleteItem(id)
+		err = sandboxOf(c).DeleteItem(id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			negotiation.Render(c, http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "item deleted"})
+		negotiation.Render(c, http.StatusOK, gin.H{"message": "item deleted"})
 	})
 
-	return r
+	// POST /items/bulk - Add several items at once
+	api.POST("/items/bulk", idempotencyKeys.Middleware(), func(c *gin.Context) {
+		atomic, err := parseAtomicParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var response *BulkResponse
+		if version == 2 {
+			var items []ItemV2
+			if err := negotiation.Bind(c, &items); err != nil {
+				negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+				return
+			}
+			response, err = sandboxOf(c).BulkAddItemsV2(items, atomic)
+		} else {
+			var items []InventoryItem
+			if err := negotiation.Bind(c, &items); err != nil {
+				negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+				return
+			}
+			response, err = sandboxOf(c).BulkAddItems(items, atomic)
+		}
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		renderBulk(c, bulkStatus(response, http.StatusCreated), version, response)
+	})
+
+	// PUT /items/bulk - Update several items at once
+	api.PUT("/items/bulk", func(c *gin.Context) {
+		atomic, err := parseAtomicParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var response *BulkResponse
+		if version == 2 {
+			var items []ItemV2
+			if err := negotiation.Bind(c, &items); err != nil {
+				negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+				return
+			}
+			response, err = sandboxOf(c).BulkUpdateItemsV2(items, atomic)
+		} else {
+			var items []InventoryItem
+			if err := negotiation.Bind(c, &items); err != nil {
+				negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+				return
+			}
+			response, err = sandboxOf(c).BulkUpdateItems(items, atomic)
+		}
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		renderBulk(c, bulkStatus(response, http.StatusOK), version, response)
+	})
+
+	// DELETE /items/bulk - Delete several items at once
+	api.DELETE("/items/bulk", func(c *gin.Context) {
+		atomic, err := parseAtomicParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var ids []int
+		if err := negotiation.Bind(c, &ids); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+
+		response, err := sandboxOf(c).BulkDeleteItems(ids, atomic)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, bulkStatus(response, http.StatusOK), response)
+	})
+
+	// GET /items/:id/stock - Get the stock level of an item
+	api.GET("/items/:id/stock", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		level, err := sandboxOf(c).GetStockLevel(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, level)
+	})
+
+	// POST /items/:id/stock - Receive, sell or write off stock
+	api.POST("/items/:id/stock", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		var adjustment StockAdjustment
+		if err := negotiation.Bind(c, &adjustment); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+
+		movement, err := sandboxOf(c).AdjustStock(id, adjustment)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, movement)
+	})
+
+	// GET /items/:id/stock/movements - Get the stock ledger of an item
+	api.GET("/items/:id/stock/movements", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+		if err != nil || limit < 0 {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+
+		history, err := sandboxOf(c).GetStockMovements(id, c.Query("type"), limit)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, history)
+	})
+
+	// POST /items/:id/reservations - Reserve stock for a limited time
+	api.POST("/items/:id/reservations", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		var request ReservationRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+
+		reservation, err := sandboxOf(c).ReserveStock(id, request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, reservation)
+	})
+
+	// GET /items/:id/reservations - Get the reservations of an item
+	api.GET("/items/:id/reservations", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		itemReservations, err := sandboxOf(c).GetReservations(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, itemReservations)
+	})
+
+	// GET /reservations/:id - Get a reservation
+	api.GET("/reservations/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		reservation, err := sandboxOf(c).GetReservation(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, reservation)
+	})
+
+	// POST /reservations/:id/commit - Sell the reserved stock
+	api.POST("/reservations/:id/commit", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		reservation, err := sandboxOf(c).CommitReservation(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, reservation)
+	})
+
+	// DELETE /reservations/:id - Release the reserved stock
+	api.DELETE("/reservations/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		reservation, err := sandboxOf(c).ReleaseReservation(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, reservation)
+	})
+
+	// POST /orders - Place an order
+	api.POST("/orders", idempotencyKeys.Middleware(), func(c *gin.Context) {
+		var request OrderRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+
+		order, err := sandboxOf(c).PlaceOrder(request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, order)
+	})
+
+	// GET /orders - Get all orders
+	api.GET("/orders", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).GetOrders(c.Query("status")))
+	})
+
+	// GET /orders/:id - Get an order
+	api.GET("/orders/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		order, err := sandboxOf(c).GetOrder(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, order)
+	})
+
+	// POST /orders/:id/ship - Ship a placed order
+	api.POST("/orders/:id/ship", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		order, err := sandboxOf(c).ShipOrder(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, order)
+	})
+
+	// POST /orders/:id/cancel - Cancel an order and restore its stock
+	api.POST("/orders/:id/cancel", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		order, err := sandboxOf(c).CancelOrder(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, order)
+	})
+
+	// POST /orders/:id/refund - Refund a shipped order and restore its stock
+	api.POST("/orders/:id/refund", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		order, err := sandboxOf(c).RefundOrder(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, order)
+	})
+
+	// GET /categories - Get all categories
+	api.GET("/categories", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).GetAllCategories())
+	})
+
+	// POST /categories - Add a new category
+	api.POST("/categories", idempotencyKeys.Middleware(), func(c *gin.Context) {
+		var newCategory Category
+		if err := negotiation.Bind(c, &newCategory); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+
+		category, err := sandboxOf(c).AddCategory(newCategory)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, category)
+	})
+
+	// GET /categories/:id - Get a category
+	api.GET("/categories/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		category, err := sandboxOf(c).GetCategoryByID(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, category)
+	})
+
+	// PUT /categories/:id - Update a category
+	api.PUT("/categories/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		var updatedData Category
+		if err := negotiation.Bind(c, &updatedData); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+
+		category, err := sandboxOf(c).UpdateCategory(id, updatedData)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, category)
+	})
+
+	// DELETE /categories/:id - Delete a category, refusing or cascading to the items that reference it
+	api.DELETE("/categories/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		policy, err := ParseDeletePolicy(c.Query("on_delete"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := sandboxOf(c).DeleteCategory(id, policy)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, result)
+	})
+
+	// GET /categories/:id/items - Get the items of a category
+	api.GET("/categories/:id/items", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		category, supplier, err := ParseExpand(c.Query("expand"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		items, err := sandboxOf(c).GetCategoryItems(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		renderItems(c, version, sandboxOf(c).ExpandItems(items, category, supplier))
+	})
+
+	// GET /suppliers - Get all suppliers
+	api.GET("/suppliers", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).GetAllSuppliers())
+	})
+
+	// POST /suppliers - Add a new supplier
+	api.POST("/suppliers", idempotencyKeys.Middleware(), func(c *gin.Context) {
+		var newSupplier Supplier
+		if err := negotiation.Bind(c, &newSupplier); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+
+		supplier, err := sandboxOf(c).AddSupplier(newSupplier)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, supplier)
+	})
+
+	// GET /suppliers/:id - Get a supplier
+	api.GET("/suppliers/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		supplier, err := sandboxOf(c).GetSupplierByID(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, supplier)
+	})
+
+	// PUT /suppliers/:id - Update a supplier
+	api.PUT("/suppliers/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		var updatedData Supplier
+		if err := negotiation.Bind(c, &updatedData); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+
+		supplier, err := sandboxOf(c).UpdateSupplier(id, updatedData)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, supplier)
+	})
+
+	// DELETE /suppliers/:id - Delete a supplier, refusing or cascading to the items that reference it
+	api.DELETE("/suppliers/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		policy, err := ParseDeletePolicy(c.Query("on_delete"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := sandboxOf(c).DeleteSupplier(id, policy)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, result)
+	})
+
+	// GET /suppliers/:id/items - Get the items of a supplier
+	api.GET("/suppliers/:id/items", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		category, supplier, err := ParseExpand(c.Query("expand"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		items, err := sandboxOf(c).GetSupplierItems(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		renderItems(c, version, sandboxOf(c).ExpandItems(items, category, supplier))
+	})
+
+	// POST /items/:id/images - Upload an image of an item
+	api.POST("/items/:id/images", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		filename, data, err := readImageUpload(c)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		image, err := sandboxOf(c).AddItemImage(id, filename, data)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		linked := linkImages(prefix, *image)[0]
+		c.Header("Location", linked.URL)
+		negotiation.Render(c, http.StatusCreated, linked)
+	})
+
+	// GET /items/:id/images - Get the images of an item
+	api.GET("/items/:id/images", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		itemImages, err := sandboxOf(c).GetItemImages(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, linkImages(prefix, itemImages...))
+	})
+
+	// GET /items/:id/images/:image_id - Download an image, supporting Range requests
+	api.GET("/items/:id/images/:image_id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+		imageID, err := parseImageIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid image ID"})
+			return
+		}
+
+		image, err := sandboxOf(c).GetItemImage(id, imageID)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := negotiation.Negotiate(c.GetHeader("Accept"), image.ContentType); err != nil {
+			negotiation.Render(c, http.StatusNotAcceptable, gin.H{
+				"error":     "none of the requested media types are supported",
+				"supported": []string{image.ContentType},
+			})
+			return
+		}
+
+		disposition := "inline"
+		if download, _ := strconv.ParseBool(c.Query("download")); download {
+			disposition = "attachment"
+		}
+		c.Header("Content-Type", image.ContentType)
+		c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": image.Filename}))
+		c.Header("ETag", image.ETag)
+		http.ServeContent(c.Writer, c.Request, image.Filename, image.CreatedAt, bytes.NewReader(image.Data))
+	})
+
+	// DELETE /items/:id/images/:image_id - Delete an image
+	api.DELETE("/items/:id/images/:image_id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+		imageID, err := parseImageIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid image ID"})
+			return
+		}
+
+		if err := sandboxOf(c).DeleteItemImage(id, imageID); err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, gin.H{"message": "image deleted"})
+	})
+
+	// POST /reports/inventory-valuation - Start a report job
+	api.POST("/reports/inventory-valuation", idempotencyKeys.Middleware(), func(c *gin.Context) {
+		var request ReportRequest
+		if c.Request.ContentLength != 0 {
+			if err := negotiation.Bind(c, &request); err != nil {
+				negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+				return
+			}
+		}
+
+		job, err := sandboxOf(c).StartInventoryValuation(request)
+		if err != nil {
+			if errors.Is(err, ErrTooManyJobs) {
+				c.Header("Retry-After", strconv.Itoa(int(ReportDuration.Seconds())))
+			}
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Location", fmt.Sprintf("/jobs/%d", job.ID))
+		c.Header("Retry-After", strconv.Itoa(job.RetryAfter(sandboxOf(c).now())))
+		negotiation.Render(c, http.StatusAccepted, job)
+	})
+
+	// GET /jobs - Get all jobs
+	api.GET("/jobs", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).GetJobs())
+	})
+
+	// GET /jobs/:id - Poll a job
+	api.GET("/jobs/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		job, err := sandboxOf(c).GetJob(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		if job.Status == JobQueued || job.Status == JobRunning {
+			c.Header("Retry-After", strconv.Itoa(job.RetryAfter(sandboxOf(c).now())))
+		}
+		negotiation.Render(c, http.StatusOK, job)
+	})
+
+	// GET /jobs/:id/result - Download the result of a job that succeeded
+	api.GET("/jobs/:id/result", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		result, err := sandboxOf(c).GetJobResult(id)
+		if err != nil {
+			if errors.Is(err, ErrJobNotFinished) {
+				if job, err := sandboxOf(c).GetJob(id); err == nil {
+					c.Header("Retry-After", strconv.Itoa(job.RetryAfter(sandboxOf(c).now())))
+				}
+			}
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, result)
+	})
+
+	// DELETE /jobs/:id - Cancel a job
+	api.DELETE("/jobs/:id", func(c *gin.Context) {
+		id, err := parseIDParam(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid ID"})
+			return
+		}
+
+		job, err := sandboxOf(c).CancelJob(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, job)
+	})
+}
+
+// bindItem reads an item payload in the representation of the API version
+func bindItem(c *gin.Context, version int) (InventoryItem, error) {
+	if version == 2 {
+		var item ItemV2
+		if err := negotiation.Bind(c, &item); err != nil {
+			return InventoryItem{}, errors.New("invalid input format")
+		}
+		return FromItemV2(item)
+	}
+	var item InventoryItem
+	if err := negotiation.Bind(c, &item); err != nil {
+		return InventoryItem{}, errors.New("invalid input format")
+	}
+	return item, nil
+}
+
+// renderItem renders an item in the representation of the API version
+func renderItem(c *gin.Context, status int, version int, item *InventoryItem) {
+	if version == 2 {
+		negotiation.Render(c, status, sandboxOf(c).ToItemV2(item))
+		return
+	}
+	negotiation.Render(c, status, item)
+}
+
+// renderItems renders a list of items in the representation of the API version
+func renderItems(c *gin.Context, version int, items []InventoryItem) {
+	if version == 2 {
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).ToItemsV2(items))
+		return
+	}
+	negotiation.Render(c, http.StatusOK, items)
+}
+
+// renderBulk renders the response of a bulk operation in the representation of the API version
+func renderBulk(c *gin.Context, status int, version int, response *BulkResponse) {
+	if version == 2 {
+		negotiation.Render(c, status, sandboxOf(c).ToBulkResponseV2(response))
+		return
+	}
+	negotiation.Render(c, status, response)
+}
+
+// versionedRoutes returns the routes under every version prefix as well as unprefixed
+func versionedRoutes(routes ...string) []string {
+	result := append([]string(nil), routes...)
+	for _, prefix := range versionPrefixes {
+		for _, route := range routes {
+			result = append(result, prefix+route)
+		}
+	}
+	return result
 }
 
 // parseIDParam extracts the ID parameter from the request
@@ -121,3 +1005,98 @@ func parseIDParam(c *gin.Context) (int, error) {
 	}
 	return id, nil
 }
+
+// parseImageIDParam extracts the image_id parameter from the request
+func parseImageIDParam(c *gin.Context) (int, error) {
+	var id int
+	_, err := fmt.Sscanf(c.Param("image_id"), "%d", &id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// multipartOverhead is the room left for the multipart boundaries and headers around an uploaded image
+const multipartOverhead = 64 << 10
+
+// readImageUpload returns the name and content of the file field of a
+// multipart/form-data request, refusing payloads larger than MaxImageSize
+func readImageUpload(c *gin.Context) (string, []byte, error) {
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" {
+		return "", nil, ErrNotMultipart
+	}
+	limit := MaxImageSize + multipartOverhead
+	if c.Request.ContentLength > limit {
+		return "", nil, imageTooLarge()
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			return "", nil, imageTooLarge()
+		case errors.Is(err, http.ErrMissingFile):
+			return "", nil, errors.New("file is required")
+		}
+		return "", nil, errors.New("invalid multipart payload")
+	}
+	if header.Size > MaxImageSize {
+		return "", nil, imageTooLarge()
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return "", nil, errors.New("invalid multipart payload")
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, MaxImageSize+1))
+	if err != nil {
+		return "", nil, errors.New("invalid multipart payload")
+	}
+	return header.Filename, data, nil
+}
+
+// parseAtomicParam reads the optional atomic query parameter of bulk operations
+func parseAtomicParam(c *gin.Context) (bool, error) {
+	raw := c.DefaultQuery("atomic", "false")
+	atomic, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, errors.New("atomic must be true or false")
+	}
+	return atomic, nil
+}
+
+// parseIncludeDeletedParam reads the include_deleted query parameter, which defaults to false
+func parseIncludeDeletedParam(c *gin.Context) (bool, error) {
+	includeDeleted, err := strconv.ParseBool(c.DefaultQuery("include_deleted", "false"))
+	if err != nil {
+		return false, errors.New("include_deleted must be true or false")
+	}
+	return includeDeleted, nil
+}
+
+// statusForError maps service errors onto HTTP status codes
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, ErrItemNotFound), errors.Is(err, ErrReservationNotFound), errors.Is(err, ErrOrderNotFound),
+		errors.Is(err, ErrCategoryNotFound), errors.Is(err, ErrSupplierNotFound), errors.Is(err, ErrImageNotFound),
+		errors.Is(err, ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInsufficientStock), errors.Is(err, ErrReservationNotActive), errors.Is(err, ErrInvalidOrderTransition),
+		errors.Is(err, ErrDuplicateName), errors.Is(err, ErrStillReferenced),
+		errors.Is(err, ErrJobNotFinished), errors.Is(err, ErrJobFinished), errors.Is(err, ErrNotInTrash):
+		return http.StatusConflict
+	case errors.Is(err, ErrReservationExpired), errors.Is(err, ErrJobNoResult):
+		return http.StatusGone
+	case errors.Is(err, ErrTooManyJobs):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrNotMultipart), errors.Is(err, ErrUnsupportedImageType):
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,180 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func performBulkRequest(t *testing.T, r *gin.Engine, method, url, payload string) (int, BulkResponse) {
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	var response BulkResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	require.NoError(t, err)
+	return resp.Code, response
+}
+
+func TestBulkAddItems(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Add a valid batch", func(t *testing.T) {
+		payload := `[
+			{"name": "Desk Lamp", "description": "LED desk lamp", "price": 25.0, "quantity": 3},
+			{"name": "Standing Desk", "description": "Height adjustable", "price": 450.0, "quantity": 2}
+		]`
+		status, response := performBulkRequest(t, r, http.MethodPost, "/items/bulk", payload)
+
+		require.Equal(t, http.StatusCreated, status)
+		require.Equal(t, 2, response.Succeeded)
+		require.Equal(t, 0, response.Failed)
+		require.Equal(t, http.StatusCreated, response.Results[0].Status)
+		require.Equal(t, 21, response.Results[0].ID)
+		require.Equal(t, "Standing Desk", response.Results[1].Item.Name)
+		require.Len(t, defaultSandbox().GetAllItems(), 22)
+	})
+
+	t.Run("Partial success returns Multi-Status", func(t *testing.T) {
+		payload := `[
+			{"name": "Office Chair", "price": 120.0, "quantity": 4},
+			{"name": "TV", "price": 300.0, "quantity": 1},
+			{"name": "Whiteboard", "price": 20000.0, "quantity": 1}
+		]`
+		status, response := performBulkRequest(t, r, http.MethodPost, "/items/bulk", payload)
+
+		require.Equal(t, http.StatusMultiStatus, status)
+		require.False(t, response.RolledBack)
+		require.Equal(t, 1, response.Succeeded)
+		require.Equal(t, 2, response.Failed)
+		require.Equal(t, http.StatusCreated, response.Results[0].Status)
+		require.Equal(t, http.StatusBadRequest, response.Results[1].Status)
+		require.Equal(t, "name must be between 3 and 50 characters", response.Results[1].Error)
+		require.Equal(t, "price must be a positive number not exceeding 10,000", response.Results[2].Error)
+		require.Len(t, defaultSandbox().GetAllItems(), 23)
+	})
+
+	t.Run("Atomic batch rolls back on failure", func(t *testing.T) {
+		payload := `[
+			{"name": "Bookshelf", "price": 80.0, "quantity": 2},
+			{"name": "Filing Cabinet", "price": 150.0, "quantity": -1},
+			{"name": "Coat Rack", "price": 35.0, "quantity": 5}
+		]`
+		status, response := performBulkRequest(t, r, http.MethodPost, "/items/bulk?atomic=true", payload)
+
+		require.Equal(t, http.StatusBadRequest, status)
+		require.True(t, response.RolledBack)
+		require.Equal(t, 0, response.Succeeded)
+		require.Len(t, response.Results, 3)
+		require.Equal(t, http.StatusFailedDependency, response.Results[0].Status)
+		require.Equal(t, "quantity must be at least 0", response.Results[1].Error)
+		require.Equal(t, http.StatusFailedDependency, response.Results[2].Status)
+		require.Len(t, defaultSandbox().GetAllItems(), 23)
+
+		// IDs allocated by the rolled back batch are reused
+		item, err := defaultSandbox().AddItem(InventoryItem{Name: "Bookshelf", Price: 80.0, Quantity: 2})
+		require.NoError(t, err)
+		require.Equal(t, 24, item.ID)
+	})
+
+	t.Run("Validation Error - Empty batch", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/items/bulk", bytes.NewBufferString(`[]`))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "batch must contain at least one element")
+	})
+
+	t.Run("Validation Error - Batch too large", func(t *testing.T) {
+		elements := make([]string, MaxBulkSize+1)
+		for i := range elements {
+			elements[i] = `{"name": "Cable", "price": 5.0, "quantity": 1}`
+		}
+		req, _ := http.NewRequest(http.MethodPost, "/items/bulk", bytes.NewBufferString("["+strings.Join(elements, ",")+"]"))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "batch cannot exceed 100 elements")
+	})
+
+	t.Run("Validation Error - Invalid atomic flag", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/items/bulk?atomic=maybe", bytes.NewBufferString(`[]`))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "atomic must be true or false")
+	})
+}
+
+func TestBulkUpdateItems(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Partial success reports not found items", func(t *testing.T) {
+		payload := `[
+			{"id": 1, "name": "Laptop Pro", "price": 2000.0, "quantity": 5},
+			{"id": 999, "name": "Ghost Item", "price": 1.0, "quantity": 1},
+			{"name": "No ID", "price": 1.0, "quantity": 1}
+		]`
+		status, response := performBulkRequest(t, r, http.MethodPut, "/items/bulk", payload)
+
+		require.Equal(t, http.StatusMultiStatus, status)
+		require.Equal(t, http.StatusOK, response.Results[0].Status)
+		require.Equal(t, http.StatusNotFound, response.Results[1].Status)
+		require.Equal(t, "item not found", response.Results[1].Error)
+		require.Equal(t, "id is required", response.Results[2].Error)
+
+		item, err := defaultSandbox().GetItemByID(1)
+		require.NoError(t, err)
+		require.Equal(t, "Laptop Pro", item.Name)
+	})
+
+	t.Run("Atomic update leaves items untouched on failure", func(t *testing.T) {
+		payload := `[
+			{"id": 2, "name": "Smartphone X", "price": 900.0, "quantity": 5},
+			{"id": 3, "name": "T", "price": 1.0, "quantity": 1}
+		]`
+		status, response := performBulkRequest(t, r, http.MethodPut, "/items/bulk?atomic=true", payload)
+
+		require.Equal(t, http.StatusBadRequest, status)
+		require.True(t, response.RolledBack)
+
+		item, err := defaultSandbox().GetItemByID(2)
+		require.NoError(t, err)
+		require.Equal(t, "Smartphone", item.Name)
+	})
+}
+
+func TestBulkDeleteItems(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Delete a valid batch", func(t *testing.T) {
+		status, response := performBulkRequest(t, r, http.MethodDelete, "/items/bulk", `[1, 2]`)
+
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 2, response.Succeeded)
+		require.Len(t, defaultSandbox().GetAllItems(), 18)
+	})
+
+	t.Run("Atomic delete restores items on failure", func(t *testing.T) {
+		status, response := performBulkRequest(t, r, http.MethodDelete, "/items/bulk?atomic=true", `[3, 1]`)
+
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Equal(t, http.StatusNotFound, response.Results[1].Status)
+		_, err := defaultSandbox().GetItemByID(3)
+		require.NoError(t, err)
+	})
+}
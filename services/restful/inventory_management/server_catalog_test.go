@@ -0,0 +1,218 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategories(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Create a category", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/categories", `{"name": "Cameras", "description": "Photo and video cameras"}`)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		var category Category
+		err := json.Unmarshal(resp.Body.Bytes(), &category)
+		require.NoError(t, err)
+		require.Equal(t, 7, category.ID)
+	})
+
+	t.Run("Category names are unique", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/categories", `{"name": "audio"}`)
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "category name is already taken: audio")
+	})
+
+	t.Run("Validation Error - Name too short", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPut, "/categories/7", `{"name": "TV"}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "name must be between 3 and 50 characters")
+	})
+
+	t.Run("Get the items of a category", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/categories/3/items", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var items []InventoryItem
+		err := json.Unmarshal(resp.Body.Bytes(), &items)
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.Equal(t, "Headphones", items[0].Name)
+		require.Equal(t, "Microphone", items[1].Name)
+	})
+
+	t.Run("Category not found", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/categories/999/items", "")
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+		require.Contains(t, resp.Body.String(), "category not found")
+	})
+}
+
+func TestSuppliers(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Create a supplier", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/suppliers", `{"name": "Lens Masters", "email": "info@lensmasters.example"}`)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Contains(t, resp.Body.String(), `"id":5`)
+	})
+
+	t.Run("Conflict - Duplicate name", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/suppliers", `{"name": "lens masters", "email": "sales@lensmasters.example"}`)
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "supplier name is already taken: lens masters")
+
+		resp = performJSONRequest(r, http.MethodPut, "/suppliers/1", `{"name": "Lens Masters", "email": "info@lensmasters.example"}`)
+		require.Equal(t, http.StatusConflict, resp.Code)
+
+		resp = performJSONRequest(r, http.MethodPut, "/suppliers/5", `{"name": "Lens Masters", "email": "sales@lensmasters.example"}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Validation Error - Invalid email", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/suppliers", `{"name": "Lens Masters", "email": "not an email"}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "email must be a valid email address")
+	})
+
+	t.Run("Get the items of a supplier", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/suppliers/3/items", "")
+
+		var items []InventoryItem
+		err := json.Unmarshal(resp.Body.Bytes(), &items)
+		require.NoError(t, err)
+		require.Len(t, items, 4)
+		for _, item := range items {
+			require.Equal(t, 3, item.SupplierID)
+		}
+	})
+}
+
+func TestItemReferences(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Create an item in a category", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items", `{"name": "Camera", "price": 450, "quantity": 3, "category_id": 6, "supplier_id": 4}`)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Contains(t, resp.Body.String(), `"category_id":6`)
+	})
+
+	t.Run("Validation Error - Unknown category", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items", `{"name": "Camera", "price": 450, "category_id": 99}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "category 99 does not exist")
+	})
+
+	t.Run("Validation Error - Unknown supplier", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPatch, "/items/1", `{"supplier_id": 99}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "supplier 99 does not exist")
+	})
+
+	t.Run("Remove the supplier of an item", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPatch, "/items/1", `{"supplier_id": null}`)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NotContains(t, resp.Body.String(), "supplier_id")
+	})
+
+	t.Run("Expand the supplier and category", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/items?expand=supplier,category", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var items []InventoryItem
+		err := json.Unmarshal(resp.Body.Bytes(), &items)
+		require.NoError(t, err)
+		require.Nil(t, items[0].Supplier)
+		require.Equal(t, "Computers", items[0].Category.Name)
+		require.Equal(t, "MobileWorld Distribution", items[1].Supplier.Name)
+	})
+
+	t.Run("Items are not expanded by default", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/items", "")
+
+		require.NotContains(t, resp.Body.String(), `"supplier":`)
+	})
+
+	t.Run("Validation Error - Unknown expand", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/items?expand=owner", "")
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "expand must be a comma separated list of: category, supplier")
+	})
+}
+
+func TestDeleteReferencedParent(t *testing.T) {
+	defer func() { DeletePolicy = DeleteRestrict }()
+	r := setupTestServer()
+
+	t.Run("Restrict a referenced category", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodDelete, "/categories/3", "")
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "category is still referenced by 2 items")
+	})
+
+	t.Run("Delete an unreferenced category", func(t *testing.T) {
+		performJSONRequest(r, http.MethodPatch, "/items/19", `{"category_id": null}`)
+
+		resp := performJSONRequest(r, http.MethodDelete, "/categories/5", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.JSONEq(t, `{"message": "category deleted", "deleted_items": []}`, resp.Body.String())
+	})
+
+	t.Run("Cascade with on_delete", func(t *testing.T) {
+		reservation, err := defaultSandbox().ReserveStock(4, ReservationRequest{Quantity: 1})
+		require.NoError(t, err)
+
+		resp := performJSONRequest(r, http.MethodDelete, "/categories/3?on_delete=cascade", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.JSONEq(t, `{"message": "category deleted", "deleted_items": [4, 12]}`, resp.Body.String())
+
+		_, err = defaultSandbox().GetItemByID(4)
+		require.ErrorIs(t, err, ErrItemNotFound)
+		released, err := defaultSandbox().GetReservation(reservation.ID)
+		require.NoError(t, err)
+		require.Equal(t, ReservationReleased, released.Status)
+	})
+
+	t.Run("Cascade by default", func(t *testing.T) {
+		DeletePolicy = DeleteCascade
+
+		resp := performJSONRequest(r, http.MethodDelete, "/suppliers/4", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"deleted_items":[6,13,15,17,18]`)
+	})
+
+	t.Run("Restrict overrides the default", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodDelete, "/suppliers/1?on_delete=restrict", "")
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "supplier is still referenced by 6 items")
+	})
+
+	t.Run("Validation Error - Invalid policy", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodDelete, "/suppliers/1?on_delete=nullify", "")
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "on_delete must be one of: restrict, cascade")
+	})
+}
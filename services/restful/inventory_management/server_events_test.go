@@ -0,0 +1,122 @@
+package restful
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// streamEvent is an event read from a text/event-stream response
+type streamEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// openItemStream connects to GET /items/events and returns a function reading the next event
+func openItemStream(t *testing.T, server *httptest.Server, lastEventID string) func() streamEvent {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/items/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() streamEvent {
+		var event streamEvent
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && event.id != "":
+				return event
+			case strings.HasPrefix(line, "id: "):
+				event.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+	return next
+}
+
+func TestItemEvents(t *testing.T) {
+	r := setupTestServer()
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	t.Run("Stream changes to items", func(t *testing.T) {
+		next := openItemStream(t, server, "")
+
+		performJSONRequest(r, http.MethodPost, "/items", `{"name": "Webcam", "price": 80, "quantity": 7}`)
+		performJSONRequest(r, http.MethodPatch, "/items/21", `{"price": 75}`)
+		performJSONRequest(r, http.MethodPost, "/items/21/stock", `{"type": "receive", "quantity": 3}`)
+		performJSONRequest(r, http.MethodDelete, "/items/21", "")
+
+		created := next()
+		require.Equal(t, streamEvent{id: "1", event: "item.created", data: `{"id":21,"name":"Webcam","description":"","price":80,"quantity":7}`}, created)
+		require.Equal(t, "item.updated", next().event)
+		restocked := next()
+		require.Equal(t, "item.updated", restocked.event)
+		require.Contains(t, restocked.data, `"quantity":10`)
+		require.Equal(t, streamEvent{id: "4", event: "item.deleted", data: `{"id":21,"name":"Webcam","description":"","price":75,"quantity":10}`}, next())
+	})
+
+	t.Run("Resume after the last event received", func(t *testing.T) {
+		next := openItemStream(t, server, "2")
+
+		require.Equal(t, "3", next().id)
+		require.Equal(t, "4", next().id)
+
+		performJSONRequest(r, http.MethodDelete, "/items/1", "")
+
+		require.Equal(t, streamEvent{id: "5", event: "item.deleted", data: `{"id":1,"name":"Laptop","description":"High-performance laptop","price":1500,"quantity":10,"category_id":1,"supplier_id":1}`}, next())
+	})
+
+	t.Run("Rolled back changes are not streamed", func(t *testing.T) {
+		next := openItemStream(t, server, "")
+
+		performJSONRequest(r, http.MethodPost, "/items/bulk?atomic=true", `[{"name": "Webcam Pro", "price": 120}, {"name": "X", "price": 1}]`)
+		performJSONRequest(r, http.MethodDelete, "/items/2", "")
+
+		event := next()
+		require.Equal(t, "6", event.id)
+		require.Equal(t, "item.deleted", event.event)
+	})
+
+	t.Run("Validation Error - Invalid Last-Event-ID", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items/events", nil)
+		req.Header.Set("Last-Event-ID", "abc")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "Last-Event-ID must be a non-negative integer")
+	})
+
+	t.Run("Not Acceptable", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items/events", nil)
+		req.Header.Set("Accept", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusNotAcceptable, resp.Code)
+		require.Contains(t, resp.Body.String(), "text/event-stream")
+	})
+}
@@ -0,0 +1,108 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func postItemWithKey(r *gin.Engine, key, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestIdempotentAddItem(t *testing.T) {
+	r := setupTestServer()
+	payload := `{"name": "Desk Lamp", "description": "LED desk lamp", "price": 25.0, "quantity": 3}`
+
+	t.Run("Retry with the same key replays the original response", func(t *testing.T) {
+		first := postItemWithKey(r, "create-lamp-1", payload)
+		require.Equal(t, http.StatusCreated, first.Code)
+
+		retry := postItemWithKey(r, "create-lamp-1", payload)
+		require.Equal(t, http.StatusCreated, retry.Code)
+		require.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		require.JSONEq(t, first.Body.String(), retry.Body.String())
+		require.Len(t, defaultSandbox().GetAllItems(), 21)
+	})
+
+	t.Run("Same key with a different payload is rejected", func(t *testing.T) {
+		resp := postItemWithKey(r, "create-lamp-1", `{"name": "Floor Lamp", "price": 60.0, "quantity": 1}`)
+
+		require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+		var response map[string]string
+		err := json.Unmarshal(resp.Body.Bytes(), &response)
+		require.NoError(t, err)
+		require.Equal(t, "idempotency key has already been used for a different request", response["error"])
+		require.Len(t, defaultSandbox().GetAllItems(), 21)
+	})
+
+	t.Run("Same key with other media types is rejected", func(t *testing.T) {
+		for header, value := range map[string]string{"Accept": "application/xml", "Content-Type": "application/yaml"} {
+			req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBufferString(payload))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", "create-lamp-1")
+			req.Header.Set(header, value)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusUnprocessableEntity, resp.Code, header)
+			require.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+		}
+		require.Len(t, defaultSandbox().GetAllItems(), 21)
+	})
+
+	t.Run("Requests without a key are not deduplicated", func(t *testing.T) {
+		require.Equal(t, http.StatusCreated, postItemWithKey(r, "", payload).Code)
+		require.Equal(t, http.StatusCreated, postItemWithKey(r, "", payload).Code)
+		require.Len(t, defaultSandbox().GetAllItems(), 23)
+	})
+
+	t.Run("Validation errors are replayed too", func(t *testing.T) {
+		invalid := `{"name": "TV", "price": 300.0, "quantity": 1}`
+		first := postItemWithKey(r, "invalid-tv", invalid)
+		retry := postItemWithKey(r, "invalid-tv", invalid)
+
+		require.Equal(t, http.StatusBadRequest, first.Code)
+		require.Equal(t, http.StatusBadRequest, retry.Code)
+		require.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("Validation Error - Key too long", func(t *testing.T) {
+		resp := postItemWithKey(r, strings.Repeat("k", 256), payload)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "idempotency key cannot exceed 255 characters")
+	})
+}
+
+func TestIdempotencyKeyExpiry(t *testing.T) {
+	defer func(ttl time.Duration) { IdempotencyKeyTTL = ttl }(IdempotencyKeyTTL)
+	IdempotencyKeyTTL = 20 * time.Millisecond
+	r := setupTestServer()
+	payload := `{"name": "Desk Lamp", "price": 25.0, "quantity": 3}`
+
+	first := postItemWithKey(r, "expiring-key", payload)
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	time.Sleep(40 * time.Millisecond)
+
+	second := postItemWithKey(r, "expiring-key", payload)
+	require.Equal(t, http.StatusCreated, second.Code)
+	require.Empty(t, second.Header().Get("Idempotent-Replayed"))
+	require.Len(t, defaultSandbox().GetAllItems(), 22)
+}
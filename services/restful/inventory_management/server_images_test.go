@@ -0,0 +1,230 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func uploadImage(r *gin.Engine, url, field, filename string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile(field, filename)
+	part.Write(data)
+	writer.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, url, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestUploadItemImage(t *testing.T) {
+	r := setupTestServer()
+	data := testPNG(t)
+
+	t.Run("Upload an image", func(t *testing.T) {
+		resp := uploadImage(r, "/items/1/images", "file", "laptop.png", data)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Equal(t, "/items/1/images/1", resp.Header().Get("Location"))
+
+		var uploaded ItemImage
+		err := json.Unmarshal(resp.Body.Bytes(), &uploaded)
+		require.NoError(t, err)
+		require.Equal(t, "image/png", uploaded.ContentType)
+		require.Equal(t, "laptop.png", uploaded.Filename)
+		require.Equal(t, len(data), uploaded.Size)
+	})
+
+	t.Run("Type is sniffed from the content", func(t *testing.T) {
+		resp := uploadImage(r, "/items/1/images", "file", "C:\\photos\\laptop.txt", data)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Contains(t, resp.Body.String(), `"filename":"laptop.txt.png"`)
+
+		resp = uploadImage(r, "/items/1/images", "file", "fake.png", []byte("this is not an image"))
+
+		require.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+		require.Contains(t, resp.Body.String(), "file must be a PNG, JPEG, GIF or WebP image")
+	})
+
+	t.Run("Image too large", func(t *testing.T) {
+		defer func(size int64) { MaxImageSize = size }(MaxImageSize)
+		MaxImageSize = 16
+
+		resp := uploadImage(r, "/items/1/images", "file", "laptop.png", data)
+
+		require.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+		require.Contains(t, resp.Body.String(), "image is too large: the limit is 16 bytes")
+	})
+
+	t.Run("Validation Error - Missing file", func(t *testing.T) {
+		resp := uploadImage(r, "/items/1/images", "picture", "laptop.png", data)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "file is required")
+	})
+
+	t.Run("Validation Error - Not multipart", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/1/images", `{"file": "laptop.png"}`)
+
+		require.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+		require.Contains(t, resp.Body.String(), "payload must be multipart/form-data")
+	})
+
+	t.Run("Item not found", func(t *testing.T) {
+		resp := uploadImage(r, "/items/999/images", "file", "laptop.png", data)
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("List the images of an item", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/items/1/images", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var itemImages []ItemImage
+		err := json.Unmarshal(resp.Body.Bytes(), &itemImages)
+		require.NoError(t, err)
+		require.Len(t, itemImages, 2)
+		require.Equal(t, "/items/1/images/2", itemImages[1].URL)
+	})
+
+	t.Run("Image URLs keep the version prefix of the request", func(t *testing.T) {
+		resp := uploadImage(r, "/v2/items/1/images", "file", "laptop.png", data)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Equal(t, "/v2/items/1/images/3", resp.Header().Get("Location"))
+		require.Contains(t, resp.Body.String(), `"url":"/v2/items/1/images/3"`)
+
+		resp = performJSONRequest(r, http.MethodGet, "/v1/items/1/images", "")
+
+		var itemImages []ItemImage
+		err := json.Unmarshal(resp.Body.Bytes(), &itemImages)
+		require.NoError(t, err)
+		require.Equal(t, "/v1/items/1/images/3", itemImages[2].URL)
+
+		req, _ := http.NewRequest(http.MethodGet, itemImages[2].URL, nil)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, data, resp.Body.Bytes())
+	})
+}
+
+func TestDownloadItemImage(t *testing.T) {
+	r := setupTestServer()
+	data := testPNG(t)
+	uploadImage(r, "/items/2/images", "file", "phone.png", data)
+
+	download := func(headers map[string]string, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Download the whole image", func(t *testing.T) {
+		resp := download(map[string]string{"Accept": "image/*"}, "/items/2/images/1")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "image/png", resp.Header().Get("Content-Type"))
+		require.Equal(t, `inline; filename=phone.png`, resp.Header().Get("Content-Disposition"))
+		require.Equal(t, "bytes", resp.Header().Get("Accept-Ranges"))
+		require.Equal(t, data, resp.Body.Bytes())
+	})
+
+	t.Run("Download as attachment", func(t *testing.T) {
+		resp := download(nil, "/items/2/images/1?download=true")
+
+		require.Equal(t, `attachment; filename=phone.png`, resp.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("Range request", func(t *testing.T) {
+		resp := download(map[string]string{"Range": "bytes=0-9"}, "/items/2/images/1")
+
+		require.Equal(t, http.StatusPartialContent, resp.Code)
+		require.Equal(t, "bytes 0-9/"+strconv.Itoa(len(data)), resp.Header().Get("Content-Range"))
+		require.Equal(t, data[:10], resp.Body.Bytes())
+	})
+
+	t.Run("Unsatisfiable range", func(t *testing.T) {
+		resp := download(map[string]string{"Range": "bytes=100000-"}, "/items/2/images/1")
+
+		require.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.Code)
+	})
+
+	t.Run("Conditional request", func(t *testing.T) {
+		etag := download(nil, "/items/2/images/1").Header().Get("ETag")
+
+		resp := download(map[string]string{"If-None-Match": etag}, "/items/2/images/1")
+
+		require.Equal(t, http.StatusNotModified, resp.Code)
+	})
+
+	t.Run("Not Acceptable", func(t *testing.T) {
+		resp := download(map[string]string{"Accept": "application/json"}, "/items/2/images/1")
+
+		require.Equal(t, http.StatusNotAcceptable, resp.Code)
+		require.Contains(t, resp.Body.String(), "image/png")
+	})
+
+	t.Run("Image not found", func(t *testing.T) {
+		resp := download(nil, "/items/3/images/1")
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+		require.Contains(t, resp.Body.String(), "image not found")
+	})
+
+	t.Run("Deleting the item hides its images until it is purged", func(t *testing.T) {
+		performJSONRequest(r, http.MethodDelete, "/items/2", "")
+
+		_, err := defaultSandbox().GetItemImage(2, 1)
+		require.ErrorIs(t, err, ErrItemNotFound)
+
+		performJSONRequest(r, http.MethodPost, "/items/2/restore", "")
+		resp := download(nil, "/items/2/images/1")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		defer func(retention time.Duration) { TrashRetention = retention }(TrashRetention)
+		TrashRetention = 0
+		performJSONRequest(r, http.MethodDelete, "/items/2", "")
+		defaultSandbox().GetTrash()
+		require.Empty(t, defaultSandbox().images)
+	})
+}
+
+func TestDeleteItemImage(t *testing.T) {
+	r := setupTestServer()
+	uploadImage(r, "/items/3/images", "file", "tablet.png", testPNG(t))
+
+	resp := performJSONRequest(r, http.MethodDelete, "/items/3/images/1", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp = performJSONRequest(r, http.MethodDelete, "/items/3/images/1", "")
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
@@ -0,0 +1,129 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func startTestReport(t *testing.T, r *gin.Engine, payload string) Job {
+	resp := performJSONRequest(r, http.MethodPost, "/reports/inventory-valuation", payload)
+	require.Equal(t, http.StatusAccepted, resp.Code, resp.Body.String())
+
+	var job Job
+	err := json.Unmarshal(resp.Body.Bytes(), &job)
+	require.NoError(t, err)
+	require.Equal(t, "/jobs/"+strconv.Itoa(job.ID), resp.Header().Get("Location"))
+	require.NotEmpty(t, resp.Header().Get("Retry-After"))
+	return job
+}
+
+func waitForJob(t *testing.T, id int, status string) {
+	require.Eventually(t, func() bool {
+		job, err := defaultSandbox().GetJob(id)
+		return err == nil && job.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+}
+
+func TestInventoryValuationReport(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Run a report to completion", func(t *testing.T) {
+		job := startTestReport(t, r, `{"duration_seconds": 0}`)
+		require.Equal(t, JobInventoryValuation, job.Type)
+
+		waitForJob(t, job.ID, JobSucceeded)
+
+		resp := performJSONRequest(r, http.MethodGet, "/jobs/"+strconv.Itoa(job.ID), "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Empty(t, resp.Header().Get("Retry-After"))
+		require.Contains(t, resp.Body.String(), `"progress":100`)
+		require.Contains(t, resp.Body.String(), `"result_url":"/jobs/1/result"`)
+
+		resp = performJSONRequest(r, http.MethodGet, "/jobs/"+strconv.Itoa(job.ID)+"/result", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var report InventoryValuation
+		err := json.Unmarshal(resp.Body.Bytes(), &report)
+		require.NoError(t, err)
+		require.Equal(t, 20, report.ItemCount)
+		require.Len(t, report.Items, 20)
+		require.Len(t, report.Categories, 6)
+		require.Equal(t, "Computers", report.Categories[0].Name)
+		require.InDelta(t, 15000.0, report.Items[0].Value, 0.001)
+	})
+
+	t.Run("Poll a running report", func(t *testing.T) {
+		job := startTestReport(t, r, `{"duration_seconds": 60}`)
+		waitForJob(t, job.ID, JobRunning)
+
+		resp := performJSONRequest(r, http.MethodGet, "/jobs/"+strconv.Itoa(job.ID), "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		retryAfter, err := strconv.Atoi(resp.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		require.InDelta(t, 60, retryAfter, 1)
+
+		resp = performJSONRequest(r, http.MethodGet, "/jobs/"+strconv.Itoa(job.ID)+"/result", "")
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.NotEmpty(t, resp.Header().Get("Retry-After"))
+		require.Contains(t, resp.Body.String(), "job has not finished yet")
+	})
+
+	t.Run("Cancel a report", func(t *testing.T) {
+		job := startTestReport(t, r, `{"duration_seconds": 60}`)
+
+		resp := performJSONRequest(r, http.MethodDelete, "/jobs/"+strconv.Itoa(job.ID), "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"status":"cancelled"`)
+
+		resp = performJSONRequest(r, http.MethodGet, "/jobs/"+strconv.Itoa(job.ID)+"/result", "")
+		require.Equal(t, http.StatusGone, resp.Code)
+		require.Contains(t, resp.Body.String(), "job has no result: it is cancelled")
+
+		resp = performJSONRequest(r, http.MethodDelete, "/jobs/"+strconv.Itoa(job.ID), "")
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "job has already finished: it is cancelled")
+	})
+
+	t.Run("Default duration", func(t *testing.T) {
+		defer func(duration time.Duration) { ReportDuration = duration }(ReportDuration)
+		ReportDuration = 0
+
+		job := startTestReport(t, r, "")
+
+		waitForJob(t, job.ID, JobSucceeded)
+	})
+
+	t.Run("Validation Error - Duration out of range", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/reports/inventory-valuation", `{"duration_seconds": 301}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "duration_seconds must be between 0 and 300")
+	})
+
+	t.Run("Job not found", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/jobs/999", "")
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
+
+func TestTooManyJobs(t *testing.T) {
+	r := setupTestServer()
+	defer defaultSandbox().stopJobs()
+
+	for i := 0; i < MaxActiveJobs; i++ {
+		startTestReport(t, r, `{"duration_seconds": 60}`)
+	}
+
+	resp := performJSONRequest(r, http.MethodPost, "/reports/inventory-valuation", `{"duration_seconds": 60}`)
+
+	require.Equal(t, http.StatusTooManyRequests, resp.Code)
+	require.NotEmpty(t, resp.Header().Get("Retry-After"))
+	require.Contains(t, resp.Body.String(), "too many jobs: at most 5 can run at the same time")
+}
@@ -0,0 +1,225 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestContentNegotiation(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Default to JSON without an Accept header", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "application/json")
+	})
+
+	t.Run("Get items as XML", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("Accept", "application/xml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "application/xml")
+
+		var list struct {
+			XMLName xml.Name        `xml:"InventoryItemList"`
+			Items   []InventoryItem `xml:"InventoryItem"`
+		}
+		err := xml.Unmarshal(resp.Body.Bytes(), &list)
+		require.NoError(t, err)
+		require.Len(t, list.Items, 20)
+		require.Equal(t, "Laptop", list.Items[0].Name)
+	})
+
+	t.Run("Get items as CSV", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("Accept", "text/csv")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "text/csv")
+
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 21)
+		require.Equal(t, []string{"id", "name", "description", "price", "quantity", "category_id", "supplier_id"}, records[0])
+		require.Equal(t, []string{"1", "Laptop", "High-performance laptop", "1500", "10", "1", "1"}, records[1])
+	})
+
+	t.Run("Get items as YAML", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("Accept", "application/yaml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "application/yaml")
+
+		var items []map[string]interface{}
+		err := yaml.Unmarshal(resp.Body.Bytes(), &items)
+		require.NoError(t, err)
+		require.Len(t, items, 20)
+		require.Equal(t, "Laptop", items[0]["name"])
+	})
+
+	t.Run("Honour quality values", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("Accept", "application/json;q=0.5, application/xml;q=0.9")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "application/xml")
+	})
+
+	t.Run("Not Acceptable for unsupported media type", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("Accept", "application/pdf")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusNotAcceptable, resp.Code)
+	})
+
+	t.Run("Not Acceptable for CSV on a single item", func(t *testing.T) {
+		payload := `{"name": "Desk Lamp", "description": "LED desk lamp", "price": 25.0, "quantity": 3}`
+		req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/csv")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusNotAcceptable, resp.Code)
+		require.Len(t, defaultSandbox().GetAllItems(), 20) // Rejected before the item was created
+	})
+
+	t.Run("Add an item with an XML body", func(t *testing.T) {
+		payload := `<item><name>Desk Lamp</name><description>LED desk lamp</description><price>25.5</price><quantity>3</quantity></item>`
+		req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/xml")
+		req.Header.Set("Accept", "application/xml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		var item InventoryItem
+		err := xml.Unmarshal(resp.Body.Bytes(), &item)
+		require.NoError(t, err)
+		require.Equal(t, "Desk Lamp", item.Name)
+		require.Equal(t, 25.5, item.Price)
+		require.Equal(t, 3, item.Quantity)
+	})
+
+	t.Run("Add an item with a YAML body", func(t *testing.T) {
+		payload := "name: Standing Desk\ndescription: Height adjustable desk\nprice: 450\nquantity: 2\n"
+		req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/yaml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		var item InventoryItem
+		err := json.Unmarshal(resp.Body.Bytes(), &item)
+		require.NoError(t, err)
+		require.Equal(t, "Standing Desk", item.Name)
+		require.Equal(t, 2, item.Quantity)
+	})
+
+	t.Run("Patch an item with an XML body", func(t *testing.T) {
+		payload := `<item><quantity>7</quantity></item>`
+		req, _ := http.NewRequest(http.MethodPatch, "/items/1", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "text/xml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var item InventoryItem
+		err := json.Unmarshal(resp.Body.Bytes(), &item)
+		require.NoError(t, err)
+		require.Equal(t, 7, item.Quantity)
+	})
+
+	t.Run("Patch text fields that look like numbers with an XML body", func(t *testing.T) {
+		payload := `<item><name>1234</name><description>true</description><price>12.5</price></item>`
+		req, _ := http.NewRequest(http.MethodPatch, "/items/1", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/xml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+		var item InventoryItem
+		err := json.Unmarshal(resp.Body.Bytes(), &item)
+		require.NoError(t, err)
+		require.Equal(t, "1234", item.Name)
+		require.Equal(t, "true", item.Description)
+		require.Equal(t, 12.5, item.Price)
+	})
+
+	t.Run("Patch the stock of a version 2 item with an XML body", func(t *testing.T) {
+		payload := `<item><price_cents>1999</price_cents><stock><on_hand>9</on_hand></stock></item>`
+		req, _ := http.NewRequest(http.MethodPatch, "/v2/items/1", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/xml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+		var item ItemV2
+		err := json.Unmarshal(resp.Body.Bytes(), &item)
+		require.NoError(t, err)
+		require.Equal(t, int64(1999), item.PriceCents)
+		require.Equal(t, 9, item.Stock.OnHand)
+	})
+
+	t.Run("Reject a quantity that is not a number in an XML body", func(t *testing.T) {
+		payload := `<item><quantity>many</quantity></item>`
+		req, _ := http.NewRequest(http.MethodPatch, "/items/1", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/xml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "quantity must be at least 0")
+	})
+
+	t.Run("Validation errors are rendered in the negotiated format", func(t *testing.T) {
+		payload := "name: TV\nprice: 10\nquantity: 1\n"
+		req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/yaml")
+		req.Header.Set("Accept", "application/yaml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "error: name must be between 3 and 50 characters")
+	})
+
+	t.Run("Unsupported Media Type for CSV body", func(t *testing.T) {
+		payload := "name,price\nLamp,10\n"
+		req, _ := http.NewRequest(http.MethodPost, "/items", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "text/csv")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+	})
+}
@@ -0,0 +1,135 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abhivaikar/playpi/services/restful/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Serve the OpenAPI document", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var spec map[string]interface{}
+		err := json.Unmarshal(resp.Body.Bytes(), &spec)
+		require.NoError(t, err)
+		require.Equal(t, "3.1.0", spec["openapi"])
+	})
+
+	t.Run("Every route is documented", func(t *testing.T) {
+		doc, err := openapi.Parse(openAPISpec)
+		require.NoError(t, err)
+
+		for _, route := range r.Routes() {
+			if route.Path == "/openapi.json" || route.Path == "/docs" || route.Path == openapi.AssetsRoute {
+				continue
+			}
+			_, ok := doc.Resolve(route.Method, route.Path, versionPrefixes...)
+			require.True(t, ok, "%s %s is missing from openapi.json", route.Method, route.Path)
+		}
+	})
+
+	t.Run("Serve the Swagger UI", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/docs", nil)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "text/html")
+		require.Contains(t, resp.Body.String(), `<script src="/docs/swagger-ui-bundle.js"`)
+
+		req, _ = http.NewRequest(http.MethodGet, "/docs/swagger-initializer.js", nil)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `url: "/openapi.json"`)
+	})
+
+	t.Run("Serve the Swagger UI assets", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/docs/swagger-ui.css", nil)
+		req.Header.Set("Accept", "text/css")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "text/css")
+		require.Contains(t, resp.Body.String(), ".swagger-ui")
+
+		req, _ = http.NewRequest(http.MethodGet, "/docs/missing.js", nil)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
+
+func TestRequestValidation(t *testing.T) {
+	defer func() { ValidateRequests = false }()
+	ValidateRequests = true
+	r := setupTestServer()
+
+	t.Run("Reject a body that violates the schema", func(t *testing.T) {
+		payload := `{"name": "TV", "price": 20000, "quantity": 1.5, "colour": "black"}`
+		req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+
+		var response struct {
+			Error      string   `json:"error"`
+			Violations []string `json:"violations"`
+		}
+		err := json.Unmarshal(resp.Body.Bytes(), &response)
+		require.NoError(t, err)
+		require.Equal(t, "request does not match the OpenAPI document", response.Error)
+		require.Equal(t, []string{
+			"body.name must be at least 3 characters",
+			"body.price must be at most 10000",
+			"body.quantity must be of type integer",
+		}, response.Violations)
+	})
+
+	t.Run("Reject an invalid path parameter", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodDelete, "/items/abc", nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "path parameter 'id' must be of type integer")
+	})
+
+	t.Run("Reject an invalid query parameter", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodDelete, "/items/bulk?atomic=maybe", bytes.NewBufferString(`[1]`))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "query parameter 'atomic' must be of type boolean")
+	})
+
+	t.Run("Accept a valid request", func(t *testing.T) {
+		payload := `{"name": "Desk Lamp", "description": "LED desk lamp", "price": 25.0, "quantity": 3}`
+		req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+	})
+}
@@ -0,0 +1,156 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func placeTestOrder(t *testing.T, r *gin.Engine, payload string) Order {
+	resp := performJSONRequest(r, http.MethodPost, "/orders", payload)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	var order Order
+	err := json.Unmarshal(resp.Body.Bytes(), &order)
+	require.NoError(t, err)
+	return order
+}
+
+func TestPlaceOrder(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Place an order", func(t *testing.T) {
+		order := placeTestOrder(t, r, `{"lines": [{"item_id": 1, "quantity": 2}, {"item_id": 2, "quantity": 5}]}`)
+
+		require.Equal(t, OrderPlaced, order.Status)
+		require.Len(t, order.Lines, 2)
+		require.Equal(t, "Laptop", order.Lines[0].Name)
+		require.InDelta(t, 2*1500.0+5*800.0, order.Total, 0.001)
+
+		laptop, _ := defaultSandbox().GetItemByID(1)
+		smartphone, _ := defaultSandbox().GetItemByID(2)
+		require.Equal(t, 8, laptop.Quantity)
+		require.Equal(t, 15, smartphone.Quantity)
+
+		history, err := defaultSandbox().GetStockMovements(2, MovementSell, 0)
+		require.NoError(t, err)
+		require.Equal(t, "order 1 placed", history[0].Reason)
+	})
+
+	t.Run("Insufficient stock rejects the whole order", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/orders", `{"lines": [{"item_id": 1, "quantity": 1}, {"item_id": 6, "quantity": 6}]}`)
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "lines[1]: insufficient stock: only 5 of item 6 available")
+
+		laptop, _ := defaultSandbox().GetItemByID(1)
+		require.Equal(t, 8, laptop.Quantity)
+		require.Len(t, defaultSandbox().GetOrders(""), 1)
+	})
+
+	t.Run("Reserved stock cannot be ordered", func(t *testing.T) {
+		_, err := defaultSandbox().ReserveStock(6, ReservationRequest{Quantity: 4})
+		require.NoError(t, err)
+
+		resp := performJSONRequest(r, http.MethodPost, "/orders", `{"lines": [{"item_id": 6, "quantity": 2}]}`)
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("Validation Error - Empty order", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/orders", `{"lines": []}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "order must contain at least one line")
+	})
+
+	t.Run("Validation Error - Unknown item", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/orders", `{"lines": [{"item_id": 999, "quantity": 1}]}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "lines[0]: item 999 does not exist")
+	})
+
+	t.Run("Validation Error - Duplicate item", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/orders", `{"lines": [{"item_id": 3, "quantity": 1}, {"item_id": 3, "quantity": 1}]}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "lines[1]: item 3 appears more than once")
+	})
+
+	t.Run("Validation Error - Non positive quantity", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/orders", `{"lines": [{"item_id": 3, "quantity": 0}]}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "lines[0]: quantity must be greater than 0")
+	})
+}
+
+func TestOrderTransitions(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Cancel a placed order", func(t *testing.T) {
+		order := placeTestOrder(t, r, `{"lines": [{"item_id": 3, "quantity": 4}]}`)
+
+		resp := performJSONRequest(r, http.MethodPost, "/orders/"+strconv.Itoa(order.ID)+"/cancel", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"status":"cancelled"`)
+
+		tablet, _ := defaultSandbox().GetItemByID(3)
+		require.Equal(t, 15, tablet.Quantity)
+
+		history, err := defaultSandbox().GetStockMovements(3, MovementReturn, 0)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.Equal(t, "order 1 cancelled", history[0].Reason)
+	})
+
+	t.Run("Refund a shipped order", func(t *testing.T) {
+		order := placeTestOrder(t, r, `{"lines": [{"item_id": 4, "quantity": 2}]}`)
+
+		resp := performJSONRequest(r, http.MethodPost, "/orders/"+strconv.Itoa(order.ID)+"/cancel", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		order = placeTestOrder(t, r, `{"lines": [{"item_id": 4, "quantity": 2}]}`)
+		resp = performJSONRequest(r, http.MethodPost, "/orders/"+strconv.Itoa(order.ID)+"/ship", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = performJSONRequest(r, http.MethodPost, "/orders/"+strconv.Itoa(order.ID)+"/cancel", "")
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "invalid order transition: order is shipped and cannot be cancelled")
+
+		resp = performJSONRequest(r, http.MethodPost, "/orders/"+strconv.Itoa(order.ID)+"/refund", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		headphones, _ := defaultSandbox().GetItemByID(4)
+		require.Equal(t, 25, headphones.Quantity)
+	})
+
+	t.Run("A placed order cannot be refunded", func(t *testing.T) {
+		order := placeTestOrder(t, r, `{"lines": [{"item_id": 5, "quantity": 1}]}`)
+
+		resp := performJSONRequest(r, http.MethodPost, "/orders/"+strconv.Itoa(order.ID)+"/refund", "")
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("Filter orders by status", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/orders?status=placed", "")
+
+		var placed []Order
+		err := json.Unmarshal(resp.Body.Bytes(), &placed)
+		require.NoError(t, err)
+		require.Len(t, placed, 1)
+		require.Equal(t, 5, placed[0].Lines[0].ItemID)
+	})
+
+	t.Run("Order not found", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/orders/999/cancel", "")
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
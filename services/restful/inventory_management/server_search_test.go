@@ -0,0 +1,167 @@
+package restful
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/abhivaikar/playpi/services/restful/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+func searchItems(t *testing.T, r http.Handler, query string) []SearchResult {
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/items/search?"+query, nil)
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var results []SearchResult
+	err := json.Unmarshal(resp.Body.Bytes(), &results)
+	require.NoError(t, err)
+	return results
+}
+
+func resultNames(results []SearchResult) []string {
+	names := []string{}
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	return names
+}
+
+func TestSearchItems(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Rank matches by relevance", func(t *testing.T) {
+		results := searchItems(t, r, "q=SMART")
+
+		require.Equal(t, []string{"Smart Home Hub", "Smartphone", "Smartwatch"}, resultNames(results))
+		require.Greater(t, results[0].Score, results[1].Score)
+	})
+
+	t.Run("Highlight matches", func(t *testing.T) {
+		results := searchItems(t, r, "q=laptop")
+
+		require.Len(t, results, 1)
+		require.Equal(t, "<mark>Laptop</mark>", results[0].Highlights.Name)
+		require.Equal(t, "High-performance <mark>laptop</mark>", results[0].Highlights.Description)
+	})
+
+	t.Run("Every word must match", func(t *testing.T) {
+		results := searchItems(t, r, "q=fitness+tracking")
+
+		require.Equal(t, []string{"Smartwatch"}, resultNames(results))
+	})
+
+	t.Run("Quoted phrases", func(t *testing.T) {
+		results := searchItems(t, r, "q="+url.QueryEscape(`"fitness tracker"`))
+
+		require.Equal(t, []string{"Fitness Tracker"}, resultNames(results))
+		require.Equal(t, "Health and <mark>fitness tracker</mark>", results[0].Highlights.Description)
+	})
+
+	t.Run("Exclude words", func(t *testing.T) {
+		results := searchItems(t, r, "q="+url.QueryEscape("fitness -tracker"))
+
+		require.Equal(t, []string{"Smartwatch"}, resultNames(results))
+	})
+
+	t.Run("Filter by price and quantity", func(t *testing.T) {
+		results := searchItems(t, r, "q="+url.QueryEscape("price:<100"))
+		require.Equal(t, []string{"Mouse", "Webcam", "Router", "Power Bank"}, resultNames(results))
+
+		results = searchItems(t, r, "q="+url.QueryEscape("smart price:<=150 quantity:>=20"))
+		require.Equal(t, []string{"Smart Home Hub"}, resultNames(results))
+
+		results = searchItems(t, r, "q="+url.QueryEscape("price:<100 -price:50"))
+		require.Equal(t, []string{"Webcam", "Router"}, resultNames(results))
+	})
+
+	t.Run("Long descriptions are cut down to a snippet", func(t *testing.T) {
+		payload := `{"name": "Desk Lamp", "description": "A bright and adjustable lamp with a sturdy metal base, a flexible neck and a warm LED bulb for reading", "price": 40}`
+		performJSONRequest(r, http.MethodPost, "/items", payload)
+
+		results := searchItems(t, r, "q=bulb")
+
+		require.Len(t, results, 1)
+		require.Equal(t, "…metal base, a flexible neck and a warm LED <mark>bulb</mark> for reading", results[0].Highlights.Description)
+		require.Empty(t, results[0].Highlights.Name)
+	})
+
+	t.Run("Paginate results", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items/search?q="+url.QueryEscape("price:<100")+"&limit=2&offset=1", nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "5", resp.Header().Get(pagination.HeaderTotalCount))
+
+		var results []SearchResult
+		err := json.Unmarshal(resp.Body.Bytes(), &results)
+		require.NoError(t, err)
+		require.Equal(t, []string{"Webcam", "Router"}, resultNames(results))
+	})
+
+	t.Run("Search results in version 2", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/v2/items/search?q=laptop", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"price_cents":150000`)
+		require.Contains(t, resp.Body.String(), `"score":4`)
+	})
+
+	t.Run("Search results as CSV", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items/search?q=laptop", nil)
+		req.Header.Set("Accept", "text/csv")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, []string{"id", "name", "description", "price", "quantity", "category_id", "supplier_id", "score", "highlights"}, records[0])
+		require.Equal(t, "Laptop", records[1][1])
+	})
+
+	validationErrors := map[string]string{
+		"":                                     "q is required",
+		"q=" + url.QueryEscape("- !"):          "q must contain at least one word, phrase or filter",
+		"q=" + url.QueryEscape(`"smart home`):  "q contains a quoted phrase without its closing quote",
+		"q=" + url.QueryEscape("colour:red"):   "unknown filter colour: filters can use price or quantity",
+		"q=" + url.QueryEscape("price:<cheap"): "price filter must be a number, optionally preceded by <, <=, > or >=",
+		"q=laptop&limit=-1":                    "limit must be a positive number",
+		"q=laptop&offset=first":                "offset must be a positive number",
+	}
+	for query, message := range validationErrors {
+		t.Run("Validation Error - "+message, func(t *testing.T) {
+			resp := performJSONRequest(r, http.MethodGet, "/items/search?"+query, "")
+
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+
+			var response map[string]string
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			require.NoError(t, err)
+			require.Equal(t, message, response["error"])
+		})
+	}
+}
+
+func TestPaginateItems(t *testing.T) {
+	r := setupTestServer()
+
+	resp := performJSONRequest(r, http.MethodGet, "/items?limit=5&offset=18", "")
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "20", resp.Header().Get(pagination.HeaderTotalCount))
+
+	var items []InventoryItem
+	err := json.Unmarshal(resp.Body.Bytes(), &items)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "Smart Home Hub", items[0].Name)
+
+	resp = performJSONRequest(r, http.MethodGet, "/items?offset=100", "")
+	require.Equal(t, "[]", resp.Body.String())
+}
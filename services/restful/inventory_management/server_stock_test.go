@@ -0,0 +1,270 @@
+package restful
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/clock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func performJSONRequest(r *gin.Engine, method, url, payload string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if payload == "" {
+		req, _ = http.NewRequest(method, url, nil)
+	} else {
+		req, _ = http.NewRequest(method, url, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestAdjustStock(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Receive stock", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/1/stock", `{"type": "receive", "quantity": 5, "reason": "Delivery"}`)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		var movement StockMovement
+		err := json.Unmarshal(resp.Body.Bytes(), &movement)
+		require.NoError(t, err)
+		require.Equal(t, MovementReceive, movement.Type)
+		require.Equal(t, 5, movement.Delta)
+		require.Equal(t, 15, movement.QuantityAfter)
+	})
+
+	t.Run("Sell stock", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/1/stock", `{"type": "sell", "quantity": 3}`)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		item, err := defaultSandbox().GetItemByID(1)
+		require.NoError(t, err)
+		require.Equal(t, 12, item.Quantity)
+	})
+
+	t.Run("Selling more than available is a conflict", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/1/stock", `{"type": "sell", "quantity": 13}`)
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "insufficient stock: only 12 available")
+	})
+
+	t.Run("Validation Error - Damage without reason", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/1/stock", `{"type": "damage", "quantity": 1}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "reason is required for damage adjustments")
+	})
+
+	t.Run("Validation Error - Invalid type", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/1/stock", `{"type": "steal", "quantity": 1}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "type must be one of: receive, sell, damage")
+	})
+
+	t.Run("Validation Error - Non positive quantity", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/1/stock", `{"type": "receive", "quantity": 0}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "quantity must be greater than 0")
+	})
+
+	t.Run("Item not found", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/999/stock", `{"type": "receive", "quantity": 1}`)
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
+
+func TestStockMovements(t *testing.T) {
+	r := setupTestServer()
+	performJSONRequest(r, http.MethodPost, "/items/2/stock", `{"type": "receive", "quantity": 10, "reason": "Delivery"}`)
+	performJSONRequest(r, http.MethodPost, "/items/2/stock", `{"type": "sell", "quantity": 4}`)
+	performJSONRequest(r, http.MethodPost, "/items/2/stock", `{"type": "damage", "quantity": 1, "reason": "Dropped"}`)
+	performJSONRequest(r, http.MethodPatch, "/items/2", `{"quantity": 50}`)
+
+	t.Run("Get the full ledger", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/items/2/stock/movements", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var history []StockMovement
+		err := json.Unmarshal(resp.Body.Bytes(), &history)
+		require.NoError(t, err)
+		require.Len(t, history, 4)
+		require.Equal(t, []int{30, 26, 25, 50}, []int{history[0].QuantityAfter, history[1].QuantityAfter, history[2].QuantityAfter, history[3].QuantityAfter})
+		require.Equal(t, MovementAdjustment, history[3].Type)
+		require.Equal(t, 25, history[3].Delta)
+	})
+
+	t.Run("Filter by type and limit", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/items/2/stock/movements?type=damage", "")
+
+		var history []StockMovement
+		err := json.Unmarshal(resp.Body.Bytes(), &history)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.Equal(t, "Dropped", history[0].Reason)
+
+		resp = performJSONRequest(r, http.MethodGet, "/items/2/stock/movements?limit=2", "")
+		err = json.Unmarshal(resp.Body.Bytes(), &history)
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		require.Equal(t, MovementDamage, history[0].Type)
+	})
+}
+
+func TestConcurrentStockAdjustments(t *testing.T) {
+	setupTestServer()
+
+	// Item 6 starts with 5 units, so only 5 of the 20 concurrent sales can succeed
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := defaultSandbox().AdjustStock(6, StockAdjustment{Type: MovementSell, Quantity: 1}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	item, err := defaultSandbox().GetItemByID(6)
+	require.NoError(t, err)
+	require.Equal(t, 5, succeeded)
+	require.Equal(t, 0, item.Quantity)
+}
+
+func TestReservations(t *testing.T) {
+	r := setupTestServer()
+
+	reserve := func(t *testing.T, itemID int, payload string) Reservation {
+		resp := performJSONRequest(r, http.MethodPost, "/items/"+strconv.Itoa(itemID)+"/reservations", payload)
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		var reservation Reservation
+		err := json.Unmarshal(resp.Body.Bytes(), &reservation)
+		require.NoError(t, err)
+		return reservation
+	}
+
+	t.Run("Reserved stock cannot be sold", func(t *testing.T) {
+		reservation := reserve(t, 15, `{"quantity": 3, "ttl_seconds": 60}`)
+		require.Equal(t, ReservationActive, reservation.Status)
+
+		resp := performJSONRequest(r, http.MethodGet, "/items/15/stock", "")
+		var level StockLevel
+		err := json.Unmarshal(resp.Body.Bytes(), &level)
+		require.NoError(t, err)
+		require.Equal(t, StockLevel{ItemID: 15, Quantity: 4, Reserved: 3, Available: 1}, level)
+
+		resp = performJSONRequest(r, http.MethodPost, "/items/15/stock", `{"type": "sell", "quantity": 2}`)
+		require.Equal(t, http.StatusConflict, resp.Code)
+
+		resp = performJSONRequest(r, http.MethodPatch, "/items/15", `{"quantity": 2}`)
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "quantity cannot be lower than the reserved quantity of 3")
+	})
+
+	t.Run("Commit a reservation", func(t *testing.T) {
+		reservation := reserve(t, 17, `{"quantity": 2}`)
+
+		resp := performJSONRequest(r, http.MethodPost, "/reservations/"+strconv.Itoa(reservation.ID)+"/commit", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		item, err := defaultSandbox().GetItemByID(17)
+		require.NoError(t, err)
+		require.Equal(t, 1, item.Quantity)
+
+		resp = performJSONRequest(r, http.MethodDelete, "/reservations/"+strconv.Itoa(reservation.ID), "")
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "reservation is no longer active: committed")
+	})
+
+	t.Run("Release a reservation", func(t *testing.T) {
+		reservation := reserve(t, 12, `{"quantity": 7}`)
+
+		resp := performJSONRequest(r, http.MethodDelete, "/reservations/"+strconv.Itoa(reservation.ID), "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		level, err := defaultSandbox().GetStockLevel(12)
+		require.NoError(t, err)
+		require.Equal(t, 7, level.Available)
+	})
+
+	t.Run("Reservations expire", func(t *testing.T) {
+		defer defaultSandbox().clock.Reset()
+		reservation := reserve(t, 18, `{"quantity": 4, "ttl_seconds": 30}`)
+
+		req, _ := http.NewRequest(http.MethodGet, "/reservations/"+strconv.Itoa(reservation.ID), nil)
+		req.Header.Set(clock.HeaderNow, time.Now().Add(time.Minute).Format(time.RFC3339))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		require.Contains(t, resp.Body.String(), `"status":"active"`, "a time override must not expire reservations")
+
+		req, _ = http.NewRequest(http.MethodPost, "/items/18/reservations", bytes.NewBufferString(`{"quantity": 1, "ttl_seconds": 30}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(clock.HeaderNow, time.Now().Add(time.Hour).Format(time.RFC3339))
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var overridden Reservation
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &overridden))
+		require.WithinDuration(t, time.Now().Add(30*time.Second), overridden.ExpiresAt, 5*time.Second, "a time override must not move the expiry of a reservation")
+
+		_, err := defaultSandbox().clock.Advance(31 * time.Second)
+		require.NoError(t, err)
+
+		resp = performJSONRequest(r, http.MethodGet, "/reservations/"+strconv.Itoa(reservation.ID), "")
+		require.Contains(t, resp.Body.String(), `"status":"expired"`)
+		resp = performJSONRequest(r, http.MethodGet, "/reservations/"+strconv.Itoa(overridden.ID), "")
+		require.Contains(t, resp.Body.String(), `"status":"expired"`)
+
+		resp = performJSONRequest(r, http.MethodPost, "/reservations/"+strconv.Itoa(reservation.ID)+"/commit", "")
+		require.Equal(t, http.StatusGone, resp.Code)
+
+		level, err := defaultSandbox().GetStockLevel(18)
+		require.NoError(t, err)
+		require.Equal(t, 5, level.Available)
+	})
+
+	t.Run("Validation Error - Not enough stock to reserve", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/20/reservations", `{"quantity": 26}`)
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("Validation Error - TTL too long", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/20/reservations", `{"quantity": 1, "ttl_seconds": 7200}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "ttl_seconds must be between 1 and 3600")
+
+		resp = performJSONRequest(r, http.MethodPost, "/items/20/reservations", `{"quantity": 1, "ttl_seconds": 9223372037}`)
+		require.Equal(t, http.StatusBadRequest, resp.Code, "a TTL that overflows a duration must be rejected")
+	})
+
+	t.Run("Reservation not found", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/reservations/999/commit", "")
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
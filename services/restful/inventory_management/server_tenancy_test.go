@@ -0,0 +1,114 @@
+package restful
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/tenancy"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// performTenantRequest sends a JSON request on behalf of a tenant
+func performTenantRequest(r *gin.Engine, header, tenant, method, url, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(header, tenant)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestTenantIsolation(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Tenants do not see each other's changes", func(t *testing.T) {
+		resp := performTenantRequest(r, tenancy.HeaderTenantID, "alice", http.MethodDelete, "/items/1", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "alice", resp.Header().Get(tenancy.HeaderTenantID))
+
+		resp = performTenantRequest(r, tenancy.HeaderTenantID, "alice", http.MethodGet, "/items", "")
+		require.NotContains(t, resp.Body.String(), "Laptop")
+
+		resp = performTenantRequest(r, tenancy.HeaderTenantID, "bob", http.MethodGet, "/items", "")
+		require.Contains(t, resp.Body.String(), "Laptop")
+
+		resp = performJSONRequest(r, http.MethodGet, "/items", "")
+		require.Equal(t, tenancy.DefaultTenant, resp.Header().Get(tenancy.HeaderTenantID))
+		require.Contains(t, resp.Body.String(), "Laptop")
+	})
+
+	t.Run("An API key names a tenant", func(t *testing.T) {
+		resp := performTenantRequest(r, tenancy.HeaderAPIKey, "workshop-key-1", http.MethodPost, "/items", `{"name": "Workshop Kit", "price": 80}`)
+		require.Equal(t, http.StatusCreated, resp.Code)
+		tenant := resp.Header().Get(tenancy.HeaderTenantID)
+		require.Regexp(t, "^key-[0-9a-f]{16}$", tenant)
+
+		resp = performTenantRequest(r, tenancy.HeaderAPIKey, "workshop-key-1", http.MethodGet, "/items", "")
+		require.Contains(t, resp.Body.String(), "Workshop Kit")
+
+		resp = performTenantRequest(r, tenancy.HeaderTenantID, tenant, http.MethodGet, "/items", "")
+		require.Contains(t, resp.Body.String(), "Workshop Kit")
+
+		resp = performJSONRequest(r, http.MethodGet, "/items", "")
+		require.NotContains(t, resp.Body.String(), "Workshop Kit")
+	})
+
+	t.Run("Idempotency keys are scoped by tenant", func(t *testing.T) {
+		for _, tenant := range []string{"carol", "dave"} {
+			req, _ := http.NewRequest(http.MethodPost, "/items", bytes.NewBufferString(`{"name": "Desk Lamp", "price": 25}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(tenancy.HeaderTenantID, tenant)
+			req.Header.Set("Idempotency-Key", "create-lamp")
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			require.Equal(t, http.StatusCreated, resp.Code)
+			require.Empty(t, resp.Header().Get("Idempotent-Replayed"))
+		}
+	})
+
+	t.Run("Validation Error - Invalid tenant ID", func(t *testing.T) {
+		resp := performTenantRequest(r, tenancy.HeaderTenantID, "alice/bob", http.MethodGet, "/items", "")
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "tenant ID must be 1 to 64 letters, digits, dashes or underscores")
+	})
+}
+
+func TestTenantLimits(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Too many tenants", func(t *testing.T) {
+		defer func(max int) { tenancy.MaxTenants = max }(tenancy.MaxTenants)
+		tenancy.MaxTenants = 1
+
+		resp := performTenantRequest(r, tenancy.HeaderTenantID, "alice", http.MethodGet, "/items", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = performTenantRequest(r, tenancy.HeaderTenantID, "bob", http.MethodGet, "/items", "")
+		require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+		require.Contains(t, resp.Body.String(), "too many tenants: at most 1 can be active at the same time")
+
+		resp = performJSONRequest(r, http.MethodGet, "/items", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Idle sandboxes are discarded", func(t *testing.T) {
+		defer func(timeout time.Duration) { tenancy.IdleTimeout = timeout }(tenancy.IdleTimeout)
+		tenancy.IdleTimeout = time.Millisecond
+
+		performTenantRequest(r, tenancy.HeaderTenantID, "alice", http.MethodDelete, "/items/1", "")
+		performJSONRequest(r, http.MethodDelete, "/items/1", "")
+		time.Sleep(5 * time.Millisecond)
+
+		resp := performTenantRequest(r, tenancy.HeaderTenantID, "alice", http.MethodGet, "/items", "")
+		require.Contains(t, resp.Body.String(), "Laptop")
+
+		resp = performJSONRequest(r, http.MethodGet, "/items", "")
+		require.NotContains(t, resp.Body.String(), "Laptop")
+	})
+}
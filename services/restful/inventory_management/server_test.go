@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/abhivaikar/playpi/services/tenancy"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 )
@@ -15,6 +16,12 @@ func setupTestServer() *gin.Engine {
 	return StartServerForTesting() // Use your updated testing setup
 }
 
+// defaultSandbox returns the sandbox of requests that do not name a tenant
+func defaultSandbox() *sandbox {
+	t, _ := sandboxes.Get(tenancy.DefaultTenant)
+	return t.sandbox()
+}
+
 func TestGetItems(t *testing.T) {
 	r := setupTestServer()
 
@@ -0,0 +1,121 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemTrash(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Deleted items move to the trash", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodDelete, "/items/1", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		performJSONRequest(r, http.MethodDelete, "/items/2", "")
+
+		resp = performJSONRequest(r, http.MethodGet, "/items", "")
+		require.NotContains(t, resp.Body.String(), "Laptop")
+
+		resp = performJSONRequest(r, http.MethodGet, "/items/trash", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var trash []InventoryItem
+		err := json.Unmarshal(resp.Body.Bytes(), &trash)
+		require.NoError(t, err)
+		require.Len(t, trash, 2)
+		require.Equal(t, "Smartphone", trash[0].Name) // The most recently deleted first
+		require.Equal(t, "Laptop", trash[1].Name)
+		require.NotNil(t, trash[1].DeletedAt)
+	})
+
+	t.Run("Include deleted items in the listing", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/items?include_deleted=true", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var items []InventoryItem
+		err := json.Unmarshal(resp.Body.Bytes(), &items)
+		require.NoError(t, err)
+		require.Len(t, items, 20)
+		require.Equal(t, "Laptop", items[0].Name)
+		require.NotNil(t, items[0].DeletedAt)
+		require.Nil(t, items[2].DeletedAt)
+	})
+
+	t.Run("Restore a deleted item", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/1/restore", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NotContains(t, resp.Body.String(), "deleted_at")
+
+		var items []InventoryItem
+		resp = performJSONRequest(r, http.MethodGet, "/items", "")
+		err := json.Unmarshal(resp.Body.Bytes(), &items)
+		require.NoError(t, err)
+		require.Equal(t, "Laptop", items[0].Name)
+		require.Len(t, defaultSandbox().GetTrash(), 1)
+	})
+
+	t.Run("Restore an item in the version 2 representation", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/v2/items/2/restore", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"price_cents":80000`)
+	})
+
+	t.Run("Conflict - Item is not in the trash", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/1/restore", "")
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "item is not in the trash")
+	})
+
+	t.Run("Restore an item whose category was deleted by a cascade", func(t *testing.T) {
+		performJSONRequest(r, http.MethodDelete, "/categories/5?on_delete=cascade", "")
+
+		resp := performJSONRequest(r, http.MethodPost, "/items/19/restore", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var item InventoryItem
+		err := json.Unmarshal(resp.Body.Bytes(), &item)
+		require.NoError(t, err)
+		require.Zero(t, item.CategoryID)
+		require.NotZero(t, item.SupplierID)
+	})
+
+	t.Run("Item not found", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/items/999/restore", "")
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("Validation Error - Invalid include_deleted", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/items?include_deleted=maybe", "")
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "include_deleted must be true or false")
+	})
+}
+
+func TestTrashRetention(t *testing.T) {
+	r := setupTestServer()
+	performJSONRequest(r, http.MethodDelete, "/items/3", "")
+
+	req, _ := http.NewRequest(http.MethodGet, "/items/trash", nil)
+	req.Header.Set(clock.HeaderNow, time.Now().Add(TrashRetention+time.Hour).Format(time.RFC3339))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Contains(t, resp.Body.String(), `"id":3`, "a time override must not purge the trash")
+
+	_, err := defaultSandbox().clock.Advance(TrashRetention)
+	require.NoError(t, err)
+
+	resp = performJSONRequest(r, http.MethodGet, "/items/trash", "")
+	require.Equal(t, "[]", resp.Body.String())
+
+	resp = performJSONRequest(r, http.MethodPost, "/items/3/restore", "")
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
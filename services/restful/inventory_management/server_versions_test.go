@@ -0,0 +1,143 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionedItems(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Create an item in version 2", func(t *testing.T) {
+		payload := `{"name": "Webcam", "price_cents": 8099, "currency": "USD", "stock": {"on_hand": 7}}`
+		resp := performJSONRequest(r, http.MethodPost, "/v2/items", payload)
+
+		require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+		require.Equal(t, "2", resp.Header().Get(HeaderAPIVersion))
+		require.Empty(t, resp.Header().Get("Deprecation"))
+
+		var item ItemV2
+		err := json.Unmarshal(resp.Body.Bytes(), &item)
+		require.NoError(t, err)
+		require.Equal(t, ItemV2{ID: 21, Name: "Webcam", PriceCents: 8099, Currency: "USD", Stock: StockV2{OnHand: 7, Available: 7}}, item)
+	})
+
+	t.Run("Both versions share the same items", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/v1/items", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"name":"Webcam","description":"","price":80.99,"quantity":7`)
+
+		resp = performJSONRequest(r, http.MethodGet, "/v2/items", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"price_cents":150000,"currency":"USD","stock":{"on_hand":10,"reserved":0,"available":10}`)
+	})
+
+	t.Run("Patch the stock in version 2", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPatch, "/v2/items/21", `{"stock": {"on_hand": 3}, "price_cents": 7500}`)
+
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		require.Contains(t, resp.Body.String(), `"price_cents":7500,"currency":"USD","stock":{"on_hand":3,"reserved":0,"available":3}`)
+	})
+
+	t.Run("Select version 2 with the API-Version header", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set(HeaderAPIVersion, "2")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "2", resp.Header().Get(HeaderAPIVersion))
+		require.Contains(t, resp.Body.String(), `"price_cents":7500`)
+	})
+
+	t.Run("Version 1 is deprecated", func(t *testing.T) {
+		for _, path := range []string{"/items", "/v1/items"} {
+			resp := performJSONRequest(r, http.MethodGet, path, "")
+
+			require.Equal(t, http.StatusOK, resp.Code)
+			require.Equal(t, "1", resp.Header().Get(HeaderAPIVersion))
+			require.Equal(t, "@1767225600", resp.Header().Get("Deprecation"))
+			require.Equal(t, "Thu, 01 Jul 2027 00:00:00 GMT", resp.Header().Get("Sunset"))
+			require.Equal(t, `</v2/items>; rel="successor-version"`, resp.Header().Get("Link"))
+		}
+	})
+
+	t.Run("Validation Error - Invalid API-Version", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set(HeaderAPIVersion, "3")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "API-Version must be 1 or 2")
+	})
+
+	t.Run("Validation Error - Invalid currency", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/v2/items", `{"name": "Webcam", "price_cents": 8099, "currency": "EUR"}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "currency must be USD")
+	})
+
+	t.Run("Validation Error - Version 1 fields in version 2", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPatch, "/v2/items/21", `{"price": 10}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "price is not part of version 2, use price_cents")
+	})
+}
+
+func TestVersionedBulkItems(t *testing.T) {
+	r := setupTestServer()
+
+	performBulkRequestV2 := func(method, payload string) (int, BulkResponseV2) {
+		resp := performJSONRequest(r, method, "/v2/items/bulk", payload)
+
+		var response BulkResponseV2
+		err := json.Unmarshal(resp.Body.Bytes(), &response)
+		require.NoError(t, err)
+		return resp.Code, response
+	}
+
+	t.Run("Add a batch in version 2", func(t *testing.T) {
+		payload := `[
+			{"name": "Webcam", "price_cents": 8099, "currency": "USD", "stock": {"on_hand": 7}},
+			{"name": "Microphone", "price_cents": 12000, "currency": "EUR", "stock": {"on_hand": 2}}
+		]`
+		status, response := performBulkRequestV2(http.MethodPost, payload)
+
+		require.Equal(t, http.StatusMultiStatus, status)
+		require.Equal(t, 1, response.Succeeded)
+		require.Equal(t, ItemV2{ID: 21, Name: "Webcam", PriceCents: 8099, Currency: "USD", Stock: StockV2{OnHand: 7, Available: 7}}, *response.Results[0].Item)
+		require.Equal(t, http.StatusBadRequest, response.Results[1].Status)
+		require.Equal(t, "currency must be USD", response.Results[1].Error)
+
+		item, err := defaultSandbox().GetItemByID(21)
+		require.NoError(t, err)
+		require.Equal(t, 80.99, item.Price)
+		require.Equal(t, 7, item.Quantity)
+	})
+
+	t.Run("Update a batch in version 2", func(t *testing.T) {
+		payload := `[
+			{"id": 21, "name": "Webcam", "price_cents": 7500, "stock": {"on_hand": 3}},
+			{"id": 21, "name": "Webcam", "price_cents": -1, "stock": {"on_hand": 3}},
+			{"name": "Webcam", "price_cents": 7500}
+		]`
+		status, response := performBulkRequestV2(http.MethodPut, payload)
+
+		require.Equal(t, http.StatusMultiStatus, status)
+		require.Equal(t, http.StatusOK, response.Results[0].Status)
+		require.Equal(t, int64(7500), response.Results[0].Item.PriceCents)
+		require.Equal(t, StockV2{OnHand: 3, Available: 3}, response.Results[0].Item.Stock)
+		require.Equal(t, 21, response.Results[1].ID)
+		require.Contains(t, response.Results[1].Error, "price_cents must be a whole number")
+		require.Equal(t, "id is required", response.Results[2].Error)
+	})
+}
@@ -0,0 +1,225 @@
+package restful
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/restful/webhooks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "playpi-test-secret"
+
+func subscribeTestReceiver(t *testing.T, r *gin.Engine, receiver *webhooks.Receiver, events string) (webhooks.Subscription, *httptest.Server) {
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	payload := `{"url": "` + server.URL + `/hooks", "events": ` + events + `, "secret": "` + testWebhookSecret + `"}`
+	resp := performJSONRequest(r, http.MethodPost, "/webhooks", payload)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	var subscription webhooks.Subscription
+	err := json.Unmarshal(resp.Body.Bytes(), &subscription)
+	require.NoError(t, err)
+	require.Equal(t, "/webhooks/"+strconv.Itoa(subscription.ID), resp.Header().Get("Location"))
+	return subscription, server
+}
+
+func waitForDeliveries(t *testing.T, receiver *webhooks.Receiver, count int) []webhooks.Received {
+	require.Eventually(t, func() bool {
+		return len(receiver.Received()) >= count
+	}, 2*time.Second, 5*time.Millisecond)
+	return receiver.Received()
+}
+
+func waitForDelivery(t *testing.T, subscriptionID int, deliveryID int, status string) webhooks.Delivery {
+	var delivery *webhooks.Delivery
+	require.Eventually(t, func() bool {
+		var err error
+		delivery, err = defaultSandbox().dispatcher.Delivery(subscriptionID, deliveryID)
+		return err == nil && delivery.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+	return *delivery
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	r := setupTestServer()
+	receiver := &webhooks.Receiver{Secret: testWebhookSecret}
+	subscription, _ := subscribeTestReceiver(t, r, receiver, `["item.created", "item.low_stock"]`)
+
+	t.Run("Signed delivery of an event", func(t *testing.T) {
+		performJSONRequest(r, http.MethodPost, "/items", `{"name": "Webcam", "price": 80, "quantity": 7}`)
+
+		received := waitForDeliveries(t, receiver, 1)
+		require.Equal(t, "item.created", received[0].Event)
+		require.Equal(t, "1", received[0].Delivery)
+		require.True(t, received[0].SignatureValid)
+
+		var body map[string]interface{}
+		err := json.Unmarshal(received[0].Body, &body)
+		require.NoError(t, err)
+		require.Equal(t, "item.created", body["event"])
+		require.Equal(t, "Webcam", body["data"].(map[string]interface{})["name"])
+
+		delivery := waitForDelivery(t, subscription.ID, 1, webhooks.DeliverySucceeded)
+		require.Len(t, delivery.Attempts, 1)
+		require.Equal(t, http.StatusOK, delivery.Attempts[0].StatusCode)
+	})
+
+	t.Run("Low stock is raised when the quantity falls to the threshold", func(t *testing.T) {
+		performJSONRequest(r, http.MethodPost, "/items/1/stock", `{"type": "sell", "quantity": 4}`)
+		performJSONRequest(r, http.MethodPost, "/items/1/stock", `{"type": "sell", "quantity": 1}`)
+		performJSONRequest(r, http.MethodPost, "/items/1/stock", `{"type": "sell", "quantity": 1}`)
+
+		received := waitForDeliveries(t, receiver, 2)
+		require.Len(t, received, 2)
+		require.Equal(t, "item.low_stock", received[1].Event)
+		require.Contains(t, string(received[1].Body), `"quantity":5`)
+	})
+
+	t.Run("Rolled back changes are not delivered", func(t *testing.T) {
+		payload := `[{"name": "Webcam Pro", "price": 120}, {"name": "X", "price": 1}]`
+		resp := performJSONRequest(r, http.MethodPost, "/items/bulk?atomic=true", payload)
+		require.Equal(t, http.StatusBadRequest, resp.Code, resp.Body.String())
+
+		performJSONRequest(r, http.MethodPost, "/items", `{"name": "Webcam Mini", "price": 40}`)
+
+		received := waitForDeliveries(t, receiver, 3)
+		require.Contains(t, string(received[2].Body), "Webcam Mini")
+	})
+
+	t.Run("Get the delivery log", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/webhooks/"+strconv.Itoa(subscription.ID)+"/deliveries?status=succeeded", "")
+
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var deliveries []webhooks.Delivery
+		err := json.Unmarshal(resp.Body.Bytes(), &deliveries)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 3)
+	})
+
+	t.Run("Deliveries are timestamped with the tenant's clock", func(t *testing.T) {
+		defer defaultSandbox().clock.Reset()
+		now := time.Date(2030, time.January, 15, 9, 0, 0, 0, time.UTC)
+		defaultSandbox().clock.Freeze()
+		defaultSandbox().clock.Set(now)
+		count := len(receiver.Received())
+
+		performJSONRequest(r, http.MethodPost, "/items", `{"name": "Microphone", "price": 120, "quantity": 9}`)
+
+		received := waitForDeliveries(t, receiver, count+1)
+		require.Equal(t, strconv.FormatInt(now.Unix(), 10), received[count].Timestamp)
+		require.True(t, received[count].SignatureValid)
+
+		deliveryID, err := strconv.Atoi(received[count].Delivery)
+		require.NoError(t, err)
+		delivery := waitForDelivery(t, subscription.ID, deliveryID, webhooks.DeliverySucceeded)
+		require.Equal(t, now, delivery.CreatedAt)
+		require.Equal(t, now, delivery.Attempts[0].At)
+	})
+}
+
+func TestWebhookRetries(t *testing.T) {
+	defer func(delay time.Duration) { webhooks.RetryBaseDelay = delay }(webhooks.RetryBaseDelay)
+	webhooks.RetryBaseDelay = time.Millisecond
+
+	r := setupTestServer()
+	receiver := &webhooks.Receiver{Secret: testWebhookSecret, Status: http.StatusInternalServerError}
+	subscription, _ := subscribeTestReceiver(t, r, receiver, `["item.deleted"]`)
+	deliveryURL := "/webhooks/" + strconv.Itoa(subscription.ID) + "/deliveries/1"
+
+	performJSONRequest(r, http.MethodDelete, "/items/3", "")
+
+	t.Run("Failing receivers are retried until the last attempt", func(t *testing.T) {
+		delivery := waitForDelivery(t, subscription.ID, 1, webhooks.DeliveryFailed)
+
+		require.Len(t, delivery.Attempts, webhooks.MaxAttempts)
+		require.Equal(t, "receiver answered with status 500", delivery.Attempts[4].Error)
+		require.Len(t, receiver.Received(), webhooks.MaxAttempts)
+	})
+
+	t.Run("Redeliver a failed delivery", func(t *testing.T) {
+		receiver.SetStatus(http.StatusNoContent)
+
+		resp := performJSONRequest(r, http.MethodPost, deliveryURL+"/redeliver", "")
+		require.Equal(t, http.StatusAccepted, resp.Code)
+
+		delivery := waitForDelivery(t, subscription.ID, 1, webhooks.DeliverySucceeded)
+		require.Len(t, delivery.Attempts, webhooks.MaxAttempts+1)
+
+		received := receiver.Received()
+		require.Equal(t, received[0].Body, received[len(received)-1].Body)
+		require.Equal(t, "1", received[len(received)-1].Delivery)
+	})
+
+	t.Run("Delivery not found", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/webhooks/"+strconv.Itoa(subscription.ID)+"/deliveries/99/redeliver", "")
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+		require.Contains(t, resp.Body.String(), "delivery not found")
+	})
+}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("A secret is generated when none is given", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/webhooks", `{"url": "http://localhost:9000/hooks", "events": ["order.placed"]}`)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Contains(t, resp.Body.String(), `"secret":"whsec_`)
+	})
+
+	t.Run("Validation Error - Invalid URL", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/webhooks", `{"url": "localhost:9000", "events": ["order.placed"]}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "url must be an absolute http or https URL")
+	})
+
+	t.Run("Validation Error - Unknown event", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodPost, "/webhooks", `{"url": "http://localhost:9000/hooks", "events": ["task.completed"]}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "events must only contain: item.created, item.updated")
+	})
+
+	t.Run("The secret is only returned when subscribing", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodGet, "/webhooks/1", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NotContains(t, resp.Body.String(), "secret")
+
+		resp = performJSONRequest(r, http.MethodGet, "/webhooks", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NotContains(t, resp.Body.String(), "secret")
+	})
+
+	t.Run("The delivery log keeps the most recent deliveries", func(t *testing.T) {
+		receiver := &webhooks.Receiver{Secret: testWebhookSecret}
+		subscription, _ := subscribeTestReceiver(t, r, receiver, `["item.updated"]`)
+		for i := 0; i <= webhooks.MaxDeliveries; i++ {
+			defaultSandbox().dispatcher.Publish(EventItemUpdated, gin.H{"id": i})
+		}
+
+		deliveries, err := defaultSandbox().dispatcher.Deliveries(subscription.ID, "")
+		require.NoError(t, err)
+		require.Len(t, deliveries, webhooks.MaxDeliveries)
+		require.Equal(t, 2, deliveries[0].ID)
+		waitForDeliveries(t, receiver, webhooks.MaxDeliveries+1)
+	})
+
+	t.Run("Delete a subscription", func(t *testing.T) {
+		resp := performJSONRequest(r, http.MethodDelete, "/webhooks/1", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = performJSONRequest(r, http.MethodGet, "/webhooks/1", "")
+		require.Equal(t, http.StatusNotFound, resp.Code)
+		require.Contains(t, resp.Body.String(), "webhook not found")
+	})
+}
@@ -2,20 +2,30 @@ package restful
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 // InventoryItem represents an item in the inventory
 type InventoryItem struct {
-	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
-	Quantity    int     `json:"quantity"`
+	ID          int     `json:"id" xml:"id"`
+	Name        string  `json:"name" xml:"name"`
+	Description string  `json:"description" xml:"description"`
+	Price       float64 `json:"price" xml:"price"`
+	Quantity    int     `json:"quantity" xml:"quantity"`
+	CategoryID  int     `json:"category_id,omitempty" xml:"category_id,omitempty"`
+	SupplierID  int     `json:"supplier_id,omitempty" xml:"supplier_id,omitempty"`
+
+	// DeletedAt is only set on items in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" xml:"deleted_at,omitempty" csv:"-"`
+
+	// Category and Supplier are only filled in when a listing asks for them with ?expand=
+	Category *Category `json:"category,omitempty" xml:"category,omitempty" csv:"-"`
+	Supplier *Supplier `json:"supplier,omitempty" xml:"supplier,omitempty" csv:"-"`
 }
 
-// In-memory store for inventory items
-var inventory = []InventoryItem{}
-var nextID = 1 // Auto-increment ID
+// ErrItemNotFound is returned when no item matches the requested ID
+var ErrItemNotFound = errors.New("item not found")
 
 // Validation functions
 func validateItem(item InventoryItem) error {
@@ -35,48 +45,108 @@ func validateItem(item InventoryItem) error {
 }
 
 // Service functions
-func GetAllItems() []InventoryItem {
-	return inventory
+func (sb *sandbox) GetAllItems() []InventoryItem {
+	sb.mu.Lock()
+	defer sb.unlock()
+	return append([]InventoryItem(nil), sb.inventory...)
+}
+
+func (sb *sandbox) GetItemByID(id int) (*InventoryItem, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+	return sb.getItemByID(id)
+}
+
+func (sb *sandbox) AddItem(newItem InventoryItem) (*InventoryItem, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+	return sb.addItem(newItem)
+}
+
+func (sb *sandbox) UpdateItem(id int, updatedData InventoryItem) (*InventoryItem, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+	return sb.updateItem(id, updatedData)
+}
+
+func (sb *sandbox) PatchItem(id int, updates map[string]interface{}) (*InventoryItem, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+	return sb.patchItem(id, updates)
+}
+
+func (sb *sandbox) DeleteItem(id int) error {
+	sb.mu.Lock()
+	defer sb.unlock()
+	return sb.deleteItem(id)
 }
 
-func GetItemByID(id int) (*InventoryItem, error) {
-	for _, item := range inventory {
+func (sb *sandbox) getItemByID(id int) (*InventoryItem, error) {
+	for _, item := range sb.inventory {
 		if item.ID == id {
 			return &item, nil
 		}
 	}
-	return nil, errors.New("item not found")
+	return nil, ErrItemNotFound
+}
+
+// findItemIndex returns the position of the item in the inventory slice
+func (sb *sandbox) findItemIndex(id int) (int, error) {
+	for i, item := range sb.inventory {
+		if item.ID == id {
+			return i, nil
+		}
+	}
+	return -1, ErrItemNotFound
 }
 
-func AddItem(newItem InventoryItem) (*InventoryItem, error) {
+func (sb *sandbox) addItem(newItem InventoryItem) (*InventoryItem, error) {
 	if err := validateItem(newItem); err != nil {
 		return nil, err
 	}
+	if err := sb.validateReferences(newItem); err != nil {
+		return nil, err
+	}
 
-	newItem.ID = nextID
-	nextID++
-	inventory = append(inventory, newItem)
+	newItem.ID = sb.nextID
+	newItem.Category, newItem.Supplier = nil, nil
+	sb.nextID++
+	sb.inventory = append(sb.inventory, newItem)
+	if newItem.Quantity > 0 {
+		sb.recordMovement(newItem.ID, MovementInitial, newItem.Quantity, newItem.Quantity, "item created")
+	}
+	sb.emit(EventItemCreated, newItem)
 
 	return &newItem, nil
 }
 
-func UpdateItem(id int, updatedData InventoryItem) (*InventoryItem, error) {
-	for i, item := range inventory {
+func (sb *sandbox) updateItem(id int, updatedData InventoryItem) (*InventoryItem, error) {
+	for i, item := range sb.inventory {
 		if item.ID == id {
 			if err := validateItem(updatedData); err != nil {
 				return nil, err
 			}
+			if err := sb.validateReferences(updatedData); err != nil {
+				return nil, err
+			}
+			if err := sb.checkReservedQuantity(id, updatedData.Quantity); err != nil {
+				return nil, err
+			}
 			updatedData.ID = id // Preserve the original ID
-			inventory[i] = updatedData
+			updatedData.Category, updatedData.Supplier = nil, nil
+			sb.inventory[i] = updatedData
+			sb.emit(EventItemUpdated, updatedData)
+			sb.recordAdjustment(item, updatedData)
 			return &updatedData, nil
 		}
 	}
-	return nil, errors.New("item not found")
+	return nil, ErrItemNotFound
 }
 
-func PatchItem(id int, updates map[string]interface{}) (*InventoryItem, error) {
-	for i, item := range inventory {
+func (sb *sandbox) patchItem(id int, updates map[string]interface{}) (*InventoryItem, error) {
+	for i, item := range sb.inventory {
 		if item.ID == id {
+			previous := item
 			if name, exists := updates["name"]; exists {
 				nameStr, ok := name.(string)
 				if !ok || len(nameStr) < 3 || len(nameStr) > 50 {
@@ -105,20 +175,73 @@ func PatchItem(id int, updates map[string]interface{}) (*InventoryItem, error) {
 				}
 				item.Quantity = int(quantityInt)
 			}
+			if categoryID, exists := updates["category_id"]; exists {
+				reference, ok := referenceID(categoryID)
+				if !ok {
+					return nil, errors.New("category_id must be a positive number or null")
+				}
+				item.CategoryID = reference
+			}
+			if supplierID, exists := updates["supplier_id"]; exists {
+				reference, ok := referenceID(supplierID)
+				if !ok {
+					return nil, errors.New("supplier_id must be a positive number or null")
+				}
+				item.SupplierID = reference
+			}
+			if err := sb.validateReferences(item); err != nil {
+				return nil, err
+			}
+			if err := sb.checkReservedQuantity(id, item.Quantity); err != nil {
+				return nil, err
+			}
 
-			inventory[i] = item
+			sb.inventory[i] = item
+			sb.emit(EventItemUpdated, item)
+			sb.recordAdjustment(previous, item)
 			return &item, nil
 		}
 	}
-	return nil, errors.New("item not found")
+	return nil, ErrItemNotFound
 }
 
-func DeleteItem(id int) error {
-	for i, item := range inventory {
+// deleteItem moves an item to the trash, where it can be restored until it is purged
+func (sb *sandbox) deleteItem(id int) error {
+	for i, item := range sb.inventory {
 		if item.ID == id {
-			inventory = append(inventory[:i], inventory[i+1:]...)
+			sb.inventory = append(sb.inventory[:i], sb.inventory[i+1:]...)
+			sb.releaseReservationsForItem(id)
+			sb.trashItem(item)
+			sb.emit(EventItemDeleted, item)
 			return nil
 		}
 	}
-	return errors.New("item not found")
+	return ErrItemNotFound
+}
+
+// referenceID reads a category_id or supplier_id of a PATCH payload, where null or 0 removes the reference
+func referenceID(value interface{}) (int, bool) {
+	if value == nil {
+		return 0, true
+	}
+	id, ok := value.(float64)
+	if !ok || id < 0 || id != float64(int(id)) {
+		return 0, false
+	}
+	return int(id), true
+}
+
+// checkReservedQuantity rejects quantities that would not cover the active reservations of the item
+func (sb *sandbox) checkReservedQuantity(id int, quantity int) error {
+	if reserved := sb.reservedQuantity(id); quantity < reserved {
+		return fmt.Errorf("quantity cannot be lower than the reserved quantity of %d", reserved)
+	}
+	return nil
+}
+
+// recordAdjustment adds a ledger entry when a PUT or PATCH changed the quantity
+func (sb *sandbox) recordAdjustment(before, after InventoryItem) {
+	if delta := after.Quantity - before.Quantity; delta != 0 {
+		sb.recordMovement(after.ID, MovementAdjustment, delta, after.Quantity, "quantity overwritten")
+	}
 }
@@ -0,0 +1,366 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Stock movement types
+const (
+	MovementReceive    = "receive"
+	MovementSell       = "sell"
+	MovementDamage     = "damage"
+	MovementInitial    = "initial"
+	MovementAdjustment = "adjustment"
+)
+
+// Reservation statuses
+const (
+	ReservationActive    = "active"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// DefaultReservationTTL is used when a reservation does not specify ttl_seconds
+const DefaultReservationTTL = 15 * time.Minute
+
+// MaxReservationTTL is the longest a reservation may be held
+const MaxReservationTTL = time.Hour
+
+var (
+	ErrInsufficientStock     = errors.New("insufficient stock")
+	ErrReservationNotFound   = errors.New("reservation not found")
+	ErrReservationExpired    = errors.New("reservation has expired")
+	ErrReservationNotActive  = errors.New("reservation is no longer active")
+	errInvalidMovementType   = errors.New("type must be one of: receive, sell, damage")
+	errInvalidStockQuantity  = errors.New("quantity must be greater than 0")
+	errReasonTooLong         = errors.New("reason cannot exceed 200 characters")
+	errReasonRequired        = errors.New("reason is required for damage adjustments")
+	errInvalidReservationTTL = fmt.Errorf("ttl_seconds must be between 1 and %d", int(MaxReservationTTL.Seconds()))
+)
+
+// StockAdjustment is a request to move stock in or out of an item
+type StockAdjustment struct {
+	Type     string `json:"type" xml:"type"`
+	Quantity int    `json:"quantity" xml:"quantity"`
+	Reason   string `json:"reason" xml:"reason"`
+}
+
+// StockMovement is an entry of the stock ledger of an item
+type StockMovement struct {
+	ID            int       `json:"id" xml:"id"`
+	ItemID        int       `json:"item_id" xml:"item_id"`
+	Type          string    `json:"type" xml:"type"`
+	Quantity      int       `json:"quantity" xml:"quantity"`
+	Delta         int       `json:"delta" xml:"delta"`
+	QuantityAfter int       `json:"quantity_after" xml:"quantity_after"`
+	Reason        string    `json:"reason,omitempty" xml:"reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at" xml:"created_at"`
+}
+
+// StockLevel summarises the stock of an item
+type StockLevel struct {
+	ItemID    int `json:"item_id" xml:"item_id"`
+	Quantity  int `json:"quantity" xml:"quantity"`
+	Reserved  int `json:"reserved" xml:"reserved"`
+	Available int `json:"available" xml:"available"`
+}
+
+// ReservationRequest is a request to hold stock for a limited time
+type ReservationRequest struct {
+	Quantity   int `json:"quantity" xml:"quantity"`
+	TTLSeconds int `json:"ttl_seconds" xml:"ttl_seconds"`
+}
+
+// Reservation holds stock of an item until it is committed, released or expires
+type Reservation struct {
+	ID        int       `json:"id" xml:"id"`
+	ItemID    int       `json:"item_id" xml:"item_id"`
+	Quantity  int       `json:"quantity" xml:"quantity"`
+	Status    string    `json:"status" xml:"status"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" xml:"expires_at"`
+}
+
+// AdjustStock receives, sells or writes off stock of an item. Outgoing
+// movements can only use stock that is not reserved, so the quantity of an
+// item never becomes negative.
+func (sb *sandbox) AdjustStock(id int, adjustment StockAdjustment) (*StockMovement, error) {
+	delta, err := validateStockAdjustment(adjustment)
+	if err != nil {
+		return nil, err
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findItemIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	if delta < 0 {
+		if available := sb.inventory[index].Quantity - sb.reservedQuantity(id); -delta > available {
+			return nil, fmt.Errorf("%w: only %d available", ErrInsufficientStock, available)
+		}
+	}
+
+	sb.inventory[index].Quantity += delta
+	return sb.recordMovement(id, adjustment.Type, delta, sb.inventory[index].Quantity, adjustment.Reason), nil
+}
+
+// GetStockLevel returns the on-hand, reserved and available quantity of an item
+func (sb *sandbox) GetStockLevel(id int) (*StockLevel, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	item, err := sb.getItemByID(id)
+	if err != nil {
+		return nil, err
+	}
+	reserved := sb.reservedQuantity(id)
+	return &StockLevel{ItemID: id, Quantity: item.Quantity, Reserved: reserved, Available: item.Quantity - reserved}, nil
+}
+
+// GetStockMovements returns the ledger of an item in chronological order,
+// optionally filtered by movement type and limited to the most recent entries
+func (sb *sandbox) GetStockMovements(id int, movementType string, limit int) ([]StockMovement, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.getItemByID(id); err != nil {
+		return nil, err
+	}
+	result := []StockMovement{}
+	for _, movement := range sb.movements {
+		if movement.ItemID == id && (movementType == "" || movement.Type == movementType) {
+			result = append(result, movement)
+		}
+	}
+	if limit > 0 && len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
+}
+
+// ReserveStock holds available stock of an item for request.TTLSeconds
+func (sb *sandbox) ReserveStock(id int, request ReservationRequest) (*Reservation, error) {
+	if request.Quantity <= 0 {
+		return nil, errInvalidStockQuantity
+	}
+	ttl := DefaultReservationTTL
+	if request.TTLSeconds != 0 {
+		// Checked before converting, as a large TTL overflows a time.Duration
+		if request.TTLSeconds < 0 || request.TTLSeconds > int(MaxReservationTTL/time.Second) {
+			return nil, errInvalidReservationTTL
+		}
+		ttl = time.Duration(request.TTLSeconds) * time.Second
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	item, err := sb.getItemByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if available := item.Quantity - sb.reservedQuantity(id); request.Quantity > available {
+		return nil, fmt.Errorf("%w: only %d available", ErrInsufficientStock, available)
+	}
+
+	// Reservations expire by the tenant's clock, so they are timestamped with it too
+	createdAt := sb.clock.Now()
+	reservation := Reservation{
+		ID:        sb.nextReservationID,
+		ItemID:    id,
+		Quantity:  request.Quantity,
+		Status:    ReservationActive,
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(ttl),
+	}
+	sb.nextReservationID++
+	sb.reservations = append(sb.reservations, reservation)
+	return &reservation, nil
+}
+
+// GetReservations returns every reservation ever made for an item
+func (sb *sandbox) GetReservations(id int) ([]Reservation, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.getItemByID(id); err != nil {
+		return nil, err
+	}
+	sb.expireReservations()
+	result := []Reservation{}
+	for _, reservation := range sb.reservations {
+		if reservation.ItemID == id {
+			result = append(result, reservation)
+		}
+	}
+	return result, nil
+}
+
+// GetReservation returns a single reservation
+func (sb *sandbox) GetReservation(id int) (*Reservation, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	sb.expireReservations()
+	index, err := sb.findReservationIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	reservation := sb.reservations[index]
+	return &reservation, nil
+}
+
+// ReleaseReservation gives the reserved stock back without selling it
+func (sb *sandbox) ReleaseReservation(id int) (*Reservation, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.activeReservationIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	sb.reservations[index].Status = ReservationReleased
+	reservation := sb.reservations[index]
+	return &reservation, nil
+}
+
+// CommitReservation sells the reserved stock
+func (sb *sandbox) CommitReservation(id int) (*Reservation, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.activeReservationIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	reservation := &sb.reservations[index]
+	itemIndex, err := sb.findItemIndex(reservation.ItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	sb.inventory[itemIndex].Quantity -= reservation.Quantity
+	sb.recordMovement(reservation.ItemID, MovementSell, -reservation.Quantity, sb.inventory[itemIndex].Quantity,
+		fmt.Sprintf("reservation %d committed", reservation.ID))
+	reservation.Status = ReservationCommitted
+	committed := *reservation
+	return &committed, nil
+}
+
+func validateStockAdjustment(adjustment StockAdjustment) (int, error) {
+	if adjustment.Quantity <= 0 {
+		return 0, errInvalidStockQuantity
+	}
+	if len(adjustment.Reason) > 200 {
+		return 0, errReasonTooLong
+	}
+	switch adjustment.Type {
+	case MovementReceive:
+		return adjustment.Quantity, nil
+	case MovementSell:
+		return -adjustment.Quantity, nil
+	case MovementDamage:
+		if adjustment.Reason == "" {
+			return 0, errReasonRequired
+		}
+		return -adjustment.Quantity, nil
+	}
+	return 0, errInvalidMovementType
+}
+
+// recordMovement appends an entry to the ledger, raises item.updated for stock
+// changes and item.low_stock when the quantity falls to LowStockThreshold or
+// below. The caller must hold sb.mu.
+func (sb *sandbox) recordMovement(itemID int, movementType string, delta int, quantityAfter int, reason string) *StockMovement {
+	quantity := delta
+	if quantity < 0 {
+		quantity = -quantity
+	}
+	movement := StockMovement{
+		ID:            sb.nextMovementID,
+		ItemID:        itemID,
+		Type:          movementType,
+		Quantity:      quantity,
+		Delta:         delta,
+		QuantityAfter: quantityAfter,
+		Reason:        reason,
+		CreatedAt:     sb.now(),
+	}
+	sb.nextMovementID++
+	sb.movements = append(sb.movements, movement)
+	item, err := sb.getItemByID(itemID)
+	if err != nil {
+		return &movement
+	}
+	// Creates, PUT and PATCH raise their own events
+	if movementType != MovementInitial && movementType != MovementAdjustment {
+		sb.emit(EventItemUpdated, item)
+	}
+	if before := quantityAfter - delta; before > LowStockThreshold && quantityAfter <= LowStockThreshold {
+		sb.emit(EventItemLowStock, item)
+	}
+	return &movement
+}
+
+// expireReservations marks active reservations past their expiry time as
+// expired. It goes by the tenant's clock, as an expiry cannot be undone.
+func (sb *tenant) expireReservations() {
+	current := sb.clock.Now()
+	for i := range sb.reservations {
+		if sb.reservations[i].Status == ReservationActive && !current.Before(sb.reservations[i].ExpiresAt) {
+			sb.reservations[i].Status = ReservationExpired
+		}
+	}
+}
+
+// reservedQuantity returns the stock of an item held by active reservations
+func (sb *sandbox) reservedQuantity(itemID int) int {
+	sb.expireReservations()
+	reserved := 0
+	for _, reservation := range sb.reservations {
+		if reservation.ItemID == itemID && reservation.Status == ReservationActive {
+			reserved += reservation.Quantity
+		}
+	}
+	return reserved
+}
+
+// releaseReservationsForItem releases the active reservations of a deleted item
+func (sb *sandbox) releaseReservationsForItem(itemID int) {
+	for i := range sb.reservations {
+		if sb.reservations[i].ItemID == itemID && sb.reservations[i].Status == ReservationActive {
+			sb.reservations[i].Status = ReservationReleased
+		}
+	}
+}
+
+func (sb *sandbox) findReservationIndex(id int) (int, error) {
+	for i, reservation := range sb.reservations {
+		if reservation.ID == id {
+			return i, nil
+		}
+	}
+	return -1, ErrReservationNotFound
+}
+
+// activeReservationIndex finds a reservation that can still be committed or released
+func (sb *sandbox) activeReservationIndex(id int) (int, error) {
+	sb.expireReservations()
+	index, err := sb.findReservationIndex(id)
+	if err != nil {
+		return -1, err
+	}
+	switch sb.reservations[index].Status {
+	case ReservationActive:
+		return index, nil
+	case ReservationExpired:
+		return -1, ErrReservationExpired
+	}
+	return -1, fmt.Errorf("%w: %s", ErrReservationNotActive, sb.reservations[index].Status)
+}
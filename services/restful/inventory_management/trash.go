@@ -0,0 +1,98 @@
+package restful
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// TrashRetention is how long deleted items stay in the trash before they are purged
+var TrashRetention = 7 * 24 * time.Hour
+
+var ErrNotInTrash = errors.New("item is not in the trash")
+
+// GetTrash returns the deleted items that have not been purged yet, the most recently deleted first
+func (sb *sandbox) GetTrash() []InventoryItem {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	sb.purgeTrash()
+	trash := append([]InventoryItem{}, sb.trash...)
+	sort.SliceStable(trash, func(i, j int) bool { return trash[i].DeletedAt.After(*trash[j].DeletedAt) })
+	return trash
+}
+
+// GetItemsIncludingDeleted returns the items of the inventory and of the trash, ordered by ID
+func (sb *sandbox) GetItemsIncludingDeleted() []InventoryItem {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	sb.purgeTrash()
+	items := append(append([]InventoryItem(nil), sb.inventory...), sb.trash...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// RestoreItem moves a deleted item back to the inventory, along with its
+// images. Reservations released by the delete stay released, and a category
+// or supplier deleted in the meantime, as by a cascading delete, is unlinked.
+func (sb *sandbox) RestoreItem(id int) (*InventoryItem, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	sb.purgeTrash()
+	index, err := sb.findTrashIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	item := sb.trash[index]
+	if _, err := sb.findCategoryIndex(item.CategoryID); err != nil {
+		item.CategoryID = 0
+	}
+	if _, err := sb.findSupplierIndex(item.SupplierID); err != nil {
+		item.SupplierID = 0
+	}
+
+	item.DeletedAt = nil
+	sb.trash = append(sb.trash[:index], sb.trash[index+1:]...)
+	position := sort.Search(len(sb.inventory), func(i int) bool { return sb.inventory[i].ID > id })
+	sb.inventory = append(sb.inventory[:position], append([]InventoryItem{item}, sb.inventory[position:]...)...)
+	sb.emit(EventItemRestored, item)
+	return &item, nil
+}
+
+// trashItem moves a deleted item to the trash. The caller must hold sb.mu.
+func (sb *sandbox) trashItem(item InventoryItem) {
+	deletedAt := sb.now()
+	item.DeletedAt = &deletedAt
+	sb.trash = append(sb.trash, item)
+}
+
+// purgeTrash permanently removes the items that have been in the trash for
+// longer than TrashRetention, and their images. It goes by the tenant's clock,
+// so a request with an X-PlayPI-Now header cannot purge items early. The
+// caller must hold sb.mu.
+func (sb *tenant) purgeTrash() {
+	current := sb.clock.Now()
+	kept := sb.trash[:0]
+	for _, item := range sb.trash {
+		if current.Sub(*item.DeletedAt) < TrashRetention {
+			kept = append(kept, item)
+			continue
+		}
+		sb.deleteImagesForItem(item.ID)
+	}
+	sb.trash = kept
+}
+
+func (sb *sandbox) findTrashIndex(id int) (int, error) {
+	for i, item := range sb.trash {
+		if item.ID == id {
+			return i, nil
+		}
+	}
+	if _, err := sb.findItemIndex(id); err == nil {
+		return -1, ErrNotInTrash
+	}
+	return -1, ErrItemNotFound
+}
@@ -0,0 +1,285 @@
+package restful
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/negotiation"
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderAPIVersion selects the version of unprefixed routes and reports the version that served a response
+const HeaderAPIVersion = "API-Version"
+
+// Currency is the currency of every price in version 2 of the API
+const Currency = "USD"
+
+// MaxPriceCents is the highest price accepted by version 2 of the API
+const MaxPriceCents = 1000000
+
+// V1DeprecatedAt and V1Sunset are announced on every version 1 response with
+// the Deprecation and Sunset headers
+var (
+	V1DeprecatedAt = time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	V1Sunset       = time.Date(2027, time.July, 1, 0, 0, 0, 0, time.UTC)
+)
+
+var (
+	errInvalidAPIVersion = errors.New("API-Version must be 1 or 2")
+	errInvalidCurrency   = fmt.Errorf("currency must be %s", Currency)
+	errInvalidPriceCents = fmt.Errorf("price_cents must be a whole number between 0 and %d", MaxPriceCents)
+	errInvalidOnHand     = errors.New("stock.on_hand must be a whole number of at least 0")
+)
+
+// StockV2 is the stock of an item in version 2 of the API. Only on_hand can be written.
+type StockV2 struct {
+	OnHand    int `json:"on_hand" xml:"on_hand"`
+	Reserved  int `json:"reserved" xml:"reserved"`
+	Available int `json:"available" xml:"available"`
+}
+
+// ItemV2 is the representation of an item in version 2 of the API: the price
+// is an integer number of cents with its currency, and the quantity is
+// replaced by a stock object that also reports reservations
+type ItemV2 struct {
+	ID          int        `json:"id" xml:"id"`
+	Name        string     `json:"name" xml:"name"`
+	Description string     `json:"description" xml:"description"`
+	PriceCents  int64      `json:"price_cents" xml:"price_cents"`
+	Currency    string     `json:"currency" xml:"currency"`
+	Stock       StockV2    `json:"stock" xml:"stock"`
+	CategoryID  int        `json:"category_id,omitempty" xml:"category_id,omitempty"`
+	SupplierID  int        `json:"supplier_id,omitempty" xml:"supplier_id,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" xml:"deleted_at,omitempty" csv:"-"`
+	Category    *Category  `json:"category,omitempty" xml:"category,omitempty" csv:"-"`
+	Supplier    *Supplier  `json:"supplier,omitempty" xml:"supplier,omitempty" csv:"-"`
+}
+
+// BulkResultV2 is the outcome of a single element of a bulk operation in version 2 of the API
+type BulkResultV2 struct {
+	Index  int     `json:"index" xml:"index"`
+	ID     int     `json:"id,omitempty" xml:"id,omitempty"`
+	Status int     `json:"status" xml:"status"`
+	Item   *ItemV2 `json:"item,omitempty" xml:"item,omitempty"`
+	Error  string  `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// BulkResponseV2 summarises a bulk operation in version 2 of the API
+type BulkResponseV2 struct {
+	Atomic     bool           `json:"atomic" xml:"atomic"`
+	RolledBack bool           `json:"rolled_back" xml:"rolled_back"`
+	Succeeded  int            `json:"succeeded" xml:"succeeded"`
+	Failed     int            `json:"failed" xml:"failed"`
+	Results    []BulkResultV2 `json:"results" xml:"result"`
+}
+
+// SearchResultV2 is a search result in version 2 of the API
+type SearchResultV2 struct {
+	ItemV2
+	Score      float64          `json:"score" xml:"score"`
+	Highlights SearchHighlights `json:"highlights" xml:"highlights"`
+}
+
+// BulkAddItemsV2 adds every version 2 item of the batch like BulkAddItems. An
+// element whose version 2 fields are invalid fails like any other invalid item.
+func (sb *sandbox) BulkAddItemsV2(items []ItemV2, atomic bool) (*BulkResponse, error) {
+	return sb.bulkAddItems(len(items), atomic, func(i int) (InventoryItem, error) { return FromItemV2(items[i]) })
+}
+
+// BulkUpdateItemsV2 replaces every version 2 item of the batch like BulkUpdateItems
+func (sb *sandbox) BulkUpdateItemsV2(items []ItemV2, atomic bool) (*BulkResponse, error) {
+	return sb.bulkUpdateItems(len(items), atomic, func(i int) (InventoryItem, error) {
+		item, err := FromItemV2(items[i])
+		item.ID = items[i].ID
+		return item, err
+	})
+}
+
+// ToBulkResponseV2 converts the items of a bulk response to their version 2 representation
+func (sb *sandbox) ToBulkResponseV2(response *BulkResponse) *BulkResponseV2 {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	converted := &BulkResponseV2{
+		Atomic:     response.Atomic,
+		RolledBack: response.RolledBack,
+		Succeeded:  response.Succeeded,
+		Failed:     response.Failed,
+		Results:    make([]BulkResultV2, 0, len(response.Results)),
+	}
+	for _, result := range response.Results {
+		resultV2 := BulkResultV2{Index: result.Index, ID: result.ID, Status: result.Status, Error: result.Error}
+		if result.Item != nil {
+			item := sb.toItemV2(*result.Item)
+			resultV2.Item = &item
+		}
+		converted.Results = append(converted.Results, resultV2)
+	}
+	return converted
+}
+
+// ToSearchResultsV2 converts the items of search results to their version 2 representation
+func (sb *sandbox) ToSearchResultsV2(results []SearchResult) []SearchResultV2 {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	converted := make([]SearchResultV2, 0, len(results))
+	for _, result := range results {
+		converted = append(converted, SearchResultV2{ItemV2: sb.toItemV2(result.InventoryItem), Score: result.Score, Highlights: result.Highlights})
+	}
+	return converted
+}
+
+// ToItemsV2 converts items to their version 2 representation
+func (sb *sandbox) ToItemsV2(items []InventoryItem) []ItemV2 {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	result := make([]ItemV2, 0, len(items))
+	for _, item := range items {
+		result = append(result, sb.toItemV2(item))
+	}
+	return result
+}
+
+// ToItemV2 converts an item to its version 2 representation
+func (sb *sandbox) ToItemV2(item *InventoryItem) *ItemV2 {
+	converted := sb.ToItemsV2([]InventoryItem{*item})[0]
+	return &converted
+}
+
+// toItemV2 converts an item to its version 2 representation. The caller must hold sb.mu.
+func (sb *sandbox) toItemV2(item InventoryItem) ItemV2 {
+	reserved := sb.reservedQuantity(item.ID)
+	return ItemV2{
+		ID:          item.ID,
+		Name:        item.Name,
+		Description: item.Description,
+		PriceCents:  int64(math.Round(item.Price * 100)),
+		Currency:    Currency,
+		Stock:       StockV2{OnHand: item.Quantity, Reserved: reserved, Available: item.Quantity - reserved},
+		CategoryID:  item.CategoryID,
+		SupplierID:  item.SupplierID,
+		DeletedAt:   item.DeletedAt,
+		Category:    item.Category,
+		Supplier:    item.Supplier,
+	}
+}
+
+// FromItemV2 validates the fields specific to version 2 and converts an item
+// to its stored representation. The remaining fields are validated as in version 1.
+func FromItemV2(item ItemV2) (InventoryItem, error) {
+	if item.Currency != "" && item.Currency != Currency {
+		return InventoryItem{}, errInvalidCurrency
+	}
+	if item.PriceCents < 0 || item.PriceCents > MaxPriceCents {
+		return InventoryItem{}, errInvalidPriceCents
+	}
+	if item.Stock.OnHand < 0 {
+		return InventoryItem{}, errInvalidOnHand
+	}
+	return InventoryItem{
+		Name:        item.Name,
+		Description: item.Description,
+		Price:       float64(item.PriceCents) / 100,
+		Quantity:    item.Stock.OnHand,
+		CategoryID:  item.CategoryID,
+		SupplierID:  item.SupplierID,
+	}, nil
+}
+
+// PatchFromV2 translates the fields of a version 2 PATCH payload into the
+// fields understood by PatchItem
+func PatchFromV2(updates map[string]interface{}) (map[string]interface{}, error) {
+	translated := map[string]interface{}{}
+	for key, value := range updates {
+		switch key {
+		case "price", "quantity":
+			return nil, fmt.Errorf("%s is not part of version 2, use %s", key, map[string]string{"price": "price_cents", "quantity": "stock.on_hand"}[key])
+		case "currency":
+			if value != Currency {
+				return nil, errInvalidCurrency
+			}
+		case "price_cents":
+			cents, ok := value.(float64)
+			if !ok || cents < 0 || cents > MaxPriceCents || cents != math.Trunc(cents) {
+				return nil, errInvalidPriceCents
+			}
+			translated["price"] = cents / 100
+		case "stock":
+			stock, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("stock must be an object")
+			}
+			if onHand, exists := stock["on_hand"]; exists {
+				quantity, ok := onHand.(float64)
+				if !ok || quantity < 0 || quantity != math.Trunc(quantity) {
+					return nil, errInvalidOnHand
+				}
+				translated["quantity"] = quantity
+			}
+		default:
+			translated[key] = value
+		}
+	}
+	return translated, nil
+}
+
+// versionHeaders reports the version that serves a route. Version 1 responses
+// also announce its deprecation, its sunset and the version 2 route replacing it.
+func versionHeaders(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(HeaderAPIVersion, fmt.Sprint(version))
+		if version == 1 {
+			successor := "/v2" + strings.TrimPrefix(c.Request.URL.Path, "/v1")
+			c.Header("Deprecation", fmt.Sprintf("@%d", V1DeprecatedAt.Unix()))
+			c.Header("Sunset", V1Sunset.Format(http.TimeFormat))
+			c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		}
+		c.Next()
+	}
+}
+
+// selectVersion serves unprefixed routes from /v2 when the request has an
+// API-Version: 2 header. Prefixed routes ignore the header, and routes that
+// are not versioned, such as /webhooks, are served as they are.
+func selectVersion(engine *gin.Engine) gin.HandlerFunc {
+	var once sync.Once
+	versioned := map[string]bool{}
+
+	return func(c *gin.Context) {
+		requested := c.GetHeader(HeaderAPIVersion)
+		if requested == "" || requested == "1" {
+			c.Next()
+			return
+		}
+		if requested != "2" {
+			c.Abort()
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": errInvalidAPIVersion.Error()})
+			return
+		}
+
+		once.Do(func() {
+			for _, route := range engine.Routes() {
+				versioned[route.Method+" "+route.Path] = true
+			}
+		})
+		route := c.FullPath()
+		if strings.HasPrefix(route, "/v1/") || strings.HasPrefix(route, "/v2/") || !versioned[c.Request.Method+" /v2"+route] {
+			c.Next()
+			return
+		}
+
+		c.Request.URL.Path = "/v2" + c.Request.URL.Path
+		if c.Request.URL.RawPath != "" {
+			c.Request.URL.RawPath = "/v2" + c.Request.URL.RawPath
+		}
+		engine.HandleContext(c)
+		c.Abort()
+	}
+}
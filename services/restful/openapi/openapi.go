@@ -0,0 +1,138 @@
+// Package openapi serves the OpenAPI documents of the RESTful playgrounds
+// together with an embedded Swagger UI that works offline, and can validate
+// incoming requests against the document before they reach the handlers.
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AssetsRoute serves the static assets of the Swagger UI. Its files are not
+// JSON, so playgrounds leave it out of content negotiation.
+const AssetsRoute = "/docs/*filepath"
+
+// swaggerUI holds the vendored swagger-ui dist bundle, see swagger-ui/NOTICE
+//
+//go:embed swagger-ui
+var swaggerUI embed.FS
+
+// Register serves the OpenAPI document at /openapi.json and the Swagger UI
+// at /docs, pointed at the document
+func Register(r *gin.Engine, spec []byte) {
+	assets, err := fs.Sub(swaggerUI, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	index, err := fs.ReadFile(assets, "index.html")
+	if err != nil {
+		panic(err)
+	}
+
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+	r.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+	})
+	r.GET(AssetsRoute, func(c *gin.Context) {
+		c.FileFromFS(c.Param("filepath"), http.FS(assets))
+	})
+}
+
+// Document is the subset of an OpenAPI 3.1 document used for request validation
+type Document struct {
+	Paths      map[string]PathItem `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*Parameter `json:"parameters"`
+	} `json:"components"`
+}
+
+// PathItem holds the operations of a single path
+type PathItem map[string]json.RawMessage
+
+// Operation is a single API operation
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []*Parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema *Schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+// Parameter describes a path, query or header parameter
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Parse decodes an OpenAPI document
+func Parse(spec []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Operation looks up the operation for an HTTP method and a gin route such as /items/:id
+func (d *Document) Operation(method, route string) (*Operation, bool) {
+	item, ok := d.Paths[PathFromRoute(route)]
+	if !ok {
+		return nil, false
+	}
+	raw, ok := item[strings.ToLower(method)]
+	if !ok {
+		return nil, false
+	}
+	var op Operation
+	if err := json.Unmarshal(raw, &op); err != nil {
+		return nil, false
+	}
+	for i, p := range op.Parameters {
+		if p.Ref != "" {
+			if resolved, ok := d.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]; ok {
+				op.Parameters[i] = resolved
+			}
+		}
+	}
+	return &op, true
+}
+
+// Resolve looks up an operation like Operation. A route under one of the
+// alias prefixes that is not documented on its own, such as /v1/items, uses
+// the operation of the route without the prefix.
+func (d *Document) Resolve(method, route string, aliasPrefixes ...string) (*Operation, bool) {
+	if op, ok := d.Operation(method, route); ok {
+		return op, true
+	}
+	for _, prefix := range aliasPrefixes {
+		if strings.HasPrefix(route, prefix+"/") {
+			return d.Operation(method, strings.TrimPrefix(route, prefix))
+		}
+	}
+	return nil, false
+}
+
+// PathFromRoute converts a gin route (/items/:id) into an OpenAPI path template (/items/{id})
+func PathFromRoute(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
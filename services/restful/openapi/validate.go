@@ -0,0 +1,369 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abhivaikar/playpi/services/negotiation"
+	"github.com/gin-gonic/gin"
+)
+
+// Schema is the subset of JSON Schema used by the playground documents
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 interface{}        `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []interface{}      `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	OneOf                []*Schema          `json:"oneOf"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	MinProperties        *int               `json:"minProperties"`
+}
+
+// ValidationMiddleware rejects requests whose parameters or JSON body do not
+// match the operation described in the OpenAPI document with a 400 listing
+// every violation. Routes missing from the document are passed through, and
+// routes under aliasPrefixes are resolved as described by Document.Resolve.
+func ValidationMiddleware(spec []byte, aliasPrefixes ...string) gin.HandlerFunc {
+	doc, err := Parse(spec)
+	if err != nil {
+		panic(fmt.Sprintf("invalid OpenAPI document: %v", err))
+	}
+
+	return func(c *gin.Context) {
+		op, ok := doc.Resolve(c.Request.Method, c.FullPath(), aliasPrefixes...)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		violations := doc.validateParameters(c, op)
+		bodyViolations, err := doc.validateBody(c, op)
+		if err != nil {
+			c.Abort()
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+		violations = append(violations, bodyViolations...)
+
+		if len(violations) > 0 {
+			c.Abort()
+			negotiation.Render(c, http.StatusBadRequest, gin.H{
+				"error":      "request does not match the OpenAPI document",
+				"violations": violations,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (d *Document) validateParameters(c *gin.Context, op *Operation) []string {
+	var violations []string
+	for _, p := range op.Parameters {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw = c.Param(p.Name)
+			present = raw != ""
+		case "query":
+			raw, present = c.GetQuery(p.Name)
+		case "header":
+			raw = c.GetHeader(p.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+
+		location := fmt.Sprintf("%s parameter '%s'", p.In, p.Name)
+		if !present {
+			if p.Required {
+				violations = append(violations, location+" is required")
+			}
+			continue
+		}
+		if p.Schema == nil {
+			continue
+		}
+		value, err := coerce(raw, d.resolve(p.Schema))
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("%s %s", location, err.Error()))
+			continue
+		}
+		violations = append(violations, d.validate(value, p.Schema, location)...)
+	}
+	return violations
+}
+
+func (d *Document) validateBody(c *gin.Context, op *Operation) ([]string, error) {
+	if op.RequestBody == nil {
+		return nil, nil
+	}
+	media, ok := op.RequestBody.Content[negotiation.MIMEJSON]
+	if !ok || media.Schema == nil {
+		return nil, nil
+	}
+	// Only JSON bodies are validated, other formats are left to the handlers
+	if contentType := c.GetHeader("Content-Type"); contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || (mediaType != negotiation.MIMEJSON && mediaType != "text/json") {
+			return nil, nil
+		}
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return []string{"request body is required"}, nil
+		}
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return d.validate(value, media.Schema, "body"), nil
+}
+
+// resolve follows a local $ref to a component schema
+func (d *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+// validate checks value against schema and returns a message per violation
+func (d *Document) validate(value interface{}, schema *Schema, path string) []string {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if len(schema.OneOf) > 0 {
+		matched := 0
+		for _, candidate := range schema.OneOf {
+			if len(d.validate(value, candidate, path)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return []string{fmt.Sprintf("%s must match exactly one of the allowed schemas", path)}
+		}
+		return nil
+	}
+
+	if !typeMatches(value, schema.Type) {
+		return []string{fmt.Sprintf("%s must be of type %s", path, typeName(schema.Type))}
+	}
+
+	var violations []string
+	if len(schema.Enum) > 0 && !inEnum(value, schema.Enum) {
+		violations = append(violations, fmt.Sprintf("%s must be one of %s", path, enumList(schema.Enum)))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			violations = append(violations, fmt.Sprintf("%s must be at least %d characters", path, *schema.MinLength))
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			violations = append(violations, fmt.Sprintf("%s must be at most %d characters", path, *schema.MaxLength))
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(v) {
+				violations = append(violations, fmt.Sprintf("%s must match the pattern %s", path, schema.Pattern))
+			}
+		}
+		if !formatMatches(v, schema.Format) {
+			violations = append(violations, fmt.Sprintf("%s must be a valid %s", path, schema.Format))
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		if schema.Minimum != nil && f < *schema.Minimum {
+			violations = append(violations, fmt.Sprintf("%s must be at least %v", path, *schema.Minimum))
+		}
+		if schema.ExclusiveMinimum != nil && f <= *schema.ExclusiveMinimum {
+			violations = append(violations, fmt.Sprintf("%s must be greater than %v", path, *schema.ExclusiveMinimum))
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			violations = append(violations, fmt.Sprintf("%s must be at most %v", path, *schema.Maximum))
+		}
+	case []interface{}:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			violations = append(violations, fmt.Sprintf("%s must contain at least %d elements", path, *schema.MinItems))
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			violations = append(violations, fmt.Sprintf("%s must contain at most %d elements", path, *schema.MaxItems))
+		}
+		if schema.Items != nil {
+			for i, elem := range v {
+				violations = append(violations, d.validate(elem, schema.Items, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s.%s is required", path, name))
+			}
+		}
+		if schema.MinProperties != nil && len(v) < *schema.MinProperties {
+			violations = append(violations, fmt.Sprintf("%s must contain at least %d properties", path, *schema.MinProperties))
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, known := schema.Properties[name]
+			if !known {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					violations = append(violations, fmt.Sprintf("%s.%s is not allowed", path, name))
+				}
+				continue
+			}
+			violations = append(violations, d.validate(v[name], property, path+"."+name)...)
+		}
+	}
+	return violations
+}
+
+// coerce converts a raw parameter value into the JSON type expected by the schema
+func coerce(raw string, schema *Schema) (interface{}, error) {
+	if schema == nil {
+		return raw, nil
+	}
+	switch typeName(schema.Type) {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, fmt.Errorf("must be of type %s", typeName(schema.Type))
+		}
+		return json.Number(raw), nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be of type boolean")
+		}
+		return b, nil
+	}
+	return raw, nil
+}
+
+func typeMatches(value interface{}, schemaType interface{}) bool {
+	switch t := schemaType.(type) {
+	case nil:
+		return true
+	case string:
+		return matchesType(value, t)
+	case []interface{}:
+		for _, candidate := range t {
+			if name, ok := candidate.(string); ok && matchesType(value, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesType(value interface{}, name string) bool {
+	switch name {
+	case "null":
+		return value == nil
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := strconv.ParseInt(n.String(), 10, 64)
+		return err == nil
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+func typeName(schemaType interface{}) string {
+	switch t := schemaType.(type) {
+	case string:
+		return t
+	case []interface{}:
+		names := make([]string, 0, len(t))
+		for _, candidate := range t {
+			names = append(names, fmt.Sprint(candidate))
+		}
+		return strings.Join(names, " or ")
+	}
+	return "any"
+}
+
+func formatMatches(value, format string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	}
+	return true
+}
+
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, v := range enum {
+		values[i] = fmt.Sprint(v)
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,56 @@
+// Package pagination selects a page of the listings of the RESTful playgrounds
+// with the limit and offset query parameters and reports the number of
+// results before pagination in the X-Total-Count header.
+package pagination
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderTotalCount reports how many results a paginated listing has before limit and offset are applied
+const HeaderTotalCount = "X-Total-Count"
+
+var (
+	errInvalidLimit  = errors.New("limit must be a positive number")
+	errInvalidOffset = errors.New("offset must be a positive number")
+)
+
+// Page is the part of a listing selected with the limit and offset query
+// parameters. A zero limit selects every result after the offset.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// Parse validates the limit and offset query parameters of listings
+func Parse(limit string, offset string) (Page, error) {
+	var page Page
+	var err error
+	if limit != "" {
+		if page.Limit, err = strconv.Atoi(limit); err != nil || page.Limit < 0 {
+			return Page{}, errInvalidLimit
+		}
+	}
+	if offset != "" {
+		if page.Offset, err = strconv.Atoi(offset); err != nil || page.Offset < 0 {
+			return Page{}, errInvalidOffset
+		}
+	}
+	return page, nil
+}
+
+// Apply returns the page of results and reports their total in the X-Total-Count header
+func Apply[T any](c *gin.Context, results []T, page Page) []T {
+	c.Header(HeaderTotalCount, strconv.Itoa(len(results)))
+	if page.Offset >= len(results) {
+		return []T{}
+	}
+	results = results[page.Offset:]
+	if page.Limit > 0 && len(results) > page.Limit {
+		results = results[:page.Limit]
+	}
+	return results
+}
@@ -0,0 +1,208 @@
+// Package sse streams the changes of the RESTful playgrounds as Server-Sent
+// Events. Every event gets an increasing ID and the most recent events are
+// kept, so that a client reconnecting with a Last-Event-ID header receives
+// the events it missed before the live ones.
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/negotiation"
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of event streams
+const ContentType = "text/event-stream"
+
+// HeaderLastEventID is sent by clients that resume a stream
+const HeaderLastEventID = "Last-Event-ID"
+
+// MaxHistory is how many past events are kept for clients that resume a stream
+const MaxHistory = 1000
+
+// subscriberBuffer is how many events a slow client can lag behind before it is disconnected
+const subscriberBuffer = 64
+
+// HeartbeatInterval is how often a comment is sent to keep idle streams open
+var HeartbeatInterval = 15 * time.Second
+
+// RetryInterval is the reconnection delay suggested to clients
+var RetryInterval = 3 * time.Second
+
+var errInvalidLastEventID = errors.New("Last-Event-ID must be a non-negative integer")
+
+// Event is a change published on a feed
+type Event struct {
+	ID   int
+	Type string
+	Data []byte
+	// data is the published value, which filters decide on
+	data interface{}
+}
+
+// Feed keeps the recent events of a resource and fans them out to the connected clients
+type Feed struct {
+	mu          sync.Mutex
+	history     []Event
+	nextID      int
+	subscribers map[chan Event]struct{}
+}
+
+// NewFeed creates an empty feed
+func NewFeed() *Feed {
+	f := &Feed{}
+	f.Reset()
+	return f
+}
+
+// Reset forgets every event and disconnects the clients
+func (f *Feed) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for subscriber := range f.subscribers {
+		close(subscriber)
+	}
+	f.subscribers = make(map[chan Event]struct{})
+	f.history = []Event{}
+	f.nextID = 1
+}
+
+// Publish sends an event to the connected clients. data is encoded as JSON when the event is published.
+func (f *Feed) Publish(eventType string, data interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	event := Event{ID: f.nextID, Type: eventType, Data: encoded, data: data}
+	f.nextID++
+	f.history = append(f.history, event)
+	if len(f.history) > MaxHistory {
+		f.history = f.history[len(f.history)-MaxHistory:]
+	}
+	for subscriber := range f.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// The client cannot keep up: disconnect it so that it resumes with Last-Event-ID
+			close(subscriber)
+			delete(f.subscribers, subscriber)
+		}
+	}
+}
+
+// subscribe registers a client and, when it resumes, returns the kept events published after lastID
+func (f *Feed) subscribe(lastID int, resume bool) (chan Event, []Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	missed := []Event{}
+	for _, event := range f.history {
+		if resume && event.ID > lastID {
+			missed = append(missed, event)
+		}
+	}
+	subscriber := make(chan Event, subscriberBuffer)
+	f.subscribers[subscriber] = struct{}{}
+	return subscriber, missed
+}
+
+func (f *Feed) unsubscribe(subscriber chan Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subscribers[subscriber]; ok {
+		close(subscriber)
+		delete(f.subscribers, subscriber)
+	}
+}
+
+// Handler streams the feed. Clients resume after the event given by the
+// Last-Event-ID header, or the last_event_id query parameter for clients that
+// cannot set headers; without either only new events are sent.
+func (f *Feed) Handler() gin.HandlerFunc {
+	return f.FilteredHandler(nil)
+}
+
+// FilteredHandler streams the events of the feed whose published value is
+// visible to the client, like Handler. Events that are not visible are
+// skipped, both live and when resuming. A nil visible streams every event.
+func (f *Feed) FilteredHandler(visible func(data interface{}) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := negotiation.Negotiate(c.GetHeader("Accept"), ContentType); err != nil {
+			negotiation.Render(c, http.StatusNotAcceptable, gin.H{"error": "none of the requested media types are supported", "supported": []string{ContentType}})
+			return
+		}
+		lastID, resume, err := parseLastEventID(c)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		subscriber, missed := f.subscribe(lastID, resume)
+		defer f.unsubscribe(subscriber)
+
+		header := c.Writer.Header()
+		header.Set("Content-Type", ContentType)
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		fmt.Fprintf(c.Writer, "retry: %d\n\n", RetryInterval.Milliseconds())
+		for _, event := range missed {
+			if visible == nil || visible(event.data) {
+				writeEvent(c, event)
+			}
+		}
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(HeartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case event, ok := <-subscriber:
+				if !ok {
+					return
+				}
+				if visible != nil && !visible(event.data) {
+					continue
+				}
+				writeEvent(c, event)
+			case <-heartbeat.C:
+				fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeEvent(c *gin.Context, event Event) {
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+// parseLastEventID reads the ID of the last event a client received, and whether it sent one at all
+func parseLastEventID(c *gin.Context) (int, bool, error) {
+	value := c.GetHeader(HeaderLastEventID)
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 0 {
+		return 0, false, errInvalidLastEventID
+	}
+	return id, true, nil
+}
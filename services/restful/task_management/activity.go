@@ -0,0 +1,130 @@
+package task_management
+
+import (
+	"regexp"
+	"time"
+)
+
+// HeaderUser names the user making a request. It is optional, except to
+// comment, and must name the authenticated user of requests with a token.
+const HeaderUser = "X-User"
+
+// Actions of the activity history
+const (
+	ActionCreated        = "created"
+	ActionUpdated        = "updated"
+	ActionDeleted        = "deleted"
+	ActionRestored       = "restored"
+	ActionCommented      = "commented"
+	ActionCommentEdited  = "comment_edited"
+	ActionCommentDeleted = "comment_deleted"
+)
+
+var validUser = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Activity is an entry of the history of a task. Entries are never changed or removed.
+type Activity struct {
+	ID     int    `json:"id" xml:"id"`
+	TaskID int    `json:"task_id" xml:"task_id"`
+	Action string `json:"action" xml:"action"`
+	// Actor is the authenticated user of the request, or the user named by its X-User header, if any
+	Actor     string        `json:"actor,omitempty" xml:"actor,omitempty"`
+	At        time.Time     `json:"at" xml:"at"`
+	Changes   []FieldChange `json:"changes" xml:"changes>change"`
+	CommentID *int          `json:"comment_id,omitempty" xml:"comment_id,omitempty"`
+}
+
+// FieldChange records the value of a field before and after a change. Empty
+// values stand for fields that were not set.
+type FieldChange struct {
+	Field  string `json:"field" xml:"field"`
+	Before string `json:"before" xml:"before"`
+	After  string `json:"after" xml:"after"`
+}
+
+// GetHistory returns the activity of an active or deleted task, oldest first
+func (sb *sandbox) GetHistory(id int) ([]Activity, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	sb.purgeTrash()
+	task := sb.findAnyTask(id)
+	if task == nil {
+		return nil, errTaskNotFound
+	}
+	if err := sb.authorize(*task, accessRead); err != nil {
+		return nil, err
+	}
+	history := []Activity{}
+	for _, activity := range sb.history {
+		if activity.TaskID == id {
+			history = append(history, activity)
+		}
+	}
+	return history, nil
+}
+
+// record adds an entry to the history of a task. The caller must hold sb.mu.
+func (sb *sandbox) record(taskID int, action string, changes []FieldChange) *Activity {
+	sb.activityIDCounter++
+	if changes == nil {
+		changes = []FieldChange{}
+	}
+	sb.history = append(sb.history, Activity{
+		ID:      sb.activityIDCounter,
+		TaskID:  taskID,
+		Action:  action,
+		Actor:   sb.actor,
+		At:      sb.now(),
+		Changes: changes,
+	})
+	return &sb.history[len(sb.history)-1]
+}
+
+// diffTasks lists the fields that differ between two versions of a task
+func diffTasks(before Task, after Task) []FieldChange {
+	changes := []FieldChange{}
+	for _, field := range []struct {
+		name          string
+		before, after string
+	}{
+		{"title", before.Title, after.Title},
+		{"description", before.Description, after.Description},
+		{"due_date", before.DueDate, after.DueDate},
+		{"priority", before.Priority, after.Priority},
+		{"status", before.Status, after.Status},
+		{"rrule", before.RRule, after.RRule},
+	} {
+		if field.before != field.after {
+			changes = append(changes, FieldChange{Field: field.name, Before: field.before, After: field.after})
+		}
+	}
+	return changes
+}
+
+// forgetHistory removes the comments, the activity and the time entries of purged tasks. The caller must hold sb.mu.
+func (sb *tenant) forgetHistory(purged map[int]bool) {
+	history := sb.history[:0]
+	for _, activity := range sb.history {
+		if !purged[activity.TaskID] {
+			history = append(history, activity)
+		}
+	}
+	sb.history = history
+
+	comments := sb.comments[:0]
+	for _, comment := range sb.comments {
+		if !purged[comment.TaskID] {
+			comments = append(comments, comment)
+		}
+	}
+	sb.comments = comments
+
+	entries := sb.timeEntries[:0]
+	for _, entry := range sb.timeEntries {
+		if !purged[entry.TaskID] {
+			entries = append(entries, entry)
+		}
+	}
+	sb.timeEntries = entries
+}
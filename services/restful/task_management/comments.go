@@ -0,0 +1,201 @@
+package task_management
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+// MaxCommentLength is the longest a comment can be, in characters
+const MaxCommentLength = 1000
+
+// mentionPattern matches @user mentions that are not part of an email address
+var mentionPattern = regexp.MustCompile(`(^|[^A-Za-z0-9_.@-])@([A-Za-z0-9_-]+(?:\.[A-Za-z0-9_-]+)*)`)
+
+var (
+	errCommentNotFound = errors.New("comment not found")
+	errCommentAuthor   = errors.New("only the author of a comment can edit or delete it")
+	errCommentUser     = errors.New("comments need an X-User header naming their author")
+	errInvalidComment  = errors.New("comment must be between 1 and 1000 characters")
+)
+
+// Comment is a comment on a task
+type Comment struct {
+	ID     int    `json:"id" xml:"id"`
+	TaskID int    `json:"task_id" xml:"task_id"`
+	Author string `json:"author" xml:"author"`
+	Body   string `json:"body" xml:"body"`
+	// Mentions lists the users mentioned with @user in the body, in the order they first appear
+	Mentions  []string   `json:"mentions" xml:"mentions>user"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty" xml:"edited_at,omitempty"`
+}
+
+// CommentRequest is the payload of POST /tasks/:id/comments and PUT /tasks/:id/comments/:comment_id
+type CommentRequest struct {
+	Body string `json:"body" xml:"body"`
+}
+
+// CommentQuery selects the comments of GET /comments. Empty fields select every comment.
+type CommentQuery struct {
+	Mention string
+	Author  string
+}
+
+func validateComment(body string) error {
+	if length := len([]rune(body)); length < 1 || length > MaxCommentLength {
+		return errInvalidComment
+	}
+	return nil
+}
+
+// parseMentions returns the users mentioned in a comment, without duplicates
+func parseMentions(body string) []string {
+	mentions := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		if indexOf(mentions, match[2]) < 0 {
+			mentions = append(mentions, match[2])
+		}
+	}
+	return mentions
+}
+
+// AddComment comments on an active task as the user of the request
+func (sb *sandbox) AddComment(taskID int, request CommentRequest) (Comment, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(taskID)
+	if err != nil {
+		return Comment{}, err
+	}
+	if err := sb.authorize(sb.tasks[index], accessRead); err != nil {
+		return Comment{}, err
+	}
+	if sb.actor == "" {
+		return Comment{}, errCommentUser
+	}
+	if err := validateComment(request.Body); err != nil {
+		return Comment{}, err
+	}
+	sb.commentIDCounter++
+	comment := Comment{
+		ID:        sb.commentIDCounter,
+		TaskID:    taskID,
+		Author:    sb.actor,
+		Body:      request.Body,
+		Mentions:  parseMentions(request.Body),
+		CreatedAt: sb.now(),
+	}
+	sb.comments = append(sb.comments, comment)
+	sb.record(taskID, ActionCommented, []FieldChange{{Field: "comment", After: comment.Body}}).CommentID = &comment.ID
+	return comment, nil
+}
+
+// GetComments returns the comments on a task, oldest first
+func (sb *sandbox) GetComments(taskID int) ([]Comment, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	task := sb.findAnyTask(taskID)
+	if task == nil {
+		return nil, errTaskNotFound
+	}
+	if err := sb.authorize(*task, accessRead); err != nil {
+		return nil, err
+	}
+	comments := []Comment{}
+	for _, comment := range sb.comments {
+		if comment.TaskID == taskID {
+			comments = append(comments, comment)
+		}
+	}
+	return comments, nil
+}
+
+// FindComments returns the comments on every task the caller can read selected by a query, oldest first
+func (sb *sandbox) FindComments(query CommentQuery) []Comment {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	sb.purgeTrash()
+	comments := []Comment{}
+	for _, comment := range sb.comments {
+		if query.Mention != "" && indexOf(comment.Mentions, query.Mention) < 0 {
+			continue
+		}
+		if query.Author != "" && comment.Author != query.Author {
+			continue
+		}
+		if task := sb.findAnyTask(comment.TaskID); task == nil || !sb.canRead(*task) {
+			continue
+		}
+		comments = append(comments, comment)
+	}
+	return comments
+}
+
+// EditComment replaces the body of a comment of the user of the request
+func (sb *sandbox) EditComment(taskID int, commentID int, request CommentRequest) (Comment, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	comment, err := sb.findOwnComment(taskID, commentID)
+	if err != nil {
+		return Comment{}, err
+	}
+	if err := validateComment(request.Body); err != nil {
+		return Comment{}, err
+	}
+	before := comment.Body
+	editedAt := sb.now()
+	comment.Body = request.Body
+	comment.Mentions = parseMentions(request.Body)
+	comment.EditedAt = &editedAt
+	sb.record(taskID, ActionCommentEdited, []FieldChange{{Field: "comment", Before: before, After: comment.Body}}).CommentID = &comment.ID
+	return *comment, nil
+}
+
+// DeleteComment removes a comment of the user of the request
+func (sb *sandbox) DeleteComment(taskID int, commentID int) error {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	comment, err := sb.findOwnComment(taskID, commentID)
+	if err != nil {
+		return err
+	}
+	deleted := *comment
+	for i := range sb.comments {
+		if sb.comments[i].ID == commentID {
+			sb.comments = append(sb.comments[:i], sb.comments[i+1:]...)
+			break
+		}
+	}
+	sb.record(taskID, ActionCommentDeleted, []FieldChange{{Field: "comment", Before: deleted.Body}}).CommentID = &deleted.ID
+	return nil
+}
+
+// findOwnComment returns a comment on an active task written by the user of
+// the request. The caller must hold sb.mu.
+func (sb *sandbox) findOwnComment(taskID int, commentID int) (*Comment, error) {
+	index, err := sb.findTaskIndex(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := sb.authorize(sb.tasks[index], accessRead); err != nil {
+		return nil, err
+	}
+	for i := range sb.comments {
+		if sb.comments[i].ID == commentID && sb.comments[i].TaskID == taskID {
+			if sb.actor == "" {
+				return nil, errCommentUser
+			}
+			if sb.comments[i].Author != sb.actor {
+				return nil, errCommentAuthor
+			}
+			return &sb.comments[i], nil
+		}
+	}
+	return nil, errCommentNotFound
+}
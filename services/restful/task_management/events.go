@@ -0,0 +1,42 @@
+package task_management
+
+// Webhook event types
+const (
+	EventTaskCreated   = "task.created"
+	EventTaskUpdated   = "task.updated"
+	EventTaskCompleted = "task.completed"
+	EventTaskDeleted   = "task.deleted"
+	EventTaskRestored  = "task.restored"
+)
+
+// webhookEvents are the events webhooks can subscribe to
+var webhookEvents = []string{EventTaskCreated, EventTaskUpdated, EventTaskCompleted, EventTaskDeleted, EventTaskRestored}
+
+// event is a task event waiting for the lock of its tenant to be released
+type event struct {
+	name string
+	task Task
+}
+
+// emit records an event to publish when sb.mu is released, so that events are
+// only published once the operation that raised them has completed. The
+// caller must hold sb.mu.
+func (sb *tenant) emit(name string, task Task) {
+	sb.pendingEvents = append(sb.pendingEvents, event{name: name, task: task})
+}
+
+// unlock publishes the events raised while sb.mu was held and releases it.
+// Webhook subscribers are notified, and creates, updates, deletes and
+// restores are also streamed on the change feed, completions being updates.
+// Publishing never blocks, and doing it before unlocking keeps the events in
+// the order of the changes.
+func (sb *tenant) unlock() {
+	for _, e := range sb.pendingEvents {
+		sb.dispatcher.Publish(e.name, e.task)
+		if e.name != EventTaskCompleted {
+			sb.feed.Publish(e.name, e.task)
+		}
+	}
+	sb.pendingEvents = nil
+	sb.mu.Unlock()
+}
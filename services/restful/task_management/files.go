@@ -0,0 +1,393 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Media types of the task files
+const (
+	MIMECalendar = "text/calendar"
+	MIMECSV      = "text/csv"
+)
+
+// Import limits
+const (
+	// MaxImportSize is the largest file POST /tasks/import accepts, in bytes
+	MaxImportSize = 1 << 20
+	// MaxImportRows is the most tasks a single import can create
+	MaxImportRows = 1000
+)
+
+// multipartOverhead is the room left for the multipart boundaries and headers around an imported file
+const multipartOverhead = 64 << 10
+
+// csvHeader lists the columns of GET /tasks.csv. Imports read title,
+// description, due_date, priority and rrule, and ignore the other columns.
+var csvHeader = []string{"id", "title", "description", "due_date", "priority", "status", "rrule", "created_at"}
+
+// icsStatuses maps the task statuses onto the VTODO statuses of RFC 5545. The
+// exact status is kept in the X-PLAYPI-STATUS property.
+var icsStatuses = map[string]string{
+	StatusPending:    "NEEDS-ACTION",
+	StatusInProgress: "IN-PROCESS",
+	StatusBlocked:    "IN-PROCESS",
+	StatusCompleted:  "COMPLETED",
+	StatusArchived:   "COMPLETED",
+}
+
+var (
+	errInvalidICS        = errors.New("ICS file must hold a VCALENDAR")
+	errCSVHeader         = errors.New("CSV header must have the columns title, due_date and priority")
+	errTooManyRows       = fmt.Errorf("imports are limited to %d rows", MaxImportRows)
+	errUnclosedTodo      = errors.New("VTODO is not closed with END:VTODO")
+	errUnsupportedImport = errors.New("import must be a text/csv or text/calendar body, or multipart/form-data with the file in the file field")
+	errImportTooLarge    = errors.New("import files are limited to 1 MiB")
+)
+
+// ImportResult reports what POST /tasks/import did with every row of a file
+type ImportResult struct {
+	Format  string      `json:"format" xml:"format"`
+	Created int         `json:"created" xml:"created"`
+	Failed  int         `json:"failed" xml:"failed"`
+	Rows    []ImportRow `json:"rows" xml:"rows>row"`
+}
+
+// ImportRow is the outcome of a row of an imported file: the task it created, or why it was rejected
+type ImportRow struct {
+	// Row counts the records of a CSV file after its header, or the VTODOs of an ICS file, from 1
+	Row    int    `json:"row" xml:"row"`
+	Title  string `json:"title" xml:"title"`
+	TaskID *int   `json:"task_id,omitempty" xml:"task_id,omitempty"`
+	Error  string `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// importRecord is a task read from a file, or the error that prevented reading it
+type importRecord struct {
+	task Task
+	err  error
+}
+
+// ImportTasks creates a task from every row of a CSV or ICS file. Rows are
+// validated like POST /tasks, and the rows that fail do not prevent the others
+// from being created.
+func (sb *sandbox) ImportTasks(format string, data []byte) (ImportResult, error) {
+	var records []importRecord
+	var err error
+	result := ImportResult{Rows: []ImportRow{}}
+	switch format {
+	case MIMECSV:
+		result.Format = "csv"
+		records, err = parseTasksCSV(data)
+	case MIMECalendar:
+		result.Format = "ics"
+		records, err = parseTasksICS(data)
+	default:
+		return ImportResult{}, errUnsupportedImport
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if len(records) > MaxImportRows {
+		return ImportResult{}, errTooManyRows
+	}
+
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	for i, record := range records {
+		row := ImportRow{Row: i + 1, Title: record.task.Title}
+		err := record.err
+		if err == nil {
+			var task Task
+			if task, err = sb.createTask(record.task); err == nil {
+				row.TaskID = &task.ID
+			}
+		}
+		if err != nil {
+			row.Error = err.Error()
+			result.Failed++
+		} else {
+			result.Created++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// detectImportFormat returns the format of an uploaded file from its name, or
+// from its content when the name has no known extension
+func detectImportFormat(filename string, data []byte) string {
+	switch {
+	case strings.HasSuffix(strings.ToLower(filename), ".csv"):
+		return MIMECSV
+	case strings.HasSuffix(strings.ToLower(filename), ".ics"):
+		return MIMECalendar
+	case bytes.HasPrefix(bytes.TrimSpace(bytes.ToUpper(data)), []byte("BEGIN:VCALENDAR")):
+		return MIMECalendar
+	}
+	return MIMECSV
+}
+
+// formatTasksCSV encodes tasks with the columns of csvHeader
+func formatTasksCSV(tasks []Task) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		record := []string{strconv.Itoa(task.ID), task.Title, task.Description, task.DueDate, task.Priority, task.Status, task.RRule, task.CreatedAt.Format(time.RFC3339)}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// parseTasksCSV reads a task from every record of a CSV file, whose header
+// names its columns in any order. Records with the wrong number of fields are
+// rejected on their own.
+func parseTasksCSV(data []byte) ([]importRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errCSVHeader
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"title", "due_date", "priority"} {
+		if _, ok := columns[required]; !ok {
+			return nil, errCSVHeader
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	records := []importRecord{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		task := Task{
+			Title:       field(record, "title"),
+			Description: field(record, "description"),
+			DueDate:     field(record, "due_date"),
+			Priority:    field(record, "priority"),
+			RRule:       field(record, "rrule"),
+		}
+		if err != nil {
+			err = fmt.Errorf("row has %d fields but the header has %d", len(record), len(header))
+		}
+		records = append(records, importRecord{task: task, err: err})
+		if len(records) > MaxImportRows {
+			break
+		}
+	}
+	return records, nil
+}
+
+// formatTasksICS encodes tasks as the VTODOs of a VCALENDAR, as described by RFC 5545
+func formatTasksICS(tasks []Task, now time.Time) []byte {
+	var buf bytes.Buffer
+	write := func(name, value string) {
+		buf.WriteString(foldICSLine(name + ":" + value))
+		buf.WriteString("\r\n")
+	}
+	write("BEGIN", "VCALENDAR")
+	write("VERSION", "2.0")
+	write("PRODID", "-//PlayPI//Task Management//EN")
+	for _, task := range tasks {
+		write("BEGIN", "VTODO")
+		write("UID", fmt.Sprintf("task-%d@playpi", task.ID))
+		write("DTSTAMP", now.UTC().Format("20060102T150405Z"))
+		write("CREATED", task.CreatedAt.UTC().Format("20060102T150405Z"))
+		write("SUMMARY", escapeICSText(task.Title))
+		if task.Description != "" {
+			write("DESCRIPTION", escapeICSText(task.Description))
+		}
+		write("DUE;VALUE=DATE", strings.ReplaceAll(task.DueDate, "-", ""))
+		write("PRIORITY", strconv.Itoa(icsPriority(task.Priority)))
+		write("STATUS", icsStatuses[task.Status])
+		write("X-PLAYPI-STATUS", task.Status)
+		if task.RRule != "" {
+			write("RRULE", task.RRule)
+		}
+		write("END", "VTODO")
+	}
+	write("END", "VCALENDAR")
+	return buf.Bytes()
+}
+
+// parseTasksICS reads a task from every VTODO of a VCALENDAR. Other
+// components, such as VEVENTs and the VALARMs of VTODOs, are ignored.
+func parseTasksICS(data []byte) ([]importRecord, error) {
+	lines := unfoldICSLines(string(data))
+	records := []importRecord{}
+	var task *Task
+	inCalendar, skipping := false, ""
+	for _, line := range lines {
+		name, value := parseICSLine(line)
+		switch {
+		case skipping != "":
+			if name == "END" && strings.EqualFold(value, skipping) {
+				skipping = ""
+			}
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			inCalendar = true
+		case !inCalendar:
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO") && task == nil:
+			// Tasks without a PRIORITY get the medium priority, as PRIORITY:0 leaves it undefined
+			task = &Task{Priority: "medium"}
+		case name == "BEGIN":
+			skipping = value
+		case name == "END" && strings.EqualFold(value, "VTODO") && task != nil:
+			records = append(records, importRecord{task: *task})
+			task = nil
+			if len(records) > MaxImportRows {
+				return records, nil
+			}
+		case task == nil:
+		case name == "SUMMARY":
+			task.Title = unescapeICSText(value)
+		case name == "DESCRIPTION":
+			task.Description = unescapeICSText(value)
+		case name == "DUE":
+			task.DueDate = icsDate(value)
+		case name == "PRIORITY":
+			task.Priority = priorityOfICS(value)
+		case name == "RRULE":
+			task.RRule = value
+		}
+	}
+	if !inCalendar {
+		return nil, errInvalidICS
+	}
+	if task != nil {
+		records = append(records, importRecord{task: *task, err: errUnclosedTodo})
+	}
+	return records, nil
+}
+
+// unfoldICSLines splits an ICS file into its content lines, joining the lines
+// that were folded by starting their continuation with a space or a tab
+func unfoldICSLines(data string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseICSLine splits a content line into its upper-cased name, without its parameters, and its value
+func parseICSLine(line string) (name string, value string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), ""
+	}
+	name, value = line[:colon], line[colon+1:]
+	if semicolon := strings.Index(name, ";"); semicolon >= 0 {
+		name = name[:semicolon]
+	}
+	return strings.ToUpper(strings.TrimSpace(name)), value
+}
+
+// foldICSLine breaks a content line into lines of at most 75 octets, without splitting characters
+func foldICSLine(line string) string {
+	var folded strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		folded.WriteString(line[:cut])
+		folded.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards their 75 octets
+		limit = 74
+	}
+	folded.WriteString(line)
+	return folded.String()
+}
+
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+var icsTextUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func escapeICSText(text string) string {
+	return icsTextEscaper.Replace(text)
+}
+
+func unescapeICSText(text string) string {
+	return icsTextUnescaper.Replace(text)
+}
+
+// icsDate returns a DUE date or date-time as YYYY-MM-DD. Values that are
+// neither are returned unchanged, for validateTask to reject them.
+func icsDate(value string) string {
+	layout := "20060102"
+	if strings.Contains(value, "T") {
+		layout = "20060102T150405"
+		value = strings.TrimSuffix(value, "Z")
+	}
+	date, err := time.Parse(layout, value)
+	if err != nil {
+		return value
+	}
+	return date.Format("2006-01-02")
+}
+
+// icsPriority maps a task priority onto the 1 (highest) to 9 (lowest) scale of RFC 5545
+func icsPriority(priority string) int {
+	switch priority {
+	case "high":
+		return 1
+	case "low":
+		return 9
+	}
+	return 5
+}
+
+// priorityOfICS maps a PRIORITY of RFC 5545 onto a task priority: 1 to 4 are
+// high, 5 is medium and 6 to 9 are low. Other values are returned unchanged,
+// for validateTask to reject them.
+func priorityOfICS(value string) string {
+	priority, err := strconv.Atoi(strings.TrimSpace(value))
+	switch {
+	case err != nil || priority < 0 || priority > 9:
+		return value
+	case priority == 0 || priority == 5:
+		return "medium"
+	case priority < 5:
+		return "high"
+	}
+	return "low"
+}
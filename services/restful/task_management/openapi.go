@@ -0,0 +1,13 @@
+package task_management
+
+import (
+	_ "embed"
+)
+
+// openAPISpec is the OpenAPI document served at /openapi.json
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// ValidateRequests enables validation of incoming requests against the OpenAPI document
+var ValidateRequests = false
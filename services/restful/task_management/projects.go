@@ -0,0 +1,574 @@
+package task_management
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultColumns are the columns of a board created without any
+var defaultColumns = []string{"To do", "In progress", "Done"}
+
+var (
+	errProjectNotFound        = errors.New("project not found")
+	errBoardNotFound          = errors.New("board not found")
+	errColumnNotFound         = errors.New("column not found")
+	errProjectArchived        = errors.New("project is archived")
+	errProjectNotArchived     = errors.New("project is not archived")
+	errColumnNotEmpty         = errors.New("column still has tasks: move them to another column first")
+	errTaskNotOnBoard         = errors.New("task is not on a board")
+	errInvalidProjectName     = errors.New("name must be between 3 and 100 characters")
+	errInvalidColumnName      = errors.New("column name must be between 1 and 50 characters")
+	errInvalidIncludeArchived = errors.New("include_archived must be true or false")
+)
+
+// Project groups boards. Archived projects are read-only.
+type Project struct {
+	ID          int        `json:"id" xml:"id"`
+	Name        string     `json:"name" xml:"name"`
+	Description string     `json:"description" xml:"description"`
+	Archived    bool       `json:"archived" xml:"archived"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty" xml:"archived_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" xml:"created_at"`
+}
+
+// Board is a Kanban board of a project, made of ordered columns of ordered tasks
+type Board struct {
+	ID        int       `json:"id" xml:"id"`
+	ProjectID int       `json:"project_id" xml:"project_id"`
+	Name      string    `json:"name" xml:"name"`
+	Columns   []Column  `json:"columns" xml:"columns>column"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+}
+
+// Column is a column of a board. TaskIDs lists its tasks from the top of the
+// column down; Tasks holds the same tasks when the whole board is fetched.
+type Column struct {
+	ID       int    `json:"id" xml:"id"`
+	Name     string `json:"name" xml:"name"`
+	Position int    `json:"position" xml:"position"`
+	TaskIDs  []int  `json:"task_ids" xml:"task_ids>id"`
+	Tasks    []Task `json:"tasks,omitempty" xml:"tasks>task,omitempty"`
+}
+
+// BoardRequest is the payload of POST /projects/:id/boards. Boards created
+// without columns get the default To do, In progress and Done columns.
+type BoardRequest struct {
+	Name    string   `json:"name" xml:"name"`
+	Columns []string `json:"columns" xml:"columns>column"`
+}
+
+// ColumnRequest is the payload of POST /boards/:id/columns and PATCH
+// /boards/:id/columns/:column_id. A missing position adds a column at the end
+// of the board, and leaves an updated column where it is.
+type ColumnRequest struct {
+	Name     *string `json:"name" xml:"name"`
+	Position *int    `json:"position" xml:"position"`
+}
+
+// PositionRequest is the payload of PATCH /tasks/:id/position. A missing
+// position puts the task at the bottom of the column.
+type PositionRequest struct {
+	ColumnID int  `json:"column_id" xml:"column_id"`
+	Position *int `json:"position" xml:"position"`
+}
+
+func validateProject(project Project) error {
+	if len(project.Name) < 3 || len(project.Name) > 100 {
+		return errInvalidProjectName
+	}
+	if len(project.Description) > 500 {
+		return errors.New("description cannot exceed 500 characters")
+	}
+	return nil
+}
+
+func validateColumnName(name string) error {
+	if len(name) < 1 || len(name) > 50 {
+		return errInvalidColumnName
+	}
+	return nil
+}
+
+// validatePosition checks a position among count elements, where count itself appends
+func validatePosition(position int, count int) error {
+	if position < 0 || position > count {
+		return fmt.Errorf("position must be between 0 and %d", count)
+	}
+	return nil
+}
+
+func (sb *sandbox) CreateProject(newProject Project) (Project, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if err := validateProject(newProject); err != nil {
+		return Project{}, err
+	}
+	sb.projectIDCounter++
+	project := Project{ID: sb.projectIDCounter, Name: newProject.Name, Description: newProject.Description, CreatedAt: sb.now()}
+	sb.projects = append(sb.projects, project)
+	return project, nil
+}
+
+// GetProjects returns the projects in the order they were created, leaving
+// out the archived ones unless includeArchived is set
+func (sb *sandbox) GetProjects(includeArchived bool) []Project {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	projects := []Project{}
+	for _, project := range sb.projects {
+		if includeArchived || !project.Archived {
+			projects = append(projects, project)
+		}
+	}
+	return projects
+}
+
+func (sb *sandbox) GetProject(id int) (Project, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	project, err := sb.findProject(id)
+	if err != nil {
+		return Project{}, err
+	}
+	return *project, nil
+}
+
+// UpdateProject renames a project and replaces its description
+func (sb *sandbox) UpdateProject(id int, updatedProject Project) (Project, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	project, err := sb.findOpenProject(id)
+	if err != nil {
+		return Project{}, err
+	}
+	if err := validateProject(updatedProject); err != nil {
+		return Project{}, err
+	}
+	project.Name = updatedProject.Name
+	project.Description = updatedProject.Description
+	return *project, nil
+}
+
+// ArchiveProject makes a project and its boards read-only. Its tasks stay where they are.
+func (sb *sandbox) ArchiveProject(id int) (Project, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	project, err := sb.findOpenProject(id)
+	if err != nil {
+		return Project{}, err
+	}
+	archivedAt := sb.now()
+	project.Archived = true
+	project.ArchivedAt = &archivedAt
+	return *project, nil
+}
+
+// UnarchiveProject makes an archived project editable again
+func (sb *sandbox) UnarchiveProject(id int) (Project, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	project, err := sb.findProject(id)
+	if err != nil {
+		return Project{}, err
+	}
+	if !project.Archived {
+		return Project{}, errProjectNotArchived
+	}
+	project.Archived = false
+	project.ArchivedAt = nil
+	return *project, nil
+}
+
+func (sb *sandbox) CreateBoard(projectID int, request BoardRequest) (Board, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.findOpenProject(projectID); err != nil {
+		return Board{}, err
+	}
+	if err := validateProject(Project{Name: request.Name}); err != nil {
+		return Board{}, err
+	}
+	names := request.Columns
+	if len(names) == 0 {
+		names = defaultColumns
+	}
+	for _, name := range names {
+		if err := validateColumnName(name); err != nil {
+			return Board{}, err
+		}
+	}
+
+	sb.boardIDCounter++
+	board := Board{ID: sb.boardIDCounter, ProjectID: projectID, Name: request.Name, Columns: []Column{}, CreatedAt: sb.now()}
+	for _, name := range names {
+		sb.columnIDCounter++
+		board.Columns = append(board.Columns, Column{ID: sb.columnIDCounter, Name: name, TaskIDs: []int{}})
+	}
+	renumberColumns(&board)
+	sb.boards = append(sb.boards, board)
+	return copyBoard(board), nil
+}
+
+// GetBoards returns the boards of a project in the order they were created
+func (sb *sandbox) GetBoards(projectID int) ([]Board, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.findProject(projectID); err != nil {
+		return nil, err
+	}
+	boards := []Board{}
+	for _, board := range sb.boards {
+		if board.ProjectID == projectID {
+			boards = append(boards, copyBoard(board))
+		}
+	}
+	return boards, nil
+}
+
+// GetBoard returns a board with the tasks of each of its columns
+func (sb *sandbox) GetBoard(id int) (Board, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	board, err := sb.findBoard(id)
+	if err != nil {
+		return Board{}, err
+	}
+	view := copyBoard(*board)
+	now := sb.now()
+	for i := range view.Columns {
+		// The tasks the caller cannot read are left out of the columns
+		taskIDs := view.Columns[i].TaskIDs
+		view.Columns[i].TaskIDs = []int{}
+		view.Columns[i].Tasks = []Task{}
+		for _, taskID := range taskIDs {
+			index, _ := sb.findTaskIndex(taskID)
+			task := sb.tasks[index]
+			if !sb.canRead(task) {
+				continue
+			}
+			task.Due = isTaskDue(task.DueDate, now)
+			view.Columns[i].TaskIDs = append(view.Columns[i].TaskIDs, taskID)
+			view.Columns[i].Tasks = append(view.Columns[i].Tasks, copyTask(task))
+		}
+	}
+	return view, nil
+}
+
+// AddColumn inserts a column into a board, at the end unless a position is given
+func (sb *sandbox) AddColumn(boardID int, request ColumnRequest) (Board, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	board, err := sb.findOpenBoard(boardID)
+	if err != nil {
+		return Board{}, err
+	}
+	name := ""
+	if request.Name != nil {
+		name = *request.Name
+	}
+	if err := validateColumnName(name); err != nil {
+		return Board{}, err
+	}
+	position := len(board.Columns)
+	if request.Position != nil {
+		position = *request.Position
+	}
+	if err := validatePosition(position, len(board.Columns)); err != nil {
+		return Board{}, err
+	}
+
+	sb.columnIDCounter++
+	column := Column{ID: sb.columnIDCounter, Name: name, TaskIDs: []int{}}
+	board.Columns = append(board.Columns[:position], append([]Column{column}, board.Columns[position:]...)...)
+	renumberColumns(board)
+	return copyBoard(*board), nil
+}
+
+// UpdateColumn renames a column and moves it to another position of its board
+func (sb *sandbox) UpdateColumn(boardID int, columnID int, request ColumnRequest) (Board, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	board, err := sb.findOpenBoard(boardID)
+	if err != nil {
+		return Board{}, err
+	}
+	index := columnIndex(board, columnID)
+	if index < 0 {
+		return Board{}, errColumnNotFound
+	}
+	if request.Name != nil {
+		if err := validateColumnName(*request.Name); err != nil {
+			return Board{}, err
+		}
+	}
+	if request.Position != nil {
+		if err := validatePosition(*request.Position, len(board.Columns)-1); err != nil {
+			return Board{}, err
+		}
+	}
+
+	if request.Name != nil {
+		board.Columns[index].Name = *request.Name
+	}
+	if request.Position != nil {
+		column := board.Columns[index]
+		board.Columns = append(board.Columns[:index], board.Columns[index+1:]...)
+		position := *request.Position
+		board.Columns = append(board.Columns[:position], append([]Column{column}, board.Columns[position:]...)...)
+		renumberColumns(board)
+	}
+	return copyBoard(*board), nil
+}
+
+// DeleteColumn removes an empty column from a board
+func (sb *sandbox) DeleteColumn(boardID int, columnID int) (Board, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	board, err := sb.findOpenBoard(boardID)
+	if err != nil {
+		return Board{}, err
+	}
+	index := columnIndex(board, columnID)
+	if index < 0 {
+		return Board{}, errColumnNotFound
+	}
+	if len(board.Columns[index].TaskIDs) > 0 {
+		return Board{}, errColumnNotEmpty
+	}
+	board.Columns = append(board.Columns[:index], board.Columns[index+1:]...)
+	renumberColumns(board)
+	return copyBoard(*board), nil
+}
+
+// MoveTask puts a task in a column at a position, counted from 0 at the top
+// of the column, and shifts the tasks below it down. The task leaves the
+// column it was in, whose tasks below it shift up, even when it is on another board.
+func (sb *sandbox) MoveTask(id int, request PositionRequest) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(id)
+	if err != nil {
+		return Task{}, err
+	}
+	task := &sb.tasks[index]
+	if err := sb.authorize(*task, accessUpdate); err != nil {
+		return Task{}, err
+	}
+	board, column := sb.findColumn(request.ColumnID)
+	if column == nil {
+		return Task{}, errColumnNotFound
+	}
+	if _, err := sb.findOpenProject(board.ProjectID); err != nil {
+		return Task{}, err
+	}
+	if task.ColumnID != nil && *task.ColumnID != column.ID {
+		if err := sb.checkColumnEditable(*task.ColumnID); err != nil {
+			return Task{}, err
+		}
+	}
+
+	others := removeID(column.TaskIDs, id)
+	position := len(others)
+	if request.Position != nil {
+		position = *request.Position
+	}
+	if err := validatePosition(position, len(others)); err != nil {
+		return Task{}, err
+	}
+
+	sb.removeFromColumn(task)
+	column.TaskIDs = append(others[:position], append([]int{id}, others[position:]...)...)
+	boardID := board.ID
+	task.BoardID = &boardID
+	columnID := column.ID
+	task.ColumnID = &columnID
+	sb.renumberTasks(column)
+	sb.emit(EventTaskUpdated, *task)
+	task.Due = isTaskDue(task.DueDate, sb.now())
+	return copyTask(*task), nil
+}
+
+// RemoveTaskFromBoard takes a task off the board it is on
+func (sb *sandbox) RemoveTaskFromBoard(id int) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(id)
+	if err != nil {
+		return Task{}, err
+	}
+	task := &sb.tasks[index]
+	if err := sb.authorize(*task, accessUpdate); err != nil {
+		return Task{}, err
+	}
+	if task.ColumnID == nil {
+		return Task{}, errTaskNotOnBoard
+	}
+	if err := sb.checkColumnEditable(*task.ColumnID); err != nil {
+		return Task{}, err
+	}
+	sb.removeFromColumn(task)
+	sb.emit(EventTaskUpdated, *task)
+	task.Due = isTaskDue(task.DueDate, sb.now())
+	return copyTask(*task), nil
+}
+
+// removeFromColumn takes a task out of its column, whose tasks below it move
+// up, and clears its place. The caller must hold sb.mu.
+func (sb *sandbox) removeFromColumn(task *Task) {
+	if task.ColumnID == nil {
+		return
+	}
+	if _, column := sb.findColumn(*task.ColumnID); column != nil {
+		column.TaskIDs = removeID(column.TaskIDs, task.ID)
+		sb.renumberTasks(column)
+	}
+	task.BoardID = nil
+	task.ColumnID = nil
+	task.Position = nil
+}
+
+// leaveColumn takes a deleted task out of its column but keeps the column it
+// was in, so that restoring the task puts it back. The caller must hold sb.mu.
+func (sb *sandbox) leaveColumn(task *Task) {
+	if task.ColumnID == nil {
+		return
+	}
+	if _, column := sb.findColumn(*task.ColumnID); column != nil {
+		column.TaskIDs = removeID(column.TaskIDs, task.ID)
+		sb.renumberTasks(column)
+	}
+	task.Position = nil
+}
+
+// rejoinColumn puts a restored task back at the bottom of the column it was
+// in, or off any board when the column was deleted. The caller must hold sb.mu.
+func (sb *sandbox) rejoinColumn(task *Task) {
+	if task.ColumnID == nil {
+		return
+	}
+	_, column := sb.findColumn(*task.ColumnID)
+	if column == nil {
+		task.BoardID = nil
+		task.ColumnID = nil
+		return
+	}
+	column.TaskIDs = append(column.TaskIDs, task.ID)
+	position := len(column.TaskIDs) - 1
+	task.Position = &position
+}
+
+// renumberTasks sets the position of every task of a column from its order. The caller must hold sb.mu.
+func (sb *sandbox) renumberTasks(column *Column) {
+	for position, taskID := range column.TaskIDs {
+		if index, err := sb.findTaskIndex(taskID); err == nil {
+			position := position
+			sb.tasks[index].Position = &position
+		}
+	}
+}
+
+// renumberColumns sets the position of every column of a board from its order
+func renumberColumns(board *Board) {
+	for i := range board.Columns {
+		board.Columns[i].Position = i
+	}
+}
+
+// checkColumnEditable rejects changes to a column of an archived project. The caller must hold sb.mu.
+func (sb *sandbox) checkColumnEditable(columnID int) error {
+	board, column := sb.findColumn(columnID)
+	if column == nil {
+		return nil
+	}
+	_, err := sb.findOpenProject(board.ProjectID)
+	return err
+}
+
+// findProject returns a project. The caller must hold sb.mu.
+func (sb *sandbox) findProject(id int) (*Project, error) {
+	for i := range sb.projects {
+		if sb.projects[i].ID == id {
+			return &sb.projects[i], nil
+		}
+	}
+	return nil, errProjectNotFound
+}
+
+// findOpenProject returns a project that is not archived. The caller must hold sb.mu.
+func (sb *sandbox) findOpenProject(id int) (*Project, error) {
+	project, err := sb.findProject(id)
+	if err != nil {
+		return nil, err
+	}
+	if project.Archived {
+		return nil, errProjectArchived
+	}
+	return project, nil
+}
+
+// findBoard returns a board. The caller must hold sb.mu.
+func (sb *sandbox) findBoard(id int) (*Board, error) {
+	for i := range sb.boards {
+		if sb.boards[i].ID == id {
+			return &sb.boards[i], nil
+		}
+	}
+	return nil, errBoardNotFound
+}
+
+// findOpenBoard returns a board of a project that is not archived. The caller must hold sb.mu.
+func (sb *sandbox) findOpenBoard(id int) (*Board, error) {
+	board, err := sb.findBoard(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sb.findOpenProject(board.ProjectID); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+// findColumn returns a column and its board, or nils. The caller must hold sb.mu.
+func (sb *sandbox) findColumn(id int) (*Board, *Column) {
+	for i := range sb.boards {
+		if index := columnIndex(&sb.boards[i], id); index >= 0 {
+			return &sb.boards[i], &sb.boards[i].Columns[index]
+		}
+	}
+	return nil, nil
+}
+
+func columnIndex(board *Board, columnID int) int {
+	for i, column := range board.Columns {
+		if column.ID == columnID {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyBoard returns a board that does not share its columns with the stored one
+func copyBoard(board Board) Board {
+	columns := make([]Column, len(board.Columns))
+	for i, column := range board.Columns {
+		column.TaskIDs = append([]int{}, column.TaskIDs...)
+		columns[i] = column
+	}
+	board.Columns = columns
+	return board
+}
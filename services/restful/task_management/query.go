@@ -0,0 +1,190 @@
+package task_management
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// priorities lists the task priorities from the lowest to the highest
+var priorities = []string{"low", "medium", "high"}
+
+// sortKeys are the fields tasks can be sorted by
+var sortKeys = []string{"id", "title", "due_date", "priority", "status", "created_at"}
+
+var (
+	errInvalidPriorityFilter = errors.New("priority must be one of: low, medium, high")
+	errInvalidOverdue        = errors.New("overdue must be true or false")
+)
+
+// TaskQuery selects and orders the tasks of GET /tasks. Empty fields select every task.
+type TaskQuery struct {
+	Statuses   []string
+	Priorities []string
+	// DueBefore and DueAfter are exclusive bounds on the due date, formatted as YYYY-MM-DD
+	DueBefore string
+	DueAfter  string
+	// Overdue selects the tasks past their due date that are not completed or archived
+	Overdue bool
+	// Text must appear in the title or the description, ignoring case
+	Text string
+	// Owner and Assignee select the tasks a user owns, or is assigned to
+	Owner          string
+	Assignee       string
+	Sort           []SortKey
+	IncludeDeleted bool
+}
+
+// SortKey orders tasks by a field
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// defaultSort keeps the historical order of GET /tasks
+var defaultSort = []SortKey{{Field: "due_date"}}
+
+// ParseTaskQuery reads the filters and the sort order of GET /tasks from its
+// query parameters. status and priority accept comma-separated lists, and sort
+// accepts comma-separated fields, each preceded by - to sort it in descending order.
+func ParseTaskQuery(values url.Values) (TaskQuery, error) {
+	query := TaskQuery{Text: strings.TrimSpace(values.Get("q")), Owner: values.Get("owner"), Assignee: values.Get("assignee"), Sort: defaultSort}
+
+	for _, status := range splitList(values.Get("status")) {
+		if validateStatus(status) != nil {
+			return TaskQuery{}, errInvalidStatus
+		}
+		query.Statuses = append(query.Statuses, status)
+	}
+	for _, priority := range splitList(values.Get("priority")) {
+		if indexOf(priorities, priority) < 0 {
+			return TaskQuery{}, errInvalidPriorityFilter
+		}
+		query.Priorities = append(query.Priorities, priority)
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value *string
+	}{{"due_before", &query.DueBefore}, {"due_after", &query.DueAfter}} {
+		value := values.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return TaskQuery{}, fmt.Errorf("%s must follow the format YYYY-MM-DD", bound.name)
+		}
+		*bound.value = value
+	}
+
+	var err error
+	if query.Overdue, err = parseBoolParam(values.Get("overdue")); err != nil {
+		return TaskQuery{}, errInvalidOverdue
+	}
+	if query.IncludeDeleted, err = parseBoolParam(values.Get("include_deleted")); err != nil {
+		return TaskQuery{}, errInvalidIncludeDeleted
+	}
+
+	if keys := splitList(values.Get("sort")); len(keys) > 0 {
+		query.Sort = nil
+		for _, key := range keys {
+			sortKey := SortKey{Field: strings.TrimPrefix(key, "-"), Descending: strings.HasPrefix(key, "-")}
+			if indexOf(sortKeys, sortKey.Field) < 0 {
+				return TaskQuery{}, fmt.Errorf("unknown sort key %s: tasks can be sorted by %s", sortKey.Field, strings.Join(sortKeys, ", "))
+			}
+			query.Sort = append(query.Sort, sortKey)
+		}
+	}
+	return query, nil
+}
+
+// matches reports whether a task whose Due flag is up to date is selected by the query
+func (query TaskQuery) matches(task Task) bool {
+	if len(query.Statuses) > 0 && indexOf(query.Statuses, task.Status) < 0 {
+		return false
+	}
+	if len(query.Priorities) > 0 && indexOf(query.Priorities, task.Priority) < 0 {
+		return false
+	}
+	// Due dates are formatted as YYYY-MM-DD and compare as strings
+	if query.DueBefore != "" && task.DueDate >= query.DueBefore {
+		return false
+	}
+	if query.DueAfter != "" && task.DueDate <= query.DueAfter {
+		return false
+	}
+	if query.Owner != "" && task.Owner != query.Owner {
+		return false
+	}
+	if query.Assignee != "" && !containsUser(task.Assignees, query.Assignee) {
+		return false
+	}
+	if query.Overdue && (!task.Due || task.Status == StatusCompleted || task.Status == StatusArchived) {
+		return false
+	}
+	if query.Text != "" {
+		text := strings.ToLower(query.Text)
+		if !strings.Contains(strings.ToLower(task.Title), text) && !strings.Contains(strings.ToLower(task.Description), text) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortTasks orders tasks by the keys of the query, then by ID
+func (query TaskQuery) sortTasks(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		for _, key := range query.Sort {
+			if c := compareTasks(tasks[i], tasks[j], key.Field); c != 0 {
+				return (c < 0) != key.Descending
+			}
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+}
+
+func compareTasks(a, b Task, field string) int {
+	switch field {
+	case "id":
+		return a.ID - b.ID
+	case "title":
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	case "priority":
+		return indexOf(priorities, a.Priority) - indexOf(priorities, b.Priority)
+	case "status":
+		return indexOf(statuses, a.Status) - indexOf(statuses, b.Status)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+	return strings.Compare(a.DueDate, b.DueDate)
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+	return list
+}
+
+func parseBoolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func indexOf(list []string, value string) int {
+	for i, element := range list {
+		if element == value {
+			return i
+		}
+	}
+	return -1
+}
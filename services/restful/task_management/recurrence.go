@@ -0,0 +1,493 @@
+package task_management
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// DefaultOccurrencesLimit and MaxOccurrencesLimit bound the limit parameter of GET /tasks/:id/occurrences
+const (
+	DefaultOccurrencesLimit = 10
+	MaxOccurrencesLimit     = 100
+)
+
+// maxIdlePeriods stops the search for occurrences of rules that never match,
+// such as the 30th of every February, after as many periods in a row without any
+const maxIdlePeriods = 1000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var (
+	errRRuleFormat        = errors.New("rrule must be a list of NAME=VALUE parts separated by semicolons, such as FREQ=WEEKLY;BYDAY=MO,WE")
+	errRRuleFreq          = errors.New("rrule FREQ must be one of: DAILY, WEEKLY, MONTHLY, YEARLY")
+	errRRuleInterval      = errors.New("rrule INTERVAL must be a positive integer")
+	errRRuleCount         = errors.New("rrule COUNT must be a positive integer")
+	errRRuleUntil         = errors.New("rrule UNTIL must be a date such as 20301231 or a UTC time such as 20301231T235959Z")
+	errRRuleCountAndUntil = errors.New("rrule cannot have both COUNT and UNTIL")
+	errRRuleByDay         = errors.New("rrule BYDAY must list days such as MO,WE or, in monthly and yearly rules, days of the month such as 1MO or -1FR")
+	errRRuleByMonthDay    = errors.New("rrule BYMONTHDAY must list days between 1 and 31 or between -31 and -1")
+	errRRuleByMonth       = errors.New("rrule BYMONTH must list months between 1 and 12")
+	errRRuleYearlyByDay   = errors.New("rrule BYDAY in a yearly rule requires BYMONTH")
+	errTaskNotRecurring   = errors.New("task does not recur")
+	errInvalidOccurrences = fmt.Errorf("limit must be between 1 and %d", MaxOccurrencesLimit)
+)
+
+// RRule is a recurrence rule in the iCalendar (RFC 5545) RRULE syntax, such as
+// FREQ=MONTHLY;BYMONTHDAY=-1. It supports the FREQ, INTERVAL, COUNT, UNTIL,
+// BYDAY, BYMONTHDAY and BYMONTH parts.
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+}
+
+// WeekdayNum is a day of a BYDAY part. A non-zero Ordinal selects the nth such
+// day of the month, counting from the end when negative.
+type WeekdayNum struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// Recurrence places a recurring task in its series
+type Recurrence struct {
+	// SeriesStart is the due date of the first occurrence, the DTSTART of the rule
+	SeriesStart string `json:"series_start" xml:"series_start"`
+	// Number counts the occurrences of the series from 1
+	Number int `json:"number" xml:"number"`
+	// PreviousID is the occurrence this task was generated from
+	PreviousID *int `json:"previous_id,omitempty" xml:"previous_id,omitempty"`
+	// NextID is the occurrence generated when this task was completed
+	NextID *int `json:"next_id,omitempty" xml:"next_id,omitempty"`
+}
+
+// Occurrence is a date a recurring task falls on
+type Occurrence struct {
+	Number  int    `json:"number" xml:"number"`
+	DueDate string `json:"due_date" xml:"due_date"`
+}
+
+// ParseRRule reads a recurrence rule. A leading RRULE: is ignored, as is case.
+func ParseRRule(value string) (RRule, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "RRULE:")
+	rule := RRule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		name, value, found := strings.Cut(part, "=")
+		if !found || value == "" {
+			return RRule{}, errRRuleFormat
+		}
+		var err error
+		switch name {
+		case "FREQ":
+			if value != FreqDaily && value != FreqWeekly && value != FreqMonthly && value != FreqYearly {
+				return RRule{}, errRRuleFreq
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			if rule.Interval, err = strconv.Atoi(value); err != nil || rule.Interval < 1 {
+				return RRule{}, errRRuleInterval
+			}
+		case "COUNT":
+			if rule.Count, err = strconv.Atoi(value); err != nil || rule.Count < 1 {
+				return RRule{}, errRRuleCount
+			}
+		case "UNTIL":
+			if rule.Until, err = parseUntil(value); err != nil {
+				return RRule{}, err
+			}
+		case "BYDAY":
+			if rule.ByDay, err = parseByDay(value); err != nil {
+				return RRule{}, err
+			}
+		case "BYMONTHDAY":
+			if rule.ByMonthDay, err = parseNumbers(value, 1, 31, true, errRRuleByMonthDay); err != nil {
+				return RRule{}, err
+			}
+		case "BYMONTH":
+			if rule.ByMonth, err = parseNumbers(value, 1, 12, false, errRRuleByMonth); err != nil {
+				return RRule{}, err
+			}
+		default:
+			return RRule{}, fmt.Errorf("rrule part %s is not supported: rules can use FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and BYMONTH", name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return RRule{}, errRRuleFreq
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return RRule{}, errRRuleCountAndUntil
+	}
+	for _, day := range rule.ByDay {
+		if day.Ordinal != 0 && rule.Freq != FreqMonthly && rule.Freq != FreqYearly {
+			return RRule{}, errRRuleByDay
+		}
+	}
+	if rule.Freq == FreqYearly && len(rule.ByDay) > 0 && len(rule.ByMonth) == 0 {
+		return RRule{}, errRRuleYearlyByDay
+	}
+	return rule, nil
+}
+
+// String formats a rule with its parts in a fixed order, which makes equivalent rules equal
+func (rule RRule) String() string {
+	parts := []string{"FREQ=" + rule.Freq}
+	if rule.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rule.Interval))
+	}
+	if rule.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(rule.Count))
+	}
+	if rule.Until != nil {
+		if rule.Until.Equal(truncateToDate(*rule.Until)) {
+			parts = append(parts, "UNTIL="+rule.Until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+rule.Until.Format("20060102T150405Z"))
+		}
+	}
+	if len(rule.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinIDs(rule.ByMonth, ","))
+	}
+	if len(rule.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinIDs(rule.ByMonthDay, ","))
+	}
+	if len(rule.ByDay) > 0 {
+		days := make([]string, len(rule.ByDay))
+		for i, day := range rule.ByDay {
+			days[i] = weekdayNames[day.Weekday]
+			if day.Ordinal != 0 {
+				days[i] = strconv.Itoa(day.Ordinal) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences calls yield with the dates of the series starting on start, in
+// order and numbered from 1, until yield returns false or the series ends.
+// start is always the first occurrence, as DTSTART is in iCalendar, even when
+// the rule does not match it. Dates that do not exist, such as the 31st of a
+// 30-day month or the 29th of February of a common year, are skipped.
+func (rule RRule) Occurrences(start time.Time, yield func(number int, date time.Time) bool) {
+	start = truncateToDate(start)
+	number := 1
+	if !yield(number, start) {
+		return
+	}
+	for period, idle := 0, 0; idle < maxIdlePeriods; period++ {
+		dates := rule.candidates(start, period)
+		if len(dates) == 0 {
+			idle++
+			continue
+		}
+		idle = 0
+		for _, date := range dates {
+			if !date.After(start) {
+				continue
+			}
+			if rule.Until != nil && date.After(*rule.Until) {
+				return
+			}
+			if rule.Count > 0 && number >= rule.Count {
+				return
+			}
+			number++
+			if !yield(number, date) {
+				return
+			}
+		}
+	}
+}
+
+// candidates returns the dates matching the rule in a period of the series, in order
+func (rule RRule) candidates(start time.Time, period int) []time.Time {
+	step := period * rule.Interval
+	var dates []time.Time
+	switch rule.Freq {
+	case FreqDaily:
+		date := start.AddDate(0, 0, step)
+		if rule.matchesWeekday(date) && rule.matchesMonthDay(date) {
+			dates = append(dates, date)
+		}
+	case FreqWeekly:
+		// Weeks start on Monday
+		monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+7*step)
+		if len(rule.ByDay) == 0 {
+			dates = append(dates, monday.AddDate(0, 0, (int(start.Weekday())+6)%7))
+			break
+		}
+		for offset := 0; offset < 7; offset++ {
+			if date := monday.AddDate(0, 0, offset); rule.matchesWeekday(date) {
+				dates = append(dates, date)
+			}
+		}
+	case FreqMonthly:
+		year, month := start.Year(), start.Month()+time.Month(step)
+		dates = rule.monthDates(year, month, start.Day())
+	case FreqYearly:
+		year := start.Year() + step
+		months := rule.ByMonth
+		if len(months) == 0 {
+			months = []int{int(start.Month())}
+		}
+		for _, month := range months {
+			dates = append(dates, rule.monthDates(year, time.Month(month), start.Day())...)
+		}
+	}
+
+	matched := dates[:0]
+	for _, date := range dates {
+		if len(rule.ByMonth) == 0 || containsID(rule.ByMonth, int(date.Month())) {
+			matched = append(matched, date)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Before(matched[j]) })
+	return matched
+}
+
+// monthDates returns the dates of a month matching BYMONTHDAY and BYDAY, or
+// the given day of the month when the rule has neither. month may overflow
+// into the following years.
+func (rule RRule) monthDates(year int, month time.Month, day int) []time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	length := first.AddDate(0, 1, -1).Day()
+	if len(rule.ByMonthDay) == 0 && len(rule.ByDay) == 0 {
+		if day > length {
+			return nil
+		}
+		return []time.Time{first.AddDate(0, 0, day-1)}
+	}
+
+	var dates []time.Time
+	for day := 1; day <= length; day++ {
+		date := first.AddDate(0, 0, day-1)
+		if (len(rule.ByMonthDay) == 0 || rule.matchesMonthDay(date)) && (len(rule.ByDay) == 0 || rule.matchesDayOfMonth(date, length)) {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// matchesWeekday reports whether a date falls on one of the BYDAY days, ignoring their ordinals
+func (rule RRule) matchesWeekday(date time.Time) bool {
+	if len(rule.ByDay) == 0 {
+		return true
+	}
+	for _, day := range rule.ByDay {
+		if day.Weekday == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDayOfMonth reports whether a date is one of the BYDAY days of its month, such as its last Friday
+func (rule RRule) matchesDayOfMonth(date time.Time, length int) bool {
+	for _, day := range rule.ByDay {
+		if day.Weekday != date.Weekday() {
+			continue
+		}
+		fromStart := (date.Day()-1)/7 + 1
+		fromEnd := -((length-date.Day())/7 + 1)
+		if day.Ordinal == 0 || day.Ordinal == fromStart || day.Ordinal == fromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthDay reports whether a date is one of the BYMONTHDAY days, negative days counting from the end of the month
+func (rule RRule) matchesMonthDay(date time.Time) bool {
+	if len(rule.ByMonthDay) == 0 {
+		return true
+	}
+	length := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, day := range rule.ByMonthDay {
+		if day == date.Day() || day == date.Day()-length-1 {
+			return true
+		}
+	}
+	return false
+}
+
+func parseUntil(value string) (*time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if until, err := time.Parse(layout, value); err == nil {
+			return &until, nil
+		}
+	}
+	return nil, errRRuleUntil
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, element := range strings.Split(value, ",") {
+		if len(element) < 2 {
+			return nil, errRRuleByDay
+		}
+		weekday, ok := weekdays[element[len(element)-2:]]
+		if !ok {
+			return nil, errRRuleByDay
+		}
+		day := WeekdayNum{Weekday: weekday}
+		if ordinal := element[:len(element)-2]; ordinal != "" {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, errRRuleByDay
+			}
+			day.Ordinal = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// parseNumbers reads a comma-separated list of numbers between min and max,
+// or between -max and -min when negative numbers are allowed
+func parseNumbers(value string, min int, max int, negative bool, invalid error) ([]int, error) {
+	var numbers []int
+	for _, element := range strings.Split(value, ",") {
+		number, err := strconv.Atoi(element)
+		magnitude := number
+		if negative && number < 0 {
+			magnitude = -number
+		}
+		if err != nil || magnitude < min || magnitude > max {
+			return nil, invalid
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+func truncateToDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// normalizeRRule checks the rule of a task and formats it with String. An empty rule stays empty.
+func normalizeRRule(value string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return "", nil
+	}
+	rule, err := ParseRRule(value)
+	if err != nil {
+		return "", err
+	}
+	return rule.String(), nil
+}
+
+// GetOccurrences returns the occurrences of a recurring task from its own due
+// date on, the first being the task itself
+func (sb *sandbox) GetOccurrences(id int, limit int) ([]Occurrence, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	task := sb.tasks[index]
+	if err := sb.authorize(task, accessRead); err != nil {
+		return nil, err
+	}
+	if task.RRule == "" {
+		return nil, errTaskNotRecurring
+	}
+	rule, start, err := seriesOf(task)
+	if err != nil {
+		return nil, err
+	}
+
+	dueDate, _ := time.Parse("2006-01-02", task.DueDate)
+	occurrences := []Occurrence{}
+	rule.Occurrences(start, func(number int, date time.Time) bool {
+		if date.Before(dueDate) {
+			return true
+		}
+		occurrences = append(occurrences, Occurrence{Number: number, DueDate: date.Format("2006-01-02")})
+		return len(occurrences) < limit
+	})
+	return occurrences, nil
+}
+
+// nextOccurrence returns the occurrence that follows a completed recurring
+// task and links the task to it, or nil when the series has ended or the
+// occurrence was already created by an earlier completion. The caller must
+// hold sb.mu and add the occurrence to sb.tasks.
+func (sb *sandbox) nextOccurrence(task *Task) *Task {
+	if task.RRule == "" || task.Recurrence == nil || task.Recurrence.NextID != nil {
+		return nil
+	}
+	rule, start, err := seriesOf(*task)
+	if err != nil {
+		return nil
+	}
+	dueDate, _ := time.Parse("2006-01-02", task.DueDate)
+	var next *Occurrence
+	rule.Occurrences(start, func(number int, date time.Time) bool {
+		if date.After(dueDate) {
+			next = &Occurrence{Number: number, DueDate: date.Format("2006-01-02")}
+			return false
+		}
+		return true
+	})
+	if next == nil {
+		return nil
+	}
+
+	sb.taskIDCounter++
+	previousID, nextID := task.ID, sb.taskIDCounter
+	task.Recurrence.NextID = &nextID
+	return &Task{
+		ID:            nextID,
+		Title:         task.Title,
+		Description:   task.Description,
+		DueDate:       next.DueDate,
+		Priority:      task.Priority,
+		Status:        StatusPending,
+		CreatedAt:     sb.now(),
+		ParentID:      task.ParentID,
+		BlockedBy:     []int{},
+		Owner:         task.Owner,
+		Assignees:     append([]string{}, task.Assignees...),
+		RRule:         task.RRule,
+		Recurrence:    &Recurrence{SeriesStart: task.Recurrence.SeriesStart, Number: next.Number, PreviousID: &previousID},
+		StatusHistory: []StatusChange{},
+	}
+}
+
+// seriesOf returns the rule of a recurring task and the date its series starts on
+func seriesOf(task Task) (RRule, time.Time, error) {
+	rule, err := ParseRRule(task.RRule)
+	if err != nil {
+		return RRule{}, time.Time{}, err
+	}
+	start, err := time.Parse("2006-01-02", task.Recurrence.SeriesStart)
+	if err != nil {
+		return RRule{}, time.Time{}, err
+	}
+	return rule, start, nil
+}
@@ -0,0 +1,415 @@
+package task_management
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types of the edges of a task graph
+const (
+	EdgeBlocks  = "blocks"
+	EdgeSubtask = "subtask"
+)
+
+var (
+	errSelfParent          = errors.New("a task cannot be its own parent")
+	errParentCycle         = errors.New("parent would create a cycle")
+	errSelfDependency      = errors.New("a task cannot be blocked by itself")
+	errDependencyCycle     = errors.New("dependency would create a cycle")
+	errDuplicateDependency = errors.New("task is already blocked by this task")
+	errDependencyNotFound  = errors.New("task is not blocked by this task")
+)
+
+// OpenBlockersError rejects the completion of a task that is blocked by tasks
+// that are neither completed nor archived
+type OpenBlockersError struct {
+	Blockers []int
+}
+
+func (e *OpenBlockersError) Error() string {
+	return "task is blocked by open tasks: " + joinIDs(e.Blockers, ", ")
+}
+
+// ParentRequest is the payload of PUT /tasks/:id/parent. A null parent makes the task a top-level task.
+type ParentRequest struct {
+	ParentID *int `json:"parent_id" xml:"parent_id"`
+}
+
+// DependencyRequest is the payload of POST /tasks/:id/dependencies
+type DependencyRequest struct {
+	TaskID int `json:"task_id" xml:"task_id"`
+}
+
+// TaskDependencies are the tasks directly blocking a task and the tasks it directly blocks
+type TaskDependencies struct {
+	BlockedBy []Task `json:"blocked_by" xml:"blocked_by>task"`
+	Blocks    []Task `json:"blocks" xml:"blocks>task"`
+}
+
+// GraphNode is a task of a task graph
+type GraphNode struct {
+	ID       int    `json:"id" xml:"id"`
+	Title    string `json:"title" xml:"title"`
+	Status   string `json:"status" xml:"status"`
+	ParentID *int   `json:"parent_id,omitempty" xml:"parent_id,omitempty"`
+}
+
+// GraphEdge relates two tasks of a task graph: with the blocks type, From
+// blocks To; with the subtask type, To is a subtask of From.
+type GraphEdge struct {
+	From int    `json:"from" xml:"from"`
+	To   int    `json:"to" xml:"to"`
+	Type string `json:"type" xml:"type"`
+}
+
+// TaskGraph is the graph of the subtasks and dependencies between tasks
+type TaskGraph struct {
+	Nodes []GraphNode `json:"nodes" xml:"nodes>node"`
+	Edges []GraphEdge `json:"edges" xml:"edges>edge"`
+}
+
+// GetSubtasks returns the direct subtasks of a task
+func (sb *sandbox) GetSubtasks(id int) ([]Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := sb.authorize(sb.tasks[index], accessRead); err != nil {
+		return nil, err
+	}
+	subtasks := []Task{}
+	for _, task := range sb.tasks {
+		if task.ParentID != nil && *task.ParentID == id && sb.canRead(task) {
+			task.Due = isTaskDue(task.DueDate, sb.now())
+			subtasks = append(subtasks, copyTask(task))
+		}
+	}
+	return subtasks, nil
+}
+
+// SetParent makes a task a subtask of another task, or a top-level task when parentID is nil
+func (sb *sandbox) SetParent(id int, parentID *int) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(id)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := sb.authorize(sb.tasks[index], accessUpdate); err != nil {
+		return Task{}, err
+	}
+	if parentID != nil {
+		if err := sb.validateParent(id, *parentID); err != nil {
+			return Task{}, err
+		}
+	}
+	task := &sb.tasks[index]
+	task.ParentID = parentID
+	sb.emit(EventTaskUpdated, *task)
+	task.Due = isTaskDue(task.DueDate, sb.now())
+	return copyTask(*task), nil
+}
+
+// GetDependencies returns the tasks blocking a task and the tasks it blocks
+func (sb *sandbox) GetDependencies(id int) (TaskDependencies, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(id)
+	if err != nil {
+		return TaskDependencies{}, err
+	}
+	if err := sb.authorize(sb.tasks[index], accessRead); err != nil {
+		return TaskDependencies{}, err
+	}
+	dependencies := TaskDependencies{BlockedBy: []Task{}, Blocks: []Task{}}
+	for _, task := range sb.readable(sb.tasks) {
+		task.Due = isTaskDue(task.DueDate, sb.now())
+		if containsID(sb.tasks[index].BlockedBy, task.ID) {
+			dependencies.BlockedBy = append(dependencies.BlockedBy, task)
+		}
+		if containsID(task.BlockedBy, id) {
+			dependencies.Blocks = append(dependencies.Blocks, task)
+		}
+	}
+	return dependencies, nil
+}
+
+// AddDependency records that a task is blocked by another task
+func (sb *sandbox) AddDependency(id int, blockerID int) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(id)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := sb.authorize(sb.tasks[index], accessUpdate); err != nil {
+		return Task{}, err
+	}
+	if blockerID == id {
+		return Task{}, errSelfDependency
+	}
+	if blocker, err := sb.findTaskIndex(blockerID); err != nil || !sb.canRead(sb.tasks[blocker]) {
+		return Task{}, fmt.Errorf("task %d does not exist", blockerID)
+	}
+	task := &sb.tasks[index]
+	if containsID(task.BlockedBy, blockerID) {
+		return Task{}, errDuplicateDependency
+	}
+	if path := sb.blockingPath(blockerID, id); path != nil {
+		return Task{}, fmt.Errorf("%w: %s", errDependencyCycle, joinIDs(append([]int{id}, path...), " -> "))
+	}
+
+	task.BlockedBy = append(append([]int{}, task.BlockedBy...), blockerID)
+	sort.Ints(task.BlockedBy)
+	sb.emit(EventTaskUpdated, *task)
+	task.Due = isTaskDue(task.DueDate, sb.now())
+	return copyTask(*task), nil
+}
+
+// RemoveDependency records that a task is no longer blocked by another task
+func (sb *sandbox) RemoveDependency(id int, blockerID int) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(id)
+	if err != nil {
+		return Task{}, err
+	}
+	task := &sb.tasks[index]
+	if err := sb.authorize(*task, accessUpdate); err != nil {
+		return Task{}, err
+	}
+	if !containsID(task.BlockedBy, blockerID) {
+		return Task{}, errDependencyNotFound
+	}
+	task.BlockedBy = removeID(task.BlockedBy, blockerID)
+	sb.emit(EventTaskUpdated, *task)
+	task.Due = isTaskDue(task.DueDate, sb.now())
+	return copyTask(*task), nil
+}
+
+// GetTaskGraph returns the graph of the active tasks, or only of the tasks
+// connected to a task by subtasks and dependencies when rootID is set. Only
+// the tasks the caller can read are part of the graph.
+func (sb *sandbox) GetTaskGraph(rootID *int) (TaskGraph, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if rootID != nil {
+		index, err := sb.findTaskIndex(*rootID)
+		if err != nil {
+			return TaskGraph{}, err
+		}
+		if err := sb.authorize(sb.tasks[index], accessRead); err != nil {
+			return TaskGraph{}, err
+		}
+	}
+	graph := TaskGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	active := map[int]bool{}
+	for _, task := range sb.readable(sb.tasks) {
+		active[task.ID] = true
+	}
+	for _, task := range sb.tasks {
+		if task.ParentID != nil && active[*task.ParentID] {
+			graph.Edges = append(graph.Edges, GraphEdge{From: *task.ParentID, To: task.ID, Type: EdgeSubtask})
+		}
+		for _, blockerID := range task.BlockedBy {
+			if active[blockerID] {
+				graph.Edges = append(graph.Edges, GraphEdge{From: blockerID, To: task.ID, Type: EdgeBlocks})
+			}
+		}
+	}
+
+	included := active
+	if rootID != nil {
+		if !active[*rootID] {
+			return TaskGraph{}, errTaskNotFound
+		}
+		included = connectedTasks(*rootID, graph.Edges)
+	}
+	for _, task := range sb.tasks {
+		if included[task.ID] && active[task.ID] {
+			node := GraphNode{ID: task.ID, Title: task.Title, Status: task.Status}
+			if task.ParentID != nil && active[*task.ParentID] {
+				node.ParentID = task.ParentID
+			}
+			graph.Nodes = append(graph.Nodes, node)
+		}
+	}
+	edges := graph.Edges[:0]
+	for _, edge := range graph.Edges {
+		if included[edge.From] {
+			edges = append(edges, edge)
+		}
+	}
+	graph.Edges = edges
+
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Type < b.Type
+	})
+	return graph, nil
+}
+
+// connectedTasks returns the tasks that can be reached from a task by following edges in either direction
+func connectedTasks(rootID int, edges []GraphEdge) map[int]bool {
+	connected := map[int]bool{rootID: true}
+	pending := []int{rootID}
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+		for _, edge := range edges {
+			for _, next := range []struct{ from, to int }{{edge.From, edge.To}, {edge.To, edge.From}} {
+				if next.from == id && !connected[next.to] {
+					connected[next.to] = true
+					pending = append(pending, next.to)
+				}
+			}
+		}
+	}
+	return connected
+}
+
+// validateParent checks that a task can become a subtask of another task. The caller must hold sb.mu.
+func (sb *sandbox) validateParent(id int, parentID int) error {
+	if parentID == id {
+		return errSelfParent
+	}
+	if index, err := sb.findTaskIndex(parentID); err != nil || !sb.canRead(sb.tasks[index]) {
+		return fmt.Errorf("parent task %d does not exist", parentID)
+	}
+	// Deleted tasks are followed too, so that restoring them cannot close a cycle
+	for ancestor := sb.findAnyTask(parentID); ancestor != nil && ancestor.ParentID != nil; ancestor = sb.findAnyTask(*ancestor.ParentID) {
+		if *ancestor.ParentID == id {
+			return fmt.Errorf("%w: task %d is a subtask of task %d", errParentCycle, parentID, id)
+		}
+	}
+	return nil
+}
+
+// blockingPath returns the chain of tasks through which a task is blocked by
+// another, each task being blocked by the next, or nil when it is not.
+// Deleted tasks are followed too, so that restoring them cannot close a cycle.
+// The caller must hold sb.mu.
+func (sb *sandbox) blockingPath(fromID int, toID int) []int {
+	visited := map[int]bool{}
+	var walk func(id int) []int
+	walk = func(id int) []int {
+		if id == toID {
+			return []int{id}
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+		if task := sb.findAnyTask(id); task != nil {
+			for _, blockerID := range task.BlockedBy {
+				if path := walk(blockerID); path != nil {
+					return append([]int{id}, path...)
+				}
+			}
+		}
+		return nil
+	}
+	return walk(fromID)
+}
+
+// checkBlockers returns an OpenBlockersError when a task is blocked by active
+// tasks that are neither completed nor archived. The caller must hold sb.mu.
+func (sb *sandbox) checkBlockers(task Task) error {
+	var open []int
+	for _, blockerID := range task.BlockedBy {
+		if index, err := sb.findTaskIndex(blockerID); err == nil {
+			if status := sb.tasks[index].Status; status != StatusCompleted && status != StatusArchived {
+				open = append(open, blockerID)
+			}
+		}
+	}
+	if len(open) > 0 {
+		return &OpenBlockersError{Blockers: open}
+	}
+	return nil
+}
+
+// forgetTasks removes the relations to purged tasks. Their subtasks become
+// top-level tasks. The caller must hold sb.mu.
+func (sb *tenant) forgetTasks(purged map[int]bool) {
+	for _, tasks := range [][]Task{sb.tasks, sb.trash} {
+		for i := range tasks {
+			if tasks[i].ParentID != nil && purged[*tasks[i].ParentID] {
+				tasks[i].ParentID = nil
+			}
+			for _, blockerID := range tasks[i].BlockedBy {
+				if purged[blockerID] {
+					tasks[i].BlockedBy = removeID(tasks[i].BlockedBy, blockerID)
+				}
+			}
+		}
+	}
+}
+
+// findTaskIndex returns the index of an active task. The caller must hold sb.mu.
+func (sb *sandbox) findTaskIndex(id int) (int, error) {
+	for i, task := range sb.tasks {
+		if task.ID == id {
+			return i, nil
+		}
+	}
+	return -1, errTaskNotFound
+}
+
+// findAnyTask returns an active or deleted task, or nil. The caller must hold sb.mu.
+func (sb *sandbox) findAnyTask(id int) *Task {
+	for _, tasks := range [][]Task{sb.tasks, sb.trash} {
+		for i := range tasks {
+			if tasks[i].ID == id {
+				return &tasks[i]
+			}
+		}
+	}
+	return nil
+}
+
+func containsID(ids []int, id int) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
+
+// removeID returns a copy of ids without id
+func removeID(ids []int, id int) []int {
+	kept := []int{}
+	for _, existing := range ids {
+		if existing != id {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
+
+func joinIDs(ids []int, separator string) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, separator)
+}
@@ -1,106 +1,1113 @@
 package task_management
 
 import (
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 
+	"github.com/abhivaikar/playpi/services/clock"
+	"github.com/abhivaikar/playpi/services/negotiation"
+	"github.com/abhivaikar/playpi/services/restful/idempotency"
+	"github.com/abhivaikar/playpi/services/restful/openapi"
+	"github.com/abhivaikar/playpi/services/restful/pagination"
+	"github.com/abhivaikar/playpi/services/restful/webhooks"
+	"github.com/abhivaikar/playpi/services/tenancy"
 	"github.com/gin-gonic/gin"
 )
 
+var errInvalidIncludeDeleted = errors.New("include_deleted must be true or false")
+
+// IdempotencyKeyTTL is how long Idempotency-Key headers on creates are remembered
+var IdempotencyKeyTTL = idempotency.DefaultTTL
+
 func StartServer() {
+	sandboxes.Reset()
 	r := setupRouter()
 	r.Run(":8085")
 }
 
 func StartServerForTesting() *gin.Engine {
+	sandboxes.Reset()
 	return setupRouter()
 }
 
 func setupRouter() *gin.Engine {
 	r := gin.Default()
+	r.Use(negotiation.Middleware("/tasks/events", "/tasks.ics", "/tasks.csv", "/tasks/import", openapi.AssetsRoute))
+	if ValidateRequests {
+		r.Use(openapi.ValidationMiddleware(openAPISpec))
+	}
+	r.Use(tenancy.Middleware(sandboxes))
+	r.Use(clock.Middleware())
+	r.Use(authMiddleware())
+	openapi.Register(r, openAPISpec)
+	idempotencyKeys := idempotency.NewStore(IdempotencyKeyTTL)
+	idempotencyKeys.Scope = tenancy.ID
 
-	r.POST("/tasks", func(c *gin.Context) {
+	r.POST("/tasks", idempotencyKeys.Middleware(), func(c *gin.Context) {
 		var newTask Task
-		if err := c.ShouldBindJSON(&newTask); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if err := negotiation.Bind(c, &newTask); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		task, err := CreateTask(newTask)
+		task, err := sandboxOf(c).CreateTask(newTask)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusCreated, task)
+		negotiation.Render(c, http.StatusCreated, task)
 	})
 
+	// GET /tasks - Get the tasks matching the filters, sorted and paginated
 	r.GET("/tasks", func(c *gin.Context) {
-		tasks, err := GetTasks()
+		query, err := ParseTaskQuery(c.Request.URL.Query())
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		page, err := pagination.Parse(c.Query("limit"), c.Query("offset"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tasks, err := sandboxOf(c).GetTasks(query)
+		if err != nil {
+			negotiation.RenderEmpty(c, http.StatusOK, []Task{}, gin.H{"message": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, pagination.Apply(c, tasks, page))
+	})
+
+	// GET /tasks.ics - Download the tasks matching the filters as the VTODOs of an iCalendar file
+	r.GET("/tasks.ics", taskFileHandler(MIMECalendar, "tasks.ics", func(sb *sandbox, tasks []Task) ([]byte, error) {
+		return formatTasksICS(tasks, sb.now()), nil
+	}))
+
+	// GET /tasks.csv - Download the tasks matching the filters as a CSV file
+	r.GET("/tasks.csv", taskFileHandler(MIMECSV, "tasks.csv", func(sb *sandbox, tasks []Task) ([]byte, error) {
+		return formatTasksCSV(tasks)
+	}))
+
+	// POST /tasks/import - Create tasks from the rows of a CSV or ICS file
+	r.POST("/tasks/import", func(c *gin.Context) {
+		format, data, err := readImportUpload(c)
 		if err != nil {
-			c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+			negotiation.Render(c, statusForImportError(err), gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, tasks)
+		result, err := sandboxOf(c).ImportTasks(format, data)
+		if err != nil {
+			negotiation.Render(c, statusForImportError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, result)
+	})
+
+	// GET /tasks/trash - Get the deleted tasks that have not been purged yet
+	r.GET("/tasks/trash", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).GetTrash())
+	})
+
+	// GET /tasks/events - Stream the changes to the tasks the caller can read as Server-Sent Events
+	r.GET("/tasks/events", func(c *gin.Context) {
+		sb := sandboxOf(c)
+		sb.feed.FilteredHandler(func(data interface{}) bool {
+			task, ok := data.(Task)
+			return ok && readableBy(task, sb.user)
+		})(c)
 	})
 
 	r.GET("/tasks/:id", func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
 			return
 		}
-		task, err := GetTaskByID(id)
+		includeDeleted, err := parseIncludeDeletedParam(c)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, task)
+		task, err := sandboxOf(c).GetTaskByID(id, includeDeleted)
+		if renderAccessError(c, err) {
+			return
+		}
+		if err != nil {
+			negotiation.Render(c, http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, task)
 	})
 
 	r.PUT("/tasks/:id", func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
 			return
 		}
 		var updatedTask Task
-		if err := c.ShouldBindJSON(&updatedTask); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if err := negotiation.Bind(c, &updatedTask); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		task, err := sandboxOf(c).UpdateTask(id, updatedTask)
+		if renderMoveError(c, err) || renderAccessError(c, err) {
 			return
 		}
-		task, err := UpdateTask(id, updatedTask)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, task)
+		negotiation.Render(c, http.StatusOK, task)
 	})
 
 	r.DELETE("/tasks/:id", func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		err = sandboxOf(c).DeleteTask(id)
+		if renderAccessError(c, err) {
+			return
+		}
+		if err != nil {
+			negotiation.Render(c, http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, gin.H{"message": "task deleted"})
+	})
+
+	// POST /tasks/:id/restore - Move a deleted task back to the active tasks
+	r.POST("/tasks/:id/restore", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		task, err := sandboxOf(c).RestoreTask(id)
+		if renderAccessError(c, err) {
+			return
+		}
+		if errors.Is(err, errTaskNotInTrash) {
+			negotiation.Render(c, http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			negotiation.Render(c, http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, task)
+	})
+
+	// PUT /tasks/:id/start, /block, /complete, /archive and /reopen - Move a task through its lifecycle
+	r.PUT("/tasks/:id/start", transitionHandler((*sandbox).StartTask))
+	r.PUT("/tasks/:id/block", transitionHandler((*sandbox).BlockTask))
+	r.PUT("/tasks/:id/complete", transitionHandler((*sandbox).MarkTaskAsCompleted))
+	r.PUT("/tasks/:id/archive", transitionHandler((*sandbox).ArchiveTask))
+	r.PUT("/tasks/:id/reopen", transitionHandler((*sandbox).ReopenTask))
+
+	// GET /tasks/graph - Get the graph of the subtasks and dependencies of every task
+	r.GET("/tasks/graph", func(c *gin.Context) {
+		graph, err := sandboxOf(c).GetTaskGraph(nil)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, graph)
+	})
+
+	// GET /tasks/:id/graph - Get the graph of the tasks connected to a task
+	r.GET("/tasks/:id/graph", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
 			return
 		}
-		if err := DeleteTask(id); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		graph, err := sandboxOf(c).GetTaskGraph(&id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "task deleted"})
+		negotiation.Render(c, http.StatusOK, graph)
 	})
 
-	r.PUT("/tasks/:id/complete", func(c *gin.Context) {
+	// GET /tasks/:id/subtasks - Get the direct subtasks of a task
+	r.GET("/tasks/:id/subtasks", func(c *gin.Context) {
 		id, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
 			return
 		}
-		task, err := MarkTaskAsCompleted(id)
+		subtasks, err := sandboxOf(c).GetSubtasks(id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, task)
+		negotiation.Render(c, http.StatusOK, subtasks)
+	})
+
+	// PUT /tasks/:id/parent - Make a task a subtask of another task, or a top-level task
+	r.PUT("/tasks/:id/parent", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		var request ParentRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		task, err := sandboxOf(c).SetParent(id, request.ParentID)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, task)
+	})
+
+	// GET /tasks/:id/dependencies - Get the tasks blocking a task and the tasks it blocks
+	r.GET("/tasks/:id/dependencies", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		dependencies, err := sandboxOf(c).GetDependencies(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, dependencies)
+	})
+
+	// POST /tasks/:id/dependencies - Record that a task is blocked by another task
+	r.POST("/tasks/:id/dependencies", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		var request DependencyRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		task, err := sandboxOf(c).AddDependency(id, request.TaskID)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, task)
+	})
+
+	// DELETE /tasks/:id/dependencies/:blocker_id - Record that a task is no longer blocked by another task
+	r.DELETE("/tasks/:id/dependencies/:blocker_id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		blockerID, err := strconv.Atoi(c.Param("blocker_id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		task, err := sandboxOf(c).RemoveDependency(id, blockerID)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, task)
+	})
+
+	// GET /tasks/:id/occurrences - Get the dates a recurring task falls on, from its own due date on
+	r.GET("/tasks/:id/occurrences", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultOccurrencesLimit)))
+		if err != nil || limit < 1 || limit > MaxOccurrencesLimit {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": errInvalidOccurrences.Error()})
+			return
+		}
+		occurrences, err := sandboxOf(c).GetOccurrences(id, limit)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, occurrences)
+	})
+
+	// GET /tasks/:id/history - Get the activity of a task, oldest first
+	r.GET("/tasks/:id/history", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		history, err := sandboxOf(c).GetHistory(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, history)
+	})
+
+	// GET /tasks/:id/comments - Get the comments on a task, oldest first
+	r.GET("/tasks/:id/comments", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		comments, err := sandboxOf(c).GetComments(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, comments)
+	})
+
+	// POST /tasks/:id/comments - Comment on a task as the user named by X-User
+	r.POST("/tasks/:id/comments", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		var request CommentRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		comment, err := sandboxOf(c).AddComment(id, request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, comment)
+	})
+
+	// PUT /tasks/:id/comments/:comment_id - Edit a comment of the user named by X-User
+	r.PUT("/tasks/:id/comments/:comment_id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		commentID, err := strconv.Atoi(c.Param("comment_id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid comment ID"})
+			return
+		}
+		var request CommentRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		comment, err := sandboxOf(c).EditComment(id, commentID, request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, comment)
+	})
+
+	// DELETE /tasks/:id/comments/:comment_id - Delete a comment of the user named by X-User
+	r.DELETE("/tasks/:id/comments/:comment_id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		commentID, err := strconv.Atoi(c.Param("comment_id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid comment ID"})
+			return
+		}
+		if err := sandboxOf(c).DeleteComment(id, commentID); err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, gin.H{"message": "comment deleted"})
+	})
+
+	// GET /comments - Get the comments on every task, such as those mentioning a user with ?mention=alice
+	r.GET("/comments", func(c *gin.Context) {
+		query := CommentQuery{Mention: c.Query("mention"), Author: c.Query("author")}
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).FindComments(query))
+	})
+
+	// PATCH /tasks/:id/position - Move a task to a position of a board column
+	r.PATCH("/tasks/:id/position", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		var request PositionRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		task, err := sandboxOf(c).MoveTask(id, request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, task)
+	})
+
+	// DELETE /tasks/:id/position - Take a task off its board
+	r.DELETE("/tasks/:id/position", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		task, err := sandboxOf(c).RemoveTaskFromBoard(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, task)
+	})
+
+	// POST /projects - Create a project
+	r.POST("/projects", idempotencyKeys.Middleware(), func(c *gin.Context) {
+		var newProject Project
+		if err := negotiation.Bind(c, &newProject); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		project, err := sandboxOf(c).CreateProject(newProject)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, project)
+	})
+
+	// GET /projects - Get the projects, including the archived ones with include_archived=true
+	r.GET("/projects", func(c *gin.Context) {
+		includeArchived, err := parseBoolParam(c.Query("include_archived"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": errInvalidIncludeArchived.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).GetProjects(includeArchived))
+	})
+
+	r.GET("/projects/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+			return
+		}
+		project, err := sandboxOf(c).GetProject(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, project)
+	})
+
+	r.PUT("/projects/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+			return
+		}
+		var updatedProject Project
+		if err := negotiation.Bind(c, &updatedProject); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		project, err := sandboxOf(c).UpdateProject(id, updatedProject)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, project)
+	})
+
+	// PUT /projects/:id/archive and /unarchive - Make a project read-only, or editable again
+	r.PUT("/projects/:id/archive", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+			return
+		}
+		project, err := sandboxOf(c).ArchiveProject(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, project)
+	})
+
+	r.PUT("/projects/:id/unarchive", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+			return
+		}
+		project, err := sandboxOf(c).UnarchiveProject(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, project)
+	})
+
+	// GET /projects/:id/boards - Get the boards of a project
+	r.GET("/projects/:id/boards", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+			return
+		}
+		boards, err := sandboxOf(c).GetBoards(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, boards)
+	})
+
+	// POST /projects/:id/boards - Create a board in a project
+	r.POST("/projects/:id/boards", idempotencyKeys.Middleware(), func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+			return
+		}
+		var request BoardRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		board, err := sandboxOf(c).CreateBoard(id, request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, board)
+	})
+
+	// GET /boards/:id - Get a board with the tasks of each of its columns
+	r.GET("/boards/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid board ID"})
+			return
+		}
+		board, err := sandboxOf(c).GetBoard(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, board)
+	})
+
+	// POST /boards/:id/columns - Add a column to a board
+	r.POST("/boards/:id/columns", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid board ID"})
+			return
+		}
+		var request ColumnRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		board, err := sandboxOf(c).AddColumn(id, request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, board)
+	})
+
+	// PATCH /boards/:id/columns/:column_id - Rename a column or move it to another position
+	r.PATCH("/boards/:id/columns/:column_id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid board ID"})
+			return
+		}
+		columnID, err := strconv.Atoi(c.Param("column_id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid column ID"})
+			return
+		}
+		var request ColumnRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		board, err := sandboxOf(c).UpdateColumn(id, columnID, request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, board)
+	})
+
+	// DELETE /boards/:id/columns/:column_id - Remove an empty column from a board
+	r.DELETE("/boards/:id/columns/:column_id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid board ID"})
+			return
+		}
+		columnID, err := strconv.Atoi(c.Param("column_id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid column ID"})
+			return
+		}
+		board, err := sandboxOf(c).DeleteColumn(id, columnID)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, board)
+	})
+
+	// POST /tasks/:id/timer/start - Start a timer on a task
+	r.POST("/tasks/:id/timer/start", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		entry, err := sandboxOf(c).StartTimer(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, entry)
+	})
+
+	// POST /tasks/:id/timer/stop - Stop the timer running on a task
+	r.POST("/tasks/:id/timer/stop", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		entry, err := sandboxOf(c).StopTimer(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, entry)
+	})
+
+	// GET /tasks/:id/time-entries - Get the time entries of a task
+	r.GET("/tasks/:id/time-entries", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		entries, err := sandboxOf(c).GetTimeEntries(id, c.Query("user"))
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, entries)
+	})
+
+	// POST /tasks/:id/time-entries - Record time spent on a task
+	r.POST("/tasks/:id/time-entries", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		var request TimeEntryRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		entry, err := sandboxOf(c).AddTimeEntry(id, request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, entry)
+	})
+
+	// PUT /tasks/:id/time-entries/:entry_id - Change a time entry
+	r.PUT("/tasks/:id/time-entries/:entry_id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		entryID, err := strconv.Atoi(c.Param("entry_id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid time entry ID"})
+			return
+		}
+		var request TimeEntryRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		entry, err := sandboxOf(c).UpdateTimeEntry(id, entryID, request)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, entry)
+	})
+
+	// DELETE /tasks/:id/time-entries/:entry_id - Delete a time entry
+	r.DELETE("/tasks/:id/time-entries/:entry_id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		entryID, err := strconv.Atoi(c.Param("entry_id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid time entry ID"})
+			return
+		}
+		if err := sandboxOf(c).DeleteTimeEntry(id, entryID); err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, gin.H{"message": "time entry deleted"})
+	})
+
+	// GET /me/timer - Get the running timer of the user of the request
+	r.GET("/me/timer", func(c *gin.Context) {
+		entry, err := sandboxOf(c).GetMyTimer()
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, entry)
+	})
+
+	// GET /reports/time - Get the time spent per task, project or day
+	r.GET("/reports/time", func(c *gin.Context) {
+		query, err := ParseTimeReportQuery(c.Request.URL.Query())
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).GetTimeReport(query))
+	})
+
+	// POST /users - Register a user and get the token authenticating its requests
+	r.POST("/users", func(c *gin.Context) {
+		var request User
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user, err := sandboxOf(c).CreateUser(request.Username)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusCreated, user)
+	})
+
+	// GET /users - Get the users, without their tokens
+	r.GET("/users", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).GetUsers())
+	})
+
+	// GET /me - Get the authenticated user
+	r.GET("/me", func(c *gin.Context) {
+		user, err := sandboxOf(c).GetMe()
+		if renderAccessError(c, err) {
+			return
+		}
+		negotiation.Render(c, http.StatusOK, user)
+	})
+
+	// GET /me/tasks - Get the tasks the authenticated user owns or is assigned to, filtered, sorted and paginated
+	r.GET("/me/tasks", func(c *gin.Context) {
+		query, err := ParseTaskQuery(c.Request.URL.Query())
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		page, err := pagination.Parse(c.Query("limit"), c.Query("offset"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tasks, err := sandboxOf(c).GetMyTasks(c.Query("role"), query)
+		if renderAccessError(c, err) {
+			return
+		}
+		if errors.Is(err, errNoTasks) {
+			negotiation.RenderEmpty(c, http.StatusOK, []Task{}, gin.H{"message": err.Error()})
+			return
+		}
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, pagination.Apply(c, tasks, page))
+	})
+
+	// PUT /tasks/:id/assignees - Replace the assignees of a task
+	r.PUT("/tasks/:id/assignees", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		var request AssigneesRequest
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		task, err := sandboxOf(c).SetAssignees(id, request)
+		if renderAccessError(c, err) {
+			return
+		}
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, task)
+	})
+
+	// GET /admin/authorization - Get how tasks a caller is not allowed to use are answered
+	r.GET("/admin/authorization", func(c *gin.Context) {
+		negotiation.Render(c, http.StatusOK, sandboxOf(c).GetPolicy())
+	})
+
+	// PUT /admin/authorization - Answer tasks a caller is not allowed to use with 404 or 403.
+	// Like /admin/clock it configures the sandbox of the tenant rather than
+	// acting on a user's tasks, so any caller of the tenant can change it:
+	// tenants, not users, are what keeps one tester's setup from another's.
+	r.PUT("/admin/authorization", func(c *gin.Context) {
+		var request AuthorizationPolicy
+		if err := negotiation.Bind(c, &request); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		policy, err := sandboxOf(c).SetPolicy(request)
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, policy)
+	})
+
+	// /webhooks - Subscriptions to task events, with their delivery logs.
+	// Like /admin/authorization they configure the sandbox of the tenant, so
+	// they need no token and receive the events of owned tasks too.
+	webhooks.Register(r, idempotencyKeys, func(c *gin.Context) *webhooks.Dispatcher {
+		return sandboxOf(c).dispatcher
+	})
+
+	// /admin/clock - The clock of the tenant, which can be frozen, set and advanced
+	clock.Register(r, func(c *gin.Context) *clock.Clock {
+		return sandboxOf(c).clock
 	})
 
 	return r
 }
+
+// transitionHandler serves an endpoint that moves a task to another status
+func transitionHandler(transition func(sb *sandbox, id int) (Task, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+			return
+		}
+		task, err := transition(sandboxOf(c), id)
+		if renderMoveError(c, err) || renderAccessError(c, err) {
+			return
+		}
+		if err != nil {
+			negotiation.Render(c, http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, task)
+	}
+}
+
+// taskFileHandler serves the tasks matching the filters of GET /tasks as a
+// file to download, encoded by format
+func taskFileHandler(mediaType string, filename string, format func(sb *sandbox, tasks []Task) ([]byte, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := negotiation.Negotiate(c.GetHeader("Accept"), mediaType); err != nil {
+			negotiation.Render(c, http.StatusNotAcceptable, gin.H{
+				"error":     "none of the requested media types are supported",
+				"supported": []string{mediaType},
+			})
+			return
+		}
+		query, err := ParseTaskQuery(c.Request.URL.Query())
+		if err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sb := sandboxOf(c)
+		tasks, err := sb.GetTasks(query)
+		if err != nil {
+			tasks = []Task{}
+		}
+		body, err := format(sb, tasks)
+		if err != nil {
+			negotiation.Render(c, http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		c.Data(http.StatusOK, mediaType+"; charset=utf-8", body)
+	}
+}
+
+// readImportUpload returns the format and the content of the file of POST
+// /tasks/import, sent either as the body of the request or as the file field
+// of a multipart/form-data request, refusing files larger than MaxImportSize
+func readImportUpload(c *gin.Context) (string, []byte, error) {
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil {
+		return "", nil, errUnsupportedImport
+	}
+	if c.Request.ContentLength > MaxImportSize+multipartOverhead {
+		return "", nil, errImportTooLarge
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportSize+multipartOverhead)
+
+	var format, filename string
+	var file io.Reader = c.Request.Body
+	switch mediaType {
+	case MIMECSV, "application/csv":
+		format = MIMECSV
+	case MIMECalendar:
+		format = MIMECalendar
+	case "multipart/form-data":
+		header, err := c.FormFile("file")
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			return "", nil, errImportTooLarge
+		case errors.Is(err, http.ErrMissingFile):
+			return "", nil, errors.New("file is required")
+		case err != nil:
+			return "", nil, errors.New("invalid multipart payload")
+		}
+		upload, err := header.Open()
+		if err != nil {
+			return "", nil, errors.New("invalid multipart payload")
+		}
+		defer upload.Close()
+		file, filename = upload, header.Filename
+	default:
+		return "", nil, errUnsupportedImport
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxImportSize+1))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || len(data) > MaxImportSize {
+		return "", nil, errImportTooLarge
+	}
+	if err != nil {
+		return "", nil, errors.New("invalid payload")
+	}
+	if format == "" {
+		format = detectImportFormat(filename, data)
+	}
+	return format, data, nil
+}
+
+// statusForImportError maps the errors of POST /tasks/import to status codes
+func statusForImportError(err error) int {
+	switch {
+	case errors.Is(err, errUnsupportedImport):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errImportTooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// renderMoveError answers a move the lifecycle does not allow with 409
+// Conflict and the statuses the task can move to instead, and the completion
+// of a blocked task with 409 Conflict and its open blockers. It reports
+// whether err was one of them.
+func renderMoveError(c *gin.Context, err error) bool {
+	var transitionErr *TransitionError
+	var blockersErr *OpenBlockersError
+	switch {
+	case errors.As(err, &transitionErr):
+		negotiation.Render(c, http.StatusConflict, gin.H{"error": err.Error(), "allowed_transitions": transitionErr.Allowed})
+	case errors.As(err, &blockersErr):
+		negotiation.Render(c, http.StatusConflict, gin.H{"error": err.Error(), "open_blockers": blockersErr.Blockers})
+	default:
+		return false
+	}
+	return true
+}
+
+// renderAccessError answers a caller who is not allowed to use a task with
+// 403 Forbidden, or with 401 Unauthorized when it is not authenticated. It
+// reports whether err was one of them.
+func renderAccessError(c *gin.Context, err error) bool {
+	var forbiddenErr *ForbiddenError
+	switch {
+	case errors.As(err, &forbiddenErr):
+		negotiation.Render(c, http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, errAuthRequired):
+		c.Header("WWW-Authenticate", `Bearer realm="playpi"`)
+		negotiation.Render(c, http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
+
+// statusForError maps the errors of subtasks, dependencies, projects, boards,
+// comments, users and time entries to status codes
+func statusForError(err error) int {
+	var forbiddenErr *ForbiddenError
+	switch {
+	case errors.Is(err, errAuthRequired):
+		return http.StatusUnauthorized
+	case errors.As(err, &forbiddenErr):
+		return http.StatusForbidden
+	case errors.Is(err, errTaskNotFound), errors.Is(err, errDependencyNotFound), errors.Is(err, errTaskNotOnBoard),
+		errors.Is(err, errProjectNotFound), errors.Is(err, errBoardNotFound), errors.Is(err, errColumnNotFound),
+		errors.Is(err, errCommentNotFound), errors.Is(err, errTimeEntryNotFound), errors.Is(err, errNoTimer):
+		return http.StatusNotFound
+	case errors.Is(err, errCommentAuthor), errors.Is(err, errTimeEntryAuthor):
+		return http.StatusForbidden
+	case errors.Is(err, errParentCycle), errors.Is(err, errDependencyCycle), errors.Is(err, errDuplicateDependency),
+		errors.Is(err, errProjectArchived), errors.Is(err, errProjectNotArchived), errors.Is(err, errColumnNotEmpty),
+		errors.Is(err, errUserExists), errors.Is(err, errTimerRunning), errors.Is(err, errTimerNotRunning),
+		errors.Is(err, errTimeEntryOverlap), errors.Is(err, errTimeEntryRunning), errors.Is(err, errTimerClosedTask):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// parseIncludeDeletedParam reads the include_deleted query parameter, which defaults to false
+func parseIncludeDeletedParam(c *gin.Context) (bool, error) {
+	includeDeleted, err := strconv.ParseBool(c.DefaultQuery("include_deleted", "false"))
+	if err != nil {
+		return false, errInvalidIncludeDeleted
+	}
+	return includeDeleted, nil
+}
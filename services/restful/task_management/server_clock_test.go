@@ -0,0 +1,122 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock(t *testing.T) {
+	r := setupTestServer()
+	id := createTaskForTest(t, r)
+
+	request := func(method, url string, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	getTask := func(headers map[string]string) Task {
+		resp := request(http.MethodGet, "/tasks/"+strconv.Itoa(id), "", headers)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+		var task Task
+		err := json.Unmarshal(resp.Body.Bytes(), &task)
+		require.NoError(t, err)
+		return task
+	}
+
+	t.Run("Tasks become due when the clock passes their due date", func(t *testing.T) {
+		require.False(t, getTask(nil).Due)
+
+		resp := request(http.MethodPost, "/admin/clock/advance", `{"duration": "768h"}`, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"offset_seconds":2764800`)
+
+		require.True(t, getTask(nil).Due)
+	})
+
+	t.Run("Freeze and set the clock", func(t *testing.T) {
+		resp := request(http.MethodPost, "/admin/clock/freeze", "", nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"frozen":true`)
+
+		resp = request(http.MethodPost, "/admin/clock/set", `{"now": "2020-01-15T09:00:00Z"}`, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = request(http.MethodGet, "/admin/clock", "", nil)
+		require.Contains(t, resp.Body.String(), `"now":"2020-01-15T09:00:00Z"`)
+		require.False(t, getTask(nil).Due)
+
+		resp = request(http.MethodPost, "/tasks", `{"title": "Backdated", "due_date": "2020-01-20", "priority": "low"}`, nil)
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Contains(t, resp.Body.String(), `"created_at":"2020-01-15T09:00:00Z"`)
+
+		resp = request(http.MethodPost, "/admin/clock/set", `{"now": "0001-01-01"}`, nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"frozen":true`, "setting the zero time must not unfreeze the clock")
+		resp = request(http.MethodGet, "/admin/clock", "", nil)
+		require.Contains(t, resp.Body.String(), `"now":"0001-01-01T00:00:00Z"`)
+	})
+
+	t.Run("Reset the clock", func(t *testing.T) {
+		resp := request(http.MethodPost, "/admin/clock/reset", "", nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"frozen":false`)
+		require.Contains(t, resp.Body.String(), `"offset_seconds":0`)
+	})
+
+	t.Run("Override the time of a single request", func(t *testing.T) {
+		later := map[string]string{"X-PlayPI-Now": getFutureDate(60)}
+		require.True(t, getTask(later).Due)
+		require.False(t, getTask(nil).Due)
+	})
+
+	t.Run("Overriding the time does not purge the trash", func(t *testing.T) {
+		request(http.MethodDelete, "/tasks/"+strconv.Itoa(id), "", nil)
+
+		later := map[string]string{"X-PlayPI-Now": time.Now().Add(TrashRetention + time.Hour).Format(time.RFC3339)}
+		resp := request(http.MethodGet, "/tasks/trash", "", later)
+		require.Contains(t, resp.Body.String(), `"id":`+strconv.Itoa(id))
+
+		resp = request(http.MethodPost, "/tasks/"+strconv.Itoa(id)+"/restore", "", nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("Every tenant has its own clock", func(t *testing.T) {
+		alice := map[string]string{"X-Tenant-ID": "alice"}
+		request(http.MethodPost, "/admin/clock/advance", `{"duration": "48h"}`, alice)
+
+		resp := request(http.MethodGet, "/admin/clock", "", nil)
+		require.Contains(t, resp.Body.String(), `"offset_seconds":0`)
+	})
+
+	validationErrors := []struct {
+		url, body string
+		headers   map[string]string
+		message   string
+	}{
+		{"/admin/clock/set", `{"now": "tomorrow"}`, nil, "now must be an RFC 3339 timestamp such as 2030-01-15T09:00:00Z or a date such as 2030-01-15"},
+		{"/admin/clock/advance", `{"duration": "-1h"}`, nil, "duration must be a positive duration such as 90m or 48h"},
+		{"/admin/clock/advance", `{"duration": "soon"}`, nil, "duration must be a positive duration such as 90m or 48h"},
+		{"/admin/clock/freeze", "", map[string]string{"X-PlayPI-Now": "yesterday"}, "X-PlayPI-Now must be an RFC 3339 timestamp such as 2030-01-15T09:00:00Z or a date such as 2030-01-15"},
+	}
+	for _, test := range validationErrors {
+		t.Run("Validation Error - "+test.message, func(t *testing.T) {
+			resp := request(http.MethodPost, test.url, test.body, test.headers)
+
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+			require.Contains(t, resp.Body.String(), test.message)
+		})
+	}
+}
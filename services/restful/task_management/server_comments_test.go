@@ -0,0 +1,135 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentsAndHistory(t *testing.T) {
+	r := setupTestServer()
+
+	request := func(method, url string, body string, user string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.Header.Set(HeaderUser, user)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(resp *httptest.ResponseRecorder, target interface{}) {
+		err := json.Unmarshal(resp.Body.Bytes(), target)
+		require.NoError(t, err, resp.Body.String())
+	}
+	id := createTaskForTest(t, r)
+	tasks := "/tasks/" + strconv.Itoa(id)
+
+	t.Run("Comment with mentions", func(t *testing.T) {
+		resp := request(http.MethodPost, tasks+"/comments", `{"body": "@bob can you review this? cc @carol.smith and @bob, not alice@example.com."}`, "alice")
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var comment Comment
+		decode(resp, &comment)
+		require.Equal(t, "alice", comment.Author)
+		require.Equal(t, []string{"bob", "carol.smith"}, comment.Mentions)
+		require.Nil(t, comment.EditedAt)
+
+		resp = request(http.MethodPost, tasks+"/comments", `{"body": "On it"}`, "bob")
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		var comments []Comment
+		decode(request(http.MethodGet, tasks+"/comments", "", ""), &comments)
+		require.Len(t, comments, 2)
+		decode(request(http.MethodGet, "/comments?mention=carol.smith", "", ""), &comments)
+		require.Len(t, comments, 1)
+		require.Equal(t, "alice", comments[0].Author)
+		decode(request(http.MethodGet, "/comments?author=bob", "", ""), &comments)
+		require.Len(t, comments, 1)
+		require.Equal(t, "On it", comments[0].Body)
+	})
+
+	t.Run("Only authors edit and delete their comments", func(t *testing.T) {
+		resp := request(http.MethodPut, tasks+"/comments/1", `{"body": "Hijacked"}`, "bob")
+		require.Equal(t, http.StatusForbidden, resp.Code)
+		require.Contains(t, resp.Body.String(), "only the author of a comment can edit or delete it")
+		require.Equal(t, http.StatusForbidden, request(http.MethodDelete, tasks+"/comments/1", "", "bob").Code)
+
+		resp = request(http.MethodPut, tasks+"/comments/1", `{"body": "@dave can you review this?"}`, "alice")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var comment Comment
+		decode(resp, &comment)
+		require.Equal(t, []string{"dave"}, comment.Mentions)
+		require.NotNil(t, comment.EditedAt)
+
+		resp = request(http.MethodDelete, tasks+"/comments/2", "", "bob")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, http.StatusNotFound, request(http.MethodDelete, tasks+"/comments/2", "", "bob").Code)
+	})
+
+	t.Run("Record every change in the history", func(t *testing.T) {
+		payload := `{"title": "Renamed task", "description": "Description for update test", "due_date": "` + getFutureDate(30) + `", "priority": "high"}`
+		require.Equal(t, http.StatusOK, request(http.MethodPut, tasks, payload, "alice").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodPut, tasks+"/complete", "", "bob").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodDelete, tasks, "", "carol").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodPost, tasks+"/restore", "", "").Code)
+
+		resp := request(http.MethodGet, tasks+"/history", "", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var history []Activity
+		decode(resp, &history)
+
+		actions := []string{}
+		for _, activity := range history {
+			actions = append(actions, activity.Action+" by "+activity.Actor)
+		}
+		require.Equal(t, []string{
+			"created by ",
+			"commented by alice",
+			"commented by bob",
+			"comment_edited by alice",
+			"comment_deleted by bob",
+			"updated by alice",
+			"updated by bob",
+			"deleted by carol",
+			"restored by ",
+		}, actions)
+
+		require.Contains(t, history[0].Changes, FieldChange{Field: "title", After: "Task for Update Test"})
+		require.Equal(t, 1, *history[3].CommentID)
+		require.Equal(t, []FieldChange{
+			{Field: "title", Before: "Task for Update Test", After: "Renamed task"},
+			{Field: "priority", Before: "medium", After: "high"},
+		}, history[5].Changes)
+		require.Equal(t, []FieldChange{{Field: "status", Before: StatusPending, After: StatusCompleted}}, history[6].Changes)
+		require.Equal(t, "deleted_at", history[7].Changes[0].Field)
+		require.Equal(t, history[7].Changes[0].After, history[8].Changes[0].Before)
+	})
+
+	validationErrors := []struct {
+		method, url, body, user string
+		status                  int
+		message                 string
+	}{
+		{http.MethodPost, tasks + "/comments", `{"body": "Anonymous"}`, "", http.StatusBadRequest, "comments need an X-User header naming their author"},
+		{http.MethodPost, tasks + "/comments", `{"body": ""}`, "alice", http.StatusBadRequest, "comment must be between 1 and 1000 characters"},
+		{http.MethodPost, tasks + "/comments", `{"body": "` + generateLongString(1001) + `"}`, "alice", http.StatusBadRequest, "comment must be between 1 and 1000 characters"},
+		{http.MethodPost, "/tasks/99/comments", `{"body": "Lost"}`, "alice", http.StatusNotFound, "task not found"},
+		{http.MethodGet, tasks, "", "alice smith", http.StatusBadRequest, "X-User must be 1 to 64 letters, digits, dots, dashes or underscores"},
+		{http.MethodPut, tasks + "/comments/99", `{"body": "Lost"}`, "alice", http.StatusNotFound, "comment not found"},
+		{http.MethodGet, "/tasks/99/history", "", "", http.StatusNotFound, "task not found"},
+	}
+	for _, test := range validationErrors {
+		t.Run("Validation Error - "+test.message, func(t *testing.T) {
+			resp := request(test.method, test.url, test.body, test.user)
+
+			require.Equal(t, test.status, resp.Code)
+			require.Contains(t, resp.Body.String(), test.message)
+		})
+	}
+}
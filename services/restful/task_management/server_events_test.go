@@ -0,0 +1,50 @@
+package task_management
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskEvents(t *testing.T) {
+	r := setupTestServer()
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	request := func(method, url, body string) {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	request(http.MethodPost, "/tasks", `{"title": "Write report", "due_date": "`+getFutureDate(3)+`", "priority": "high"}`)
+	request(http.MethodPut, "/tasks/1/complete", "")
+	request(http.MethodDelete, "/tasks/1", "")
+
+	t.Run("Resume after the last event received", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/tasks/events", nil)
+		req.Header.Set("Last-Event-ID", "1")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		reader := bufio.NewReader(resp.Body)
+		var lines []string
+		for len(lines) < 4 {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if strings.HasPrefix(line, "id: ") || strings.HasPrefix(line, "event: ") {
+				lines = append(lines, strings.TrimSpace(line))
+			}
+		}
+		require.Equal(t, []string{"id: 2", "event: task.updated", "id: 3", "event: task.deleted"}, lines)
+	})
+}
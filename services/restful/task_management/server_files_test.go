@@ -0,0 +1,150 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportAndExportTasks(t *testing.T) {
+	r := setupTestServer()
+
+	request := func(method, url string, body []byte, contentType string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(resp *httptest.ResponseRecorder, target interface{}) {
+		err := json.Unmarshal(resp.Body.Bytes(), target)
+		require.NoError(t, err, resp.Body.String())
+	}
+	due := getFutureDate(10)
+	payload := `{"title": "Plan the release", "description": "Dates, owners; and a checklist\nfor everyone involved in shipping the next release of the product", "due_date": "` + due + `", "priority": "high"}`
+	require.Equal(t, http.StatusCreated, request(http.MethodPost, "/tasks", []byte(payload), "application/json").Code)
+	payload = `{"title": "Water the plants", "due_date": "` + due + `", "priority": "low", "rrule": "FREQ=WEEKLY;BYDAY=MO"}`
+	require.Equal(t, http.StatusCreated, request(http.MethodPost, "/tasks", []byte(payload), "application/json").Code)
+
+	var ics, tasksCSV []byte
+	t.Run("Export tasks as an iCalendar file", func(t *testing.T) {
+		resp := request(http.MethodGet, "/tasks.ics", nil, "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "text/calendar; charset=utf-8", resp.Header().Get("Content-Type"))
+		require.Equal(t, "attachment; filename=tasks.ics", resp.Header().Get("Content-Disposition"))
+		ics = resp.Body.Bytes()
+
+		body := resp.Body.String()
+		require.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+		require.Equal(t, 2, strings.Count(body, "BEGIN:VTODO\r\n"))
+		require.Contains(t, body, "UID:task-1@playpi\r\n")
+		require.Contains(t, body, "DUE;VALUE=DATE:"+strings.ReplaceAll(due, "-", "")+"\r\n")
+		require.Contains(t, body, "PRIORITY:1\r\n")
+		require.Contains(t, body, "STATUS:NEEDS-ACTION\r\n")
+		require.Contains(t, body, "RRULE:FREQ=WEEKLY;BYDAY=MO\r\n")
+		require.Contains(t, body, `DESCRIPTION:Dates\, owners\; and a checklist\nfor everyone`)
+		for _, line := range strings.Split(body, "\r\n") {
+			require.LessOrEqual(t, len(line), 75, line)
+		}
+
+		resp = request(http.MethodGet, "/tasks.ics?priority=low", nil, "")
+		require.Equal(t, 1, strings.Count(resp.Body.String(), "BEGIN:VTODO"))
+
+		req, _ := http.NewRequest(http.MethodGet, "/tasks.ics", nil)
+		req.Header.Set("Accept", "application/json")
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusNotAcceptable, resp.Code)
+	})
+
+	t.Run("Export tasks as a CSV file", func(t *testing.T) {
+		resp := request(http.MethodGet, "/tasks.csv", nil, "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "attachment; filename=tasks.csv", resp.Header().Get("Content-Disposition"))
+		tasksCSV = resp.Body.Bytes()
+		lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+		require.Equal(t, "id,title,description,due_date,priority,status,rrule,created_at", lines[0])
+		require.Contains(t, lines[len(lines)-1], ",Water the plants,,"+due+",low,pending,FREQ=WEEKLY;BYDAY=MO,")
+	})
+
+	t.Run("Import the exported files", func(t *testing.T) {
+		for _, file := range []struct {
+			format      string
+			contentType string
+			data        []byte
+		}{{"csv", "text/csv", tasksCSV}, {"ics", "text/calendar", ics}} {
+			resp := request(http.MethodPost, "/tasks/import", file.data, file.contentType)
+			require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+			var result ImportResult
+			decode(resp, &result)
+			require.Equal(t, file.format, result.Format)
+			require.Equal(t, 2, result.Created)
+			require.Equal(t, 0, result.Failed)
+
+			var task Task
+			decode(request(http.MethodGet, "/tasks/"+strconv.Itoa(*result.Rows[0].TaskID), nil, ""), &task)
+			require.Equal(t, "Dates, owners; and a checklist\nfor everyone involved in shipping the next release of the product", task.Description)
+			require.Equal(t, "high", task.Priority)
+			decode(request(http.MethodGet, "/tasks/"+strconv.Itoa(*result.Rows[1].TaskID), nil, ""), &task)
+			require.Equal(t, "FREQ=WEEKLY;BYDAY=MO", task.RRule)
+		}
+	})
+
+	t.Run("Report every row of an import", func(t *testing.T) {
+		file := "Title,Due_Date,Priority,Notes\n" +
+			"Valid task," + due + ",medium,ignored\n" +
+			"Past task,2000-01-01,low,\n" +
+			"Odd priority," + due + ",urgent,\n" +
+			"Too short\n"
+		resp := request(http.MethodPost, "/tasks/import", []byte(file), "text/csv")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var result ImportResult
+		decode(resp, &result)
+		require.Equal(t, 1, result.Created)
+		require.Equal(t, 3, result.Failed)
+		require.NotNil(t, result.Rows[0].TaskID)
+		require.Equal(t, "due date cannot be in the past", result.Rows[1].Error)
+		require.Equal(t, "priority must be one of: low, medium, high", result.Rows[2].Error)
+		require.Equal(t, "row has 1 fields but the header has 4", result.Rows[3].Error)
+		require.Equal(t, 4, result.Rows[3].Row)
+
+		calendar := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:From a calendar\r\nDUE:" + strings.ReplaceAll(due, "-", "") + "T090000Z\r\n" +
+			"BEGIN:VALARM\r\nSUMMARY:Not a task\r\nEND:VALARM\r\nEND:VTODO\r\nBEGIN:VTODO\r\nSUMMARY:No due date\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+		decode(request(http.MethodPost, "/tasks/import", []byte(calendar), "text/calendar"), &result)
+		require.Equal(t, 1, result.Created)
+		require.Equal(t, "From a calendar", result.Rows[0].Title)
+		require.Equal(t, "due date must follow the format YYYY-MM-DD", result.Rows[1].Error)
+	})
+
+	t.Run("Upload a file to import", func(t *testing.T) {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", "tasks.ics")
+		part.Write(ics)
+		writer.Close()
+		resp := request(http.MethodPost, "/tasks/import", body.Bytes(), writer.FormDataContentType())
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		var result ImportResult
+		decode(resp, &result)
+		require.Equal(t, "ics", result.Format)
+		require.Equal(t, 2, result.Created)
+	})
+
+	t.Run("Reject files that cannot be imported", func(t *testing.T) {
+		resp := request(http.MethodPost, "/tasks/import", []byte("name,when\nTask,tomorrow\n"), "text/csv")
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "CSV header must have the columns title, due_date and priority")
+		require.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/tasks/import", []byte("BEGIN:VTODO\r\nEND:VTODO\r\n"), "text/calendar").Code)
+		require.Equal(t, http.StatusUnsupportedMediaType, request(http.MethodPost, "/tasks/import", []byte(`{"title": "Task"}`), "application/json").Code)
+		require.Equal(t, http.StatusRequestEntityTooLarge, request(http.MethodPost, "/tasks/import", bytes.Repeat([]byte("a"), MaxImportSize+1), "text/csv").Code)
+	})
+}
@@ -0,0 +1,47 @@
+package task_management
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotentCreateTask(t *testing.T) {
+	r := setupTestServer()
+	payload := `{
+		"title": "Idempotent Task",
+		"due_date": "` + getFutureDate(5) + `",
+		"priority": "low"
+	}`
+
+	createWithKey := func(key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Retry with the same key does not create a duplicate", func(t *testing.T) {
+		first := createWithKey("task-key-1", payload)
+		retry := createWithKey("task-key-1", payload)
+
+		require.Equal(t, http.StatusCreated, first.Code)
+		require.Equal(t, http.StatusCreated, retry.Code)
+		require.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		require.Equal(t, first.Body.String(), retry.Body.String())
+		require.Len(t, defaultSandbox().tasks, 1)
+	})
+
+	t.Run("Same key with a different payload is rejected", func(t *testing.T) {
+		resp := createWithKey("task-key-1", strings.Replace(payload, "Idempotent Task", "Another Task", 1))
+
+		require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		require.Len(t, defaultSandbox().tasks, 1)
+	})
+}
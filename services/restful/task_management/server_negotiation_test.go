@@ -0,0 +1,84 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskContentNegotiation(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Get no tasks as CSV", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
+		req.Header.Set("Accept", "text/csv")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "text/csv")
+		require.Equal(t, "id,title,description,due_date,priority,status,created_at,due", strings.TrimSpace(resp.Body.String()))
+	})
+
+	createTaskForTest(t, r)
+
+	t.Run("Get tasks as CSV", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
+		req.Header.Set("Accept", "text/csv")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "text/csv")
+
+		lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		require.Equal(t, "id,title,description,due_date,priority,status,created_at,due", lines[0])
+		require.True(t, strings.HasPrefix(lines[1], "1,Task for Update Test,"))
+	})
+
+	t.Run("Get a task as XML", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/tasks/1", nil)
+		req.Header.Set("Accept", "text/xml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var task Task
+		err := xml.Unmarshal(resp.Body.Bytes(), &task)
+		require.NoError(t, err)
+		require.Equal(t, "Task for Update Test", task.Title)
+		require.Equal(t, "pending", task.Status)
+	})
+
+	t.Run("Create a task with a YAML body", func(t *testing.T) {
+		payload := "title: YAML Task\ndue_date: \"" + getFutureDate(10) + "\"\npriority: low\n"
+		req, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/x-yaml")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		var task Task
+		err := json.Unmarshal(resp.Body.Bytes(), &task)
+		require.NoError(t, err)
+		require.Equal(t, "YAML Task", task.Title)
+	})
+
+	t.Run("Not Acceptable for CSV on a single task", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/tasks/1", nil)
+		req.Header.Set("Accept", "text/csv")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusNotAcceptable, resp.Code)
+	})
+}
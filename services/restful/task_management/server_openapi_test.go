@@ -0,0 +1,61 @@
+package task_management
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abhivaikar/playpi/services/restful/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	r := setupTestServer()
+
+	t.Run("Every route is documented", func(t *testing.T) {
+		doc, err := openapi.Parse(openAPISpec)
+		require.NoError(t, err)
+
+		for _, route := range r.Routes() {
+			if route.Path == "/openapi.json" || route.Path == "/docs" || route.Path == openapi.AssetsRoute {
+				continue
+			}
+			_, ok := doc.Operation(route.Method, route.Path)
+			require.True(t, ok, "%s %s is missing from openapi.json", route.Method, route.Path)
+		}
+	})
+
+	t.Run("Serve the Swagger UI and its assets", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/docs", nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `<div id="swagger-ui"></div>`)
+
+		req, _ = http.NewRequest(http.MethodGet, "/docs/swagger-ui-bundle.js", nil)
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Header().Get("Content-Type"), "javascript")
+		require.Contains(t, resp.Body.String(), "SwaggerUIBundle")
+	})
+
+	t.Run("Validate requests against the document", func(t *testing.T) {
+		defer func() { ValidateRequests = false }()
+		ValidateRequests = true
+		r := setupTestServer()
+
+		payload := `{"title": "Task", "due_date": "next week", "priority": "urgent"}`
+		req, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "body.due_date must be a valid date")
+		require.Contains(t, resp.Body.String(), "body.priority must be one of low, medium, high")
+	})
+}
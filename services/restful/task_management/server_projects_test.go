@@ -0,0 +1,200 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectsAndBoards(t *testing.T) {
+	r := setupTestServer()
+
+	request := func(method, url string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(resp *httptest.ResponseRecorder, target interface{}) {
+		err := json.Unmarshal(resp.Body.Bytes(), target)
+		require.NoError(t, err, resp.Body.String())
+	}
+	taskIDs := func(board Board) [][]int {
+		columns := [][]int{}
+		for _, column := range board.Columns {
+			columns = append(columns, column.TaskIDs)
+		}
+		return columns
+	}
+	for i := 0; i < 4; i++ {
+		createTaskForTest(t, r)
+	}
+
+	t.Run("Create a project and a board", func(t *testing.T) {
+		resp := request(http.MethodPost, "/projects", `{"name": "Website", "description": "The new website"}`)
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var project Project
+		decode(resp, &project)
+		require.Equal(t, 1, project.ID)
+		require.False(t, project.Archived)
+
+		resp = request(http.MethodPost, "/projects/1/boards", `{"name": "Sprint 1"}`)
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var board Board
+		decode(resp, &board)
+		require.Equal(t, 1, board.ProjectID)
+		require.Len(t, board.Columns, 3)
+		for i, name := range []string{"To do", "In progress", "Done"} {
+			require.Equal(t, name, board.Columns[i].Name)
+			require.Equal(t, i, board.Columns[i].Position)
+			require.Equal(t, i+1, board.Columns[i].ID)
+		}
+
+		resp = request(http.MethodGet, "/projects/1/boards", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var boards []Board
+		decode(resp, &boards)
+		require.Len(t, boards, 1)
+	})
+
+	t.Run("Move tasks between columns", func(t *testing.T) {
+		for _, id := range []string{"1", "2", "3"} {
+			resp := request(http.MethodPatch, "/tasks/"+id+"/position", `{"column_id": 1}`)
+			require.Equal(t, http.StatusOK, resp.Code)
+		}
+
+		resp := request(http.MethodPatch, "/tasks/3/position", `{"column_id": 1, "position": 0}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		var task Task
+		decode(resp, &task)
+		require.Equal(t, 1, *task.BoardID)
+		require.Equal(t, 1, *task.ColumnID)
+		require.Equal(t, 0, *task.Position)
+
+		resp = request(http.MethodPatch, "/tasks/1/position", `{"column_id": 2, "position": 0}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = request(http.MethodGet, "/boards/1", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var board Board
+		decode(resp, &board)
+		require.Equal(t, [][]int{{3, 2}, {1}, {}}, taskIDs(board))
+		require.Equal(t, 2, board.Columns[0].Tasks[1].ID)
+		require.Equal(t, 1, *board.Columns[0].Tasks[1].Position)
+
+		resp = request(http.MethodGet, "/tasks/2", "")
+		decode(resp, &task)
+		require.Equal(t, 1, *task.Position)
+	})
+
+	t.Run("Reorder columns", func(t *testing.T) {
+		columnOf := func(id string) int {
+			var task Task
+			decode(request(http.MethodGet, "/tasks/"+id, ""), &task)
+			return *task.ColumnID
+		}
+
+		resp := request(http.MethodPost, "/boards/1/columns", `{"name": "Review", "position": 2}`)
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var board Board
+		decode(resp, &board)
+		require.Equal(t, "Review", board.Columns[2].Name)
+		require.Equal(t, 2, board.Columns[2].Position)
+		require.Equal(t, 3, board.Columns[3].Position)
+
+		resp = request(http.MethodPatch, "/boards/1/columns/4", `{"name": "Code review", "position": 0}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		decode(resp, &board)
+		names := []string{}
+		for i, column := range board.Columns {
+			names = append(names, column.Name)
+			require.Equal(t, i, column.Position)
+		}
+		require.Equal(t, []string{"Code review", "To do", "In progress", "Done"}, names)
+		require.Equal(t, 1, columnOf("2"))
+		require.Equal(t, 2, columnOf("1"))
+
+		resp = request(http.MethodDelete, "/boards/1/columns/1", "")
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "column still has tasks")
+
+		resp = request(http.MethodDelete, "/boards/1/columns/4", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		decode(resp, &board)
+		require.Len(t, board.Columns, 3)
+		require.Equal(t, 0, board.Columns[0].Position)
+		require.Equal(t, 1, columnOf("2"))
+		require.Equal(t, 2, columnOf("1"))
+	})
+
+	t.Run("Deleted tasks leave their column", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, request(http.MethodDelete, "/tasks/3", "").Code)
+		var board Board
+		decode(request(http.MethodGet, "/boards/1", ""), &board)
+		require.Equal(t, [][]int{{2}, {1}, {}}, taskIDs(board))
+
+		require.Equal(t, http.StatusOK, request(http.MethodPost, "/tasks/3/restore", "").Code)
+		decode(request(http.MethodGet, "/boards/1", ""), &board)
+		require.Equal(t, [][]int{{2, 3}, {1}, {}}, taskIDs(board))
+
+		resp := request(http.MethodDelete, "/tasks/1/position", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NotContains(t, resp.Body.String(), "column_id")
+	})
+
+	t.Run("Archive a project", func(t *testing.T) {
+		resp := request(http.MethodPut, "/projects/1/archive", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var project Project
+		decode(resp, &project)
+		require.True(t, project.Archived)
+		require.NotNil(t, project.ArchivedAt)
+
+		var projects []Project
+		decode(request(http.MethodGet, "/projects", ""), &projects)
+		require.Empty(t, projects)
+		decode(request(http.MethodGet, "/projects?include_archived=true", ""), &projects)
+		require.Len(t, projects, 1)
+
+		resp = request(http.MethodPatch, "/tasks/4/position", `{"column_id": 1}`)
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "project is archived")
+		resp = request(http.MethodPost, "/boards/1/columns", `{"name": "Later"}`)
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Equal(t, http.StatusOK, request(http.MethodGet, "/boards/1", "").Code)
+
+		require.Equal(t, http.StatusOK, request(http.MethodPut, "/projects/1/unarchive", "").Code)
+		require.Equal(t, http.StatusConflict, request(http.MethodPut, "/projects/1/unarchive", "").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodPatch, "/tasks/4/position", `{"column_id": 1}`).Code)
+	})
+
+	validationErrors := []struct {
+		method, url, body string
+		status            int
+		message           string
+	}{
+		{http.MethodPost, "/projects", `{"name": "X"}`, http.StatusBadRequest, "name must be between 3 and 100 characters"},
+		{http.MethodGet, "/projects?include_archived=maybe", "", http.StatusBadRequest, "include_archived must be true or false"},
+		{http.MethodGet, "/projects/99", "", http.StatusNotFound, "project not found"},
+		{http.MethodPost, "/projects/1/boards", `{"name": "Empty columns", "columns": [""]}`, http.StatusBadRequest, "column name must be between 1 and 50 characters"},
+		{http.MethodGet, "/boards/99", "", http.StatusNotFound, "board not found"},
+		{http.MethodPatch, "/boards/1/columns/99", `{"name": "Gone"}`, http.StatusNotFound, "column not found"},
+		{http.MethodPatch, "/boards/1/columns/1", `{"position": 3}`, http.StatusBadRequest, "position must be between 0 and 2"},
+		{http.MethodPatch, "/tasks/2/position", `{"column_id": 99}`, http.StatusNotFound, "column not found"},
+		{http.MethodPatch, "/tasks/2/position", `{"column_id": 2, "position": 1}`, http.StatusBadRequest, "position must be between 0 and 0"},
+		{http.MethodDelete, "/tasks/1/position", "", http.StatusNotFound, "task is not on a board"},
+	}
+	for _, test := range validationErrors {
+		t.Run("Validation Error - "+test.message, func(t *testing.T) {
+			resp := request(test.method, test.url, test.body)
+
+			require.Equal(t, test.status, resp.Code)
+			require.Contains(t, resp.Body.String(), test.message)
+		})
+	}
+}
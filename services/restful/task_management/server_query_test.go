@@ -0,0 +1,119 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abhivaikar/playpi/services/restful/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterSortAndPaginateTasks(t *testing.T) {
+	r := setupTestServer()
+
+	request := func(method, url string, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	for _, task := range []struct {
+		title, description, priority string
+		days                         int
+	}{
+		{"Write report", "Quarterly numbers", "high", 10},
+		{"Review pull request", "Report generator changes", "medium", 3},
+		{"Plan sprint", "", "low", 20},
+		{"Book flights", "Conference travel", "high", 5},
+	} {
+		payload := `{"title": "` + task.title + `", "description": "` + task.description + `", "due_date": "` + getFutureDate(task.days) + `", "priority": "` + task.priority + `"}`
+		require.Equal(t, http.StatusCreated, request(http.MethodPost, "/tasks", payload, nil).Code)
+	}
+	request(http.MethodPut, "/tasks/2/start", "", nil)
+	request(http.MethodPut, "/tasks/4/complete", "", nil)
+
+	titles := func(query string, headers map[string]string) []string {
+		resp := request(http.MethodGet, "/tasks?"+query, "", headers)
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+		var tasks []Task
+		err := json.Unmarshal(resp.Body.Bytes(), &tasks)
+		require.NoError(t, err)
+		titles := []string{}
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+
+	t.Run("Sorted by due date by default", func(t *testing.T) {
+		require.Equal(t, []string{"Review pull request", "Book flights", "Write report", "Plan sprint"}, titles("", nil))
+	})
+
+	t.Run("Filter by status and priority", func(t *testing.T) {
+		require.Equal(t, []string{"Review pull request", "Plan sprint"}, titles("status=pending,in_progress&priority=low,medium", nil))
+		require.Equal(t, []string{"Book flights", "Write report"}, titles("priority=high", nil))
+	})
+
+	t.Run("Filter by due date", func(t *testing.T) {
+		query := "due_after=" + getFutureDate(3) + "&due_before=" + getFutureDate(20)
+		require.Equal(t, []string{"Book flights", "Write report"}, titles(query, nil))
+	})
+
+	t.Run("Only overdue tasks", func(t *testing.T) {
+		require.Empty(t, titles("overdue=true", nil))
+
+		later := map[string]string{"X-PlayPI-Now": getFutureDate(12)}
+		require.Equal(t, []string{"Review pull request", "Write report"}, titles("overdue=true", later))
+	})
+
+	t.Run("Search the title and description", func(t *testing.T) {
+		require.Equal(t, []string{"Review pull request", "Write report"}, titles("q=REPORT", nil))
+	})
+
+	t.Run("Sort by several keys", func(t *testing.T) {
+		require.Equal(t, []string{"Write report", "Book flights", "Review pull request", "Plan sprint"}, titles("sort=-priority,-due_date", nil))
+		require.Equal(t, []string{"Book flights", "Plan sprint", "Review pull request", "Write report"}, titles("sort=title", nil))
+		require.Equal(t, []string{"Write report", "Plan sprint", "Review pull request", "Book flights"}, titles("sort=status,id", nil))
+	})
+
+	t.Run("Paginate tasks", func(t *testing.T) {
+		resp := request(http.MethodGet, "/tasks?sort=id&limit=2&offset=1", "", nil)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, "4", resp.Header().Get(pagination.HeaderTotalCount))
+		require.Equal(t, []string{"Review pull request", "Plan sprint"}, titles("sort=id&limit=2&offset=1", nil))
+		require.Empty(t, titles("offset=10", nil))
+	})
+
+	validationErrors := map[string]string{
+		"status=done":            "status must be one of: pending, in_progress, blocked, completed, archived",
+		"priority=urgent":        "priority must be one of: low, medium, high",
+		"due_before=tomorrow":    "due_before must follow the format YYYY-MM-DD",
+		"due_after=2030-13-01":   "due_after must follow the format YYYY-MM-DD",
+		"overdue=maybe":          "overdue must be true or false",
+		"sort=-colour":           "unknown sort key colour: tasks can be sorted by id, title, due_date, priority, status, created_at",
+		"limit=-1":               "limit must be a positive number",
+		"offset=first":           "offset must be a positive number",
+		"include_deleted=always": "include_deleted must be true or false",
+	}
+	for query, message := range validationErrors {
+		t.Run("Validation Error - "+message, func(t *testing.T) {
+			resp := request(http.MethodGet, "/tasks?"+query, "", nil)
+
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+
+			var response map[string]string
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			require.NoError(t, err)
+			require.Equal(t, message, response["error"])
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurringTasks(t *testing.T) {
+	r := setupTestServer()
+
+	request := func(method, url string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(resp *httptest.ResponseRecorder, target interface{}) {
+		err := json.Unmarshal(resp.Body.Bytes(), target)
+		require.NoError(t, err, resp.Body.String())
+	}
+	create := func(dueDate, rrule string) Task {
+		payload := `{"title": "Recurring", "due_date": "` + dueDate + `", "priority": "medium", "rrule": "` + rrule + `"}`
+		resp := request(http.MethodPost, "/tasks", payload)
+		require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+		var task Task
+		decode(resp, &task)
+		return task
+	}
+	dueDates := func(id int, limit int) []string {
+		resp := request(http.MethodGet, "/tasks/"+strconv.Itoa(id)+"/occurrences?limit="+strconv.Itoa(limit), "")
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		var occurrences []Occurrence
+		decode(resp, &occurrences)
+		dates := []string{}
+		for i, occurrence := range occurrences {
+			require.Equal(t, i+1, occurrence.Number)
+			dates = append(dates, occurrence.DueDate)
+		}
+		return dates
+	}
+
+	t.Run("Complete an occurrence", func(t *testing.T) {
+		task := create("2099-01-05", "rrule:freq=weekly;byday=we,mo")
+		require.Equal(t, "FREQ=WEEKLY;BYDAY=WE,MO", task.RRule)
+		require.Equal(t, &Recurrence{SeriesStart: "2099-01-05", Number: 1}, task.Recurrence)
+
+		resp := request(http.MethodPut, "/tasks/"+strconv.Itoa(task.ID)+"/complete", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		decode(resp, &task)
+		require.NotNil(t, task.Recurrence.NextID)
+
+		resp = request(http.MethodGet, "/tasks/"+strconv.Itoa(*task.Recurrence.NextID), "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var next Task
+		decode(resp, &next)
+		require.Equal(t, "2099-01-07", next.DueDate)
+		require.Equal(t, StatusPending, next.Status)
+		require.Equal(t, task.RRule, next.RRule)
+		require.Equal(t, 2, next.Recurrence.Number)
+		require.Equal(t, task.ID, *next.Recurrence.PreviousID)
+
+		// Completing a reopened occurrence does not create its next occurrence again
+		require.Equal(t, http.StatusOK, request(http.MethodPut, "/tasks/"+strconv.Itoa(task.ID)+"/reopen", "").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodPut, "/tasks/"+strconv.Itoa(task.ID)+"/complete", "").Code)
+		var tasks []Task
+		decode(request(http.MethodGet, "/tasks", ""), &tasks)
+		require.Len(t, tasks, 2)
+	})
+
+	t.Run("Returned tasks do not share their recurrence", func(t *testing.T) {
+		task := create("2099-02-02", "FREQ=DAILY")
+		fetched, err := defaultSandbox().GetTaskByID(task.ID, false)
+		require.NoError(t, err)
+
+		_, err = defaultSandbox().MarkTaskAsCompleted(task.ID)
+		require.NoError(t, err)
+		require.Nil(t, fetched.Recurrence.NextID, "a task returned earlier must not change under its reader")
+	})
+
+	t.Run("List upcoming occurrences", func(t *testing.T) {
+		occurrences := []struct {
+			name, dueDate, rrule string
+			expected             []string
+		}{
+			{"Every other day", "2099-01-30", "FREQ=DAILY;INTERVAL=2", []string{"2099-01-30", "2099-02-01", "2099-02-03"}},
+			{"Weekly on two days", "2099-01-05", "FREQ=WEEKLY;BYDAY=MO,WE", []string{"2099-01-05", "2099-01-07", "2099-01-12", "2099-01-14"}},
+			{"The 31st skips shorter months", "2099-01-31", "FREQ=MONTHLY", []string{"2099-01-31", "2099-03-31", "2099-05-31"}},
+			{"The last day of the month", "2099-01-31", "FREQ=MONTHLY;BYMONTHDAY=-1", []string{"2099-01-31", "2099-02-28", "2099-03-31", "2099-04-30"}},
+			{"The last Friday of the month", "2099-01-30", "FREQ=MONTHLY;BYDAY=-1FR", []string{"2099-01-30", "2099-02-27", "2099-03-27", "2099-04-24"}},
+			{"The 29th of February skips 2100", "2096-02-29", "FREQ=YEARLY", []string{"2096-02-29", "2104-02-29", "2108-02-29"}},
+			{"Series end after COUNT occurrences", "2099-01-01", "FREQ=DAILY;COUNT=3", []string{"2099-01-01", "2099-01-02", "2099-01-03"}},
+			{"Series end on UNTIL", "2099-01-01", "FREQ=WEEKLY;UNTIL=20990115", []string{"2099-01-01", "2099-01-08", "2099-01-15"}},
+		}
+		for _, test := range occurrences {
+			t.Run(test.name, func(t *testing.T) {
+				task := create(test.dueDate, test.rrule)
+				require.Equal(t, test.expected, dueDates(task.ID, len(test.expected)+1)[:len(test.expected)])
+			})
+		}
+
+		task := create("2099-01-01", "FREQ=DAILY;COUNT=2")
+		require.Equal(t, []string{"2099-01-01", "2099-01-02"}, dueDates(task.ID, 10))
+		resp := request(http.MethodPut, "/tasks/"+strconv.Itoa(task.ID)+"/complete", "")
+		decode(resp, &task)
+		next := *task.Recurrence.NextID
+		resp = request(http.MethodPut, "/tasks/"+strconv.Itoa(next)+"/complete", "")
+		var last Task
+		decode(resp, &last)
+		require.Nil(t, last.Recurrence.NextID, "the series ends after its last occurrence")
+	})
+
+	t.Run("Change the rule", func(t *testing.T) {
+		task := create("2099-01-31", "FREQ=MONTHLY")
+		payload := `{"title": "Recurring", "due_date": "2099-02-10", "priority": "medium", "rrule": "FREQ=DAILY"}`
+		resp := request(http.MethodPut, "/tasks/"+strconv.Itoa(task.ID), payload)
+		require.Equal(t, http.StatusOK, resp.Code)
+		decode(resp, &task)
+		require.Equal(t, "2099-02-10", task.Recurrence.SeriesStart)
+
+		payload = `{"title": "Recurring", "due_date": "2099-02-10", "priority": "medium"}`
+		resp = request(http.MethodPut, "/tasks/"+strconv.Itoa(task.ID), payload)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NotContains(t, resp.Body.String(), "rrule")
+		require.NotContains(t, resp.Body.String(), "recurrence")
+	})
+
+	validationErrors := []struct {
+		rrule   string
+		message string
+	}{
+		{"FREQ=HOURLY", "rrule FREQ must be one of: DAILY, WEEKLY, MONTHLY, YEARLY"},
+		{"INTERVAL=2", "rrule FREQ must be one of: DAILY, WEEKLY, MONTHLY, YEARLY"},
+		{"FREQ=DAILY;INTERVAL=0", "rrule INTERVAL must be a positive integer"},
+		{"FREQ=DAILY;COUNT=2;UNTIL=20991231", "rrule cannot have both COUNT and UNTIL"},
+		{"FREQ=WEEKLY;BYDAY=1MO", "rrule BYDAY must list days"},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", "rrule BYMONTHDAY must list days between 1 and 31 or between -31 and -1"},
+		{"FREQ=YEARLY;BYDAY=MO", "rrule BYDAY in a yearly rule requires BYMONTH"},
+		{"FREQ=DAILY;BYHOUR=9", "rrule part BYHOUR is not supported"},
+		{"FREQ", "rrule must be a list of NAME=VALUE parts"},
+	}
+	for _, test := range validationErrors {
+		t.Run("Validation Error - "+test.message, func(t *testing.T) {
+			payload := `{"title": "Recurring", "due_date": "2099-01-01", "priority": "medium", "rrule": "` + test.rrule + `"}`
+			resp := request(http.MethodPost, "/tasks", payload)
+
+			require.Equal(t, http.StatusBadRequest, resp.Code)
+			require.Contains(t, resp.Body.String(), test.message)
+		})
+	}
+
+	t.Run("Occurrences of a task that does not recur", func(t *testing.T) {
+		id := createTaskForTest(t, r)
+		resp := request(http.MethodGet, "/tasks/"+strconv.Itoa(id)+"/occurrences", "")
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "task does not recur")
+
+		resp = request(http.MethodGet, "/tasks/1/occurrences?limit=101", "")
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "limit must be between 1 and 100")
+	})
+}
@@ -0,0 +1,177 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubtasksAndDependencies(t *testing.T) {
+	r := setupTestServer()
+
+	request := func(method, url string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(resp *httptest.ResponseRecorder, target interface{}) {
+		err := json.Unmarshal(resp.Body.Bytes(), target)
+		require.NoError(t, err, resp.Body.String())
+	}
+	for i := 0; i < 4; i++ {
+		createTaskForTest(t, r)
+	}
+
+	t.Run("Create a subtask", func(t *testing.T) {
+		payload := `{"title": "Subtask", "due_date": "` + getFutureDate(10) + `", "priority": "low", "parent_id": 1}`
+		resp := request(http.MethodPost, "/tasks", payload)
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		var task Task
+		decode(resp, &task)
+		require.Equal(t, 5, task.ID)
+		require.Equal(t, 1, *task.ParentID)
+
+		resp = request(http.MethodGet, "/tasks/1/subtasks", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var subtasks []Task
+		decode(resp, &subtasks)
+		require.Len(t, subtasks, 1)
+		require.Equal(t, 5, subtasks[0].ID)
+	})
+
+	t.Run("Move a subtask", func(t *testing.T) {
+		resp := request(http.MethodPut, "/tasks/2/parent", `{"parent_id": 5}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = request(http.MethodPut, "/tasks/1/parent", `{"parent_id": 2}`)
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "parent would create a cycle: task 2 is a subtask of task 1")
+
+		resp = request(http.MethodPut, "/tasks/2/parent", `{"parent_id": null}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NotContains(t, resp.Body.String(), "parent_id")
+	})
+
+	t.Run("Add dependencies", func(t *testing.T) {
+		resp := request(http.MethodPost, "/tasks/1/dependencies", `{"task_id": 2}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+		resp = request(http.MethodPost, "/tasks/2/dependencies", `{"task_id": 3}`)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var task Task
+		decode(resp, &task)
+		require.Equal(t, []int{3}, task.BlockedBy)
+
+		resp = request(http.MethodGet, "/tasks/2/dependencies", "")
+		var dependencies TaskDependencies
+		decode(resp, &dependencies)
+		require.Len(t, dependencies.BlockedBy, 1)
+		require.Equal(t, 3, dependencies.BlockedBy[0].ID)
+		require.Len(t, dependencies.Blocks, 1)
+		require.Equal(t, 1, dependencies.Blocks[0].ID)
+	})
+
+	t.Run("Conflict - Dependency cycle", func(t *testing.T) {
+		resp := request(http.MethodPost, "/tasks/3/dependencies", `{"task_id": 1}`)
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+
+		var response map[string]string
+		decode(resp, &response)
+		require.Equal(t, "dependency would create a cycle: 3 -> 1 -> 2 -> 3", response["error"])
+	})
+
+	t.Run("Conflict - Cycle through a deleted task", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, request(http.MethodDelete, "/tasks/2", "").Code)
+
+		resp := request(http.MethodPost, "/tasks/3/dependencies", `{"task_id": 1}`)
+		require.Equal(t, http.StatusConflict, resp.Code)
+
+		require.Equal(t, http.StatusOK, request(http.MethodPost, "/tasks/2/restore", "").Code)
+	})
+
+	t.Run("Blocked tasks cannot be completed", func(t *testing.T) {
+		resp := request(http.MethodPut, "/tasks/2/complete", "")
+		require.Equal(t, http.StatusConflict, resp.Code)
+
+		var response struct {
+			Error        string `json:"error"`
+			OpenBlockers []int  `json:"open_blockers"`
+		}
+		decode(resp, &response)
+		require.Equal(t, "task is blocked by open tasks: 3", response.Error)
+		require.Equal(t, []int{3}, response.OpenBlockers)
+
+		resp = request(http.MethodPut, "/tasks/2", `{"title": "Blocked", "due_date": "`+getFutureDate(30)+`", "priority": "low", "status": "completed"}`)
+		require.Equal(t, http.StatusConflict, resp.Code)
+
+		require.Equal(t, http.StatusOK, request(http.MethodPut, "/tasks/2/block", "").Code)
+		resp = request(http.MethodPut, "/tasks/2/complete", "")
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "task is blocked by open tasks: 3")
+
+		require.Equal(t, http.StatusOK, request(http.MethodPut, "/tasks/3/complete", "").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodPut, "/tasks/2/complete", "").Code)
+	})
+
+	t.Run("Get the task graph", func(t *testing.T) {
+		resp := request(http.MethodGet, "/tasks/graph", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var graph TaskGraph
+		decode(resp, &graph)
+		require.Len(t, graph.Nodes, 5)
+		require.Equal(t, []GraphEdge{
+			{From: 1, To: 5, Type: EdgeSubtask},
+			{From: 2, To: 1, Type: EdgeBlocks},
+			{From: 3, To: 2, Type: EdgeBlocks},
+		}, graph.Edges)
+
+		resp = request(http.MethodGet, "/tasks/3/graph", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		decode(resp, &graph)
+		ids := []int{}
+		for _, node := range graph.Nodes {
+			ids = append(ids, node.ID)
+		}
+		require.Equal(t, []int{1, 2, 3, 5}, ids)
+	})
+
+	t.Run("Remove a dependency", func(t *testing.T) {
+		resp := request(http.MethodDelete, "/tasks/1/dependencies/2", "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), `"blocked_by":[]`)
+
+		resp = request(http.MethodDelete, "/tasks/1/dependencies/2", "")
+		require.Equal(t, http.StatusNotFound, resp.Code)
+		require.Contains(t, resp.Body.String(), "task is not blocked by this task")
+	})
+
+	validationErrors := []struct {
+		method, url, body string
+		status            int
+		message           string
+	}{
+		{http.MethodPost, "/tasks", `{"title": "Orphan", "due_date": "` + getFutureDate(10) + `", "priority": "low", "parent_id": 99}`, http.StatusBadRequest, "parent task 99 does not exist"},
+		{http.MethodPut, "/tasks/4/parent", `{"parent_id": 4}`, http.StatusBadRequest, "a task cannot be its own parent"},
+		{http.MethodPost, "/tasks/4/dependencies", `{"task_id": 4}`, http.StatusBadRequest, "a task cannot be blocked by itself"},
+		{http.MethodPost, "/tasks/4/dependencies", `{"task_id": 99}`, http.StatusBadRequest, "task 99 does not exist"},
+		{http.MethodPost, "/tasks/2/dependencies", `{"task_id": 3}`, http.StatusConflict, "task is already blocked by this task"},
+		{http.MethodGet, "/tasks/99/graph", "", http.StatusNotFound, "task not found"},
+	}
+	for _, test := range validationErrors {
+		t.Run("Validation Error - "+test.message, func(t *testing.T) {
+			resp := request(test.method, test.url, test.body)
+
+			require.Equal(t, test.status, resp.Code)
+			require.Contains(t, resp.Body.String(), test.message)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package task_management
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abhivaikar/playpi/services/tenancy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskTenants(t *testing.T) {
+	r := setupTestServer()
+	payload := `{"title": "Tenant Task", "due_date": "` + getFutureDate(5) + `", "priority": "low"}`
+
+	t.Run("Tenants do not see each other's tasks", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(tenancy.HeaderTenantID, "alice")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusCreated, resp.Code)
+		require.Contains(t, resp.Body.String(), `"id":1`)
+
+		req, _ = http.NewRequest(http.MethodGet, "/tasks/1", nil)
+		req.Header.Set(tenancy.HeaderTenantID, "bob")
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusNotFound, resp.Code)
+		require.Equal(t, "bob", resp.Header().Get(tenancy.HeaderTenantID))
+
+		req, _ = http.NewRequest(http.MethodGet, "/tasks/1", nil)
+		req.Header.Set(tenancy.HeaderAPIKey, "not-alice")
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("Validation Error - Invalid tenant ID", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
+		req.Header.Set(tenancy.HeaderTenantID, strings.Repeat("a", 65))
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "tenant ID must be 1 to 64 letters, digits, dashes or underscores")
+	})
+}
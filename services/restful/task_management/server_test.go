@@ -9,17 +9,22 @@ import (
 	"testing"
 	"time"
 
+	"github.com/abhivaikar/playpi/services/tenancy"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 )
 
 func setupTestServer() *gin.Engine {
 	// Start the server without any pre-seeded tasks
-	tasks = []Task{}
-	taskIDCounter = 0
 	return StartServerForTesting()
 }
 
+// defaultSandbox returns the sandbox of requests that do not name a tenant
+func defaultSandbox() *sandbox {
+	t, _ := sandboxes.Get(tenancy.DefaultTenant)
+	return t.sandbox()
+}
+
 func TestCreateTask(t *testing.T) {
 	r := setupTestServer()
 
@@ -53,7 +58,7 @@ func TestCreateTask(t *testing.T) {
 	t.Run("Validation Error - Missing Title", func(t *testing.T) {
 		payload := `{
 			"description": "Task description",
-			"due_date": "2025-02-01",
+			"due_date": "` + getFutureDate(30) + `",
 			"priority": "medium"
 		}`
 		req, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
@@ -73,7 +78,7 @@ func TestCreateTask(t *testing.T) {
 		payload := `{
 			"title": "Up",
 			"description": "Task description",
-			"due_date": "2025-02-01",
+			"due_date": "` + getFutureDate(30) + `",
 			"priority": "medium"
 		}`
 		req, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
@@ -93,7 +98,7 @@ func TestCreateTask(t *testing.T) {
 		payload := `{
 			"title": "Task with Long Description",
 			"description": "` + generateLongString(501) + `",
-			"due_date": "2025-02-01",
+			"due_date": "` + getFutureDate(30) + `",
 			"priority": "medium"
 		}`
 		req, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
@@ -153,7 +158,7 @@ func TestCreateTask(t *testing.T) {
 		payload := `{
 			"title": "Task with Invalid Priority",
 			"description": "Task description",
-			"due_date": "2025-02-01",
+			"due_date": "` + getFutureDate(30) + `",
 			"priority": "urgent"
 		}`
 		req, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
@@ -191,7 +196,7 @@ func TestGetTasks(t *testing.T) {
 		payload := `{
 			"title": "Test Task",
 			"description": "This is a test task",
-			"due_date": "2025-01-31",
+			"due_date": "` + getFutureDate(30) + `",
 			"priority": "medium"
 		}`
 		reqCreate, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
@@ -208,7 +213,7 @@ func TestGetTasks(t *testing.T) {
 		var tasks []Task
 		err := json.Unmarshal(resp.Body.Bytes(), &tasks)
 		require.NoError(t, err)
-		require.Len(t, tasks, 1)
+		require.Len(t, defaultSandbox().tasks, 1)
 		require.Equal(t, "Test Task", tasks[0].Title)
 		require.Equal(t, "This is a test task", tasks[0].Description)
 	})
@@ -235,7 +240,7 @@ func TestGetTaskByID(t *testing.T) {
 		payload := `{
 			"title": "Test Task",
 			"description": "This is a test task",
-			"due_date": "2025-01-31",
+			"due_date": "` + getFutureDate(30) + `",
 			"priority": "medium"
 		}`
 		reqCreate, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
@@ -254,7 +259,7 @@ func TestGetTaskByID(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, "Test Task", task.Title)
 		require.Equal(t, "This is a test task", task.Description)
-		require.Equal(t, "2025-01-31", task.DueDate)
+		require.Equal(t, getFutureDate(30), task.DueDate)
 	})
 
 	t.Run("Get task by invalid ID", func(t *testing.T) {
@@ -262,7 +267,7 @@ func TestGetTaskByID(t *testing.T) {
 		payload := `{
 			"title": "Test Task",
 			"description": "This is a test task",
-			"due_date": "2025-01-31",
+			"due_date": "` + getFutureDate(30) + `",
 			"priority": "medium"
 		}`
 		reqCreate, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
@@ -427,7 +432,7 @@ func TestDeleteTask(t *testing.T) {
 		payload := `{
 			"title": "Task to Delete",
 			"description": "Description",
-			"due_date": "2025-02-01",
+			"due_date": "` + getFutureDate(30) + `",
 			"priority": "medium"
 		}`
 		reqCreate, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
@@ -470,7 +475,7 @@ func TestMarkTaskAsCompleted(t *testing.T) {
 		payload := `{
 			"title": "Task to Complete",
 			"description": "Description",
-			"due_date": "2025-02-01",
+			"due_date": "` + getFutureDate(30) + `",
 			"priority": "medium"
 		}`
 		reqCreate, _ := http.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(payload))
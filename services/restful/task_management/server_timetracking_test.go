@@ -0,0 +1,149 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeTracking(t *testing.T) {
+	r := setupTestServer()
+
+	request := func(method, url string, body string, user string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.Header.Set(HeaderUser, user)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(resp *httptest.ResponseRecorder, target interface{}) {
+		err := json.Unmarshal(resp.Body.Bytes(), target)
+		require.NoError(t, err, resp.Body.String())
+	}
+	require.Equal(t, http.StatusOK, request(http.MethodPost, "/admin/clock/freeze", "", "").Code)
+	require.Equal(t, http.StatusOK, request(http.MethodPost, "/admin/clock/set", `{"now": "2030-01-15T09:00:00Z"}`, "").Code)
+	for _, title := range []string{"Build the website", "Answer emails"} {
+		payload := `{"title": "` + title + `", "due_date": "2030-02-01", "priority": "medium"}`
+		require.Equal(t, http.StatusCreated, request(http.MethodPost, "/tasks", payload, "").Code)
+	}
+	require.Equal(t, http.StatusCreated, request(http.MethodPost, "/projects", `{"name": "Website"}`, "").Code)
+	require.Equal(t, http.StatusCreated, request(http.MethodPost, "/projects/1/boards", `{"name": "Launch"}`, "").Code)
+	require.Equal(t, http.StatusOK, request(http.MethodPatch, "/tasks/1/position", `{"column_id": 1}`, "").Code)
+
+	t.Run("Start and stop a timer", func(t *testing.T) {
+		resp := request(http.MethodPost, "/tasks/1/timer/start", "", "alice")
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var entry TimeEntry
+		decode(resp, &entry)
+		require.True(t, entry.Running)
+		require.Equal(t, SourceTimer, entry.Source)
+
+		resp = request(http.MethodPost, "/tasks/2/timer/start", "", "alice")
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "a timer is already running on task 1")
+		require.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/tasks/1/timer/start", "", "").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodGet, "/me/timer", "", "alice").Code)
+		require.Equal(t, http.StatusNotFound, request(http.MethodGet, "/me/timer", "", "bob").Code)
+
+		require.Equal(t, http.StatusOK, request(http.MethodPost, "/admin/clock/advance", `{"duration": "30m"}`, "").Code)
+		resp = request(http.MethodPost, "/tasks/1/timer/stop", "", "alice")
+		require.Equal(t, http.StatusOK, resp.Code)
+		decode(resp, &entry)
+		require.False(t, entry.Running)
+		require.Equal(t, int64(1800), entry.DurationSeconds)
+		require.Equal(t, http.StatusConflict, request(http.MethodPost, "/tasks/1/timer/stop", "", "alice").Code)
+
+		var task Task
+		decode(request(http.MethodGet, "/tasks/1", "", ""), &task)
+		require.Equal(t, int64(1800), task.TrackedSeconds)
+	})
+
+	t.Run("Record time entries by hand", func(t *testing.T) {
+		resp := request(http.MethodPost, "/tasks/2/time-entries", `{"start": "2030-01-15T08:00:00Z", "duration_minutes": 60, "note": "Inbox zero"}`, "alice")
+		require.Equal(t, http.StatusCreated, resp.Code)
+		resp = request(http.MethodPost, "/tasks/2/time-entries", `{"start": "2030-01-14T23:00:00Z", "end": "2030-01-15T01:00:00Z"}`, "alice")
+		require.Equal(t, http.StatusCreated, resp.Code)
+
+		for _, test := range []struct {
+			payload string
+			status  int
+			message string
+		}{
+			{`{"start": "2030-01-15T08:30:00Z", "end": "2030-01-15T09:15:00Z"}`, http.StatusConflict, "time entries cannot overlap: the entry overlaps time entry 1 on task 1"},
+			{`{"start": "2030-01-15T09:00:00Z", "duration_minutes": 60}`, http.StatusBadRequest, "time entries cannot end in the future"},
+			{`{"start": "2030-01-13T00:00:00Z", "duration_minutes": 1500}`, http.StatusBadRequest, "time entries cannot be longer than 24 hours"},
+			{`{"start": "2030-01-13T00:00:00Z", "end": "2030-01-13T01:00:00Z", "duration_minutes": 60}`, http.StatusBadRequest, "time entries need either an end or a duration_minutes, but not both"},
+			{`{"start": "2030-01-13T01:00:00Z", "end": "2030-01-13T00:00:00Z"}`, http.StatusBadRequest, "end must be after start"},
+			{`{"duration_minutes": 60}`, http.StatusBadRequest, "start is required"},
+		} {
+			resp := request(http.MethodPost, "/tasks/2/time-entries", test.payload, "alice")
+			require.Equal(t, test.status, resp.Code, test.payload)
+			require.Contains(t, resp.Body.String(), test.message)
+		}
+		require.Equal(t, http.StatusCreated, request(http.MethodPost, "/tasks/2/time-entries", `{"start": "2030-01-15T08:30:00Z", "end": "2030-01-15T09:15:00Z"}`, "bob").Code)
+
+		require.Equal(t, http.StatusForbidden, request(http.MethodPut, "/tasks/2/time-entries/2", `{"start": "2030-01-15T07:00:00Z", "duration_minutes": 60}`, "bob").Code)
+		resp = request(http.MethodPut, "/tasks/2/time-entries/2", `{"start": "2030-01-15T07:00:00Z", "duration_minutes": 60, "note": "Inbox"}`, "alice")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, http.StatusOK, request(http.MethodDelete, "/tasks/2/time-entries/4", "", "bob").Code)
+
+		var entries []TimeEntry
+		decode(request(http.MethodGet, "/tasks/2/time-entries", "", ""), &entries)
+		require.Len(t, entries, 2)
+		require.Equal(t, 3, entries[0].ID)
+		var task Task
+		decode(request(http.MethodGet, "/tasks/2", "", ""), &task)
+		require.Equal(t, int64(3*3600), task.TrackedSeconds)
+	})
+
+	t.Run("Report the time per task, project and day", func(t *testing.T) {
+		var report TimeReport
+		decode(request(http.MethodGet, "/reports/time", "", ""), &report)
+		require.Equal(t, GroupByTask, report.GroupBy)
+		require.Equal(t, int64(1800+3*3600), report.TotalSeconds)
+		require.Len(t, report.Groups, 2)
+		require.Equal(t, "Answer emails", report.Groups[1].Name)
+		require.Equal(t, int64(3*3600), report.Groups[1].Seconds)
+		require.Equal(t, 2, report.Groups[1].Entries)
+
+		decode(request(http.MethodGet, "/reports/time?group_by=project", "", ""), &report)
+		require.Len(t, report.Groups, 2)
+		require.Equal(t, "Website", report.Groups[0].Name)
+		require.Equal(t, int64(1800), report.Groups[0].Seconds)
+		require.Nil(t, report.Groups[1].ProjectID)
+
+		decode(request(http.MethodGet, "/reports/time?group_by=day", "", ""), &report)
+		require.Len(t, report.Groups, 2)
+		require.Equal(t, "2030-01-14", report.Groups[0].Day)
+		require.Equal(t, int64(3600), report.Groups[0].Seconds)
+		require.Equal(t, int64(3600+3600+1800), report.Groups[1].Seconds)
+		require.Equal(t, 3, report.Groups[1].Entries)
+
+		decode(request(http.MethodGet, "/reports/time?group_by=day&from=2030-01-15&user=alice", "", ""), &report)
+		require.Len(t, report.Groups, 1)
+		require.Equal(t, int64(3600+3600+1800), report.TotalSeconds)
+
+		require.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/reports/time?group_by=week", "", "").Code)
+		require.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/reports/time?from=2030-01-16&to=2030-01-15", "", "").Code)
+	})
+
+	t.Run("Deleting a task stops its timers", func(t *testing.T) {
+		require.Equal(t, http.StatusCreated, request(http.MethodPost, "/tasks/1/timer/start", "", "bob").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodPost, "/admin/clock/advance", `{"duration": "10m"}`, "").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodDelete, "/tasks/1", "", "").Code)
+		require.Equal(t, http.StatusNotFound, request(http.MethodGet, "/me/timer", "", "bob").Code)
+
+		var entries []TimeEntry
+		decode(request(http.MethodGet, "/tasks/1/time-entries?user=bob", "", ""), &entries)
+		require.Len(t, entries, 1)
+		require.False(t, entries[0].Running)
+		require.Equal(t, int64(600), entries[0].DurationSeconds)
+	})
+}
@@ -0,0 +1,91 @@
+package task_management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskTrash(t *testing.T) {
+	r := setupTestServer()
+	id := createTaskForTest(t, r)
+
+	request := func(method, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("Deleted tasks move to the trash", func(t *testing.T) {
+		resp := request(http.MethodDelete, "/tasks/"+strconv.Itoa(id))
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		resp = request(http.MethodGet, "/tasks/"+strconv.Itoa(id))
+		require.Equal(t, http.StatusNotFound, resp.Code)
+
+		resp = request(http.MethodGet, "/tasks/trash")
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var trash []Task
+		err := json.Unmarshal(resp.Body.Bytes(), &trash)
+		require.NoError(t, err)
+		require.Len(t, trash, 1)
+		require.NotNil(t, trash[0].DeletedAt)
+	})
+
+	t.Run("Include deleted tasks", func(t *testing.T) {
+		resp := request(http.MethodGet, "/tasks?include_deleted=true")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), "deleted_at")
+
+		resp = request(http.MethodGet, "/tasks/"+strconv.Itoa(id)+"?include_deleted=true")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Contains(t, resp.Body.String(), "deleted_at")
+
+		resp = request(http.MethodGet, "/tasks?include_deleted=yes")
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "include_deleted must be true or false")
+	})
+
+	t.Run("Restore a deleted task", func(t *testing.T) {
+		resp := request(http.MethodPost, "/tasks/"+strconv.Itoa(id)+"/restore")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NotContains(t, resp.Body.String(), "deleted_at")
+
+		resp = request(http.MethodGet, "/tasks/"+strconv.Itoa(id))
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Empty(t, defaultSandbox().GetTrash())
+	})
+
+	t.Run("Conflict - Task is not in the trash", func(t *testing.T) {
+		resp := request(http.MethodPost, "/tasks/"+strconv.Itoa(id)+"/restore")
+
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "task is not in the trash")
+	})
+
+	t.Run("Task not found", func(t *testing.T) {
+		resp := request(http.MethodPost, "/tasks/999/restore")
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("Deleted tasks are purged after the retention period", func(t *testing.T) {
+		defer func(retention time.Duration) { TrashRetention = retention }(TrashRetention)
+		TrashRetention = 0
+
+		request(http.MethodDelete, "/tasks/"+strconv.Itoa(id))
+
+		resp := request(http.MethodGet, "/tasks/trash")
+		require.Equal(t, "[]", resp.Body.String())
+
+		resp = request(http.MethodPost, "/tasks/"+strconv.Itoa(id)+"/restore")
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
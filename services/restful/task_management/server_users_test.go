@@ -0,0 +1,204 @@
+package task_management
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsersAndAuthorization(t *testing.T) {
+	r := setupTestServer()
+
+	request := func(method, url string, body string, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(resp *httptest.ResponseRecorder, target interface{}) {
+		err := json.Unmarshal(resp.Body.Bytes(), target)
+		require.NoError(t, err, resp.Body.String())
+	}
+	tokens := map[string]string{}
+	var id int
+
+	t.Run("Register users", func(t *testing.T) {
+		for _, username := range []string{"alice", "bob", "carol"} {
+			resp := request(http.MethodPost, "/users", `{"username": "`+username+`"}`, "")
+			require.Equal(t, http.StatusCreated, resp.Code)
+			var user User
+			decode(resp, &user)
+			require.Equal(t, username, user.Username)
+			require.NotEmpty(t, user.Token)
+			tokens[username] = user.Token
+		}
+		require.Equal(t, http.StatusConflict, request(http.MethodPost, "/users", `{"username": "alice"}`, "").Code)
+		require.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/users", `{"username": "not a user"}`, "").Code)
+
+		var users []User
+		decode(request(http.MethodGet, "/users", "", ""), &users)
+		require.Len(t, users, 3)
+		require.Empty(t, users[0].Token)
+	})
+
+	t.Run("Authenticate with a bearer token", func(t *testing.T) {
+		resp := request(http.MethodGet, "/me", "", tokens["alice"])
+		require.Equal(t, http.StatusOK, resp.Code)
+		var me User
+		decode(resp, &me)
+		require.Equal(t, "alice", me.Username)
+		require.Empty(t, me.Token)
+
+		require.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/me", "", "").Code)
+		resp = request(http.MethodGet, "/me", "", "not-a-token")
+		require.Equal(t, http.StatusUnauthorized, resp.Code)
+		require.NotEmpty(t, resp.Header().Get("WWW-Authenticate"))
+
+		req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens["alice"])
+		req.Header.Set(HeaderUser, "bob")
+		resp = httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("Get my tasks before any task is created", func(t *testing.T) {
+		resp := request(http.MethodGet, "/me/tasks", "", tokens["alice"])
+		require.Equal(t, http.StatusOK, resp.Code)
+		var response map[string]string
+		decode(resp, &response)
+		require.Equal(t, "no tasks created", response["message"])
+	})
+
+	t.Run("Owners and assignees", func(t *testing.T) {
+		payload := `{"title": "Owned task", "description": "Only for alice and bob", "due_date": "` + getFutureDate(10) + `", "priority": "high", "assignees": ["bob"]}`
+		resp := request(http.MethodPost, "/tasks", payload, tokens["alice"])
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var task Task
+		decode(resp, &task)
+		require.Equal(t, "alice", task.Owner)
+		require.Equal(t, []string{"bob"}, task.Assignees)
+		id = task.ID
+
+		payload = `{"title": "Unowned task", "description": "Open to everyone", "due_date": "` + getFutureDate(10) + `", "priority": "low", "assignees": ["bob"]}`
+		require.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/tasks", payload, "").Code)
+	})
+
+	t.Run("Only owners and assignees use a task", func(t *testing.T) {
+		task := "/tasks/" + strconv.Itoa(id)
+		require.Equal(t, http.StatusOK, request(http.MethodGet, task, "", tokens["bob"]).Code)
+		require.Equal(t, http.StatusNotFound, request(http.MethodGet, task, "", tokens["carol"]).Code)
+		require.Equal(t, http.StatusNotFound, request(http.MethodGet, task, "", "").Code)
+
+		var tasks []Task
+		decode(request(http.MethodGet, "/tasks?owner=alice", "", tokens["bob"]), &tasks)
+		require.Len(t, tasks, 1)
+		decode(request(http.MethodGet, "/tasks?owner=alice", "", tokens["carol"]), &tasks)
+		require.Empty(t, tasks)
+
+		payload := `{"title": "Renamed by bob", "description": "Only for alice and bob", "due_date": "` + getFutureDate(10) + `", "priority": "high"}`
+		require.Equal(t, http.StatusOK, request(http.MethodPut, task, payload, tokens["bob"]).Code)
+		require.Equal(t, http.StatusOK, request(http.MethodPut, task+"/start", "", tokens["bob"]).Code)
+
+		resp := request(http.MethodDelete, task, "", tokens["bob"])
+		require.Equal(t, http.StatusForbidden, resp.Code)
+		require.Contains(t, resp.Body.String(), "only the owner of the task can do this")
+		require.Equal(t, http.StatusForbidden, request(http.MethodPut, task+"/assignees", `{"assignees": ["bob", "carol"]}`, tokens["bob"]).Code)
+
+		resp = request(http.MethodPut, task+"/assignees", `{"assignees": ["carol", "carol"]}`, tokens["alice"])
+		require.Equal(t, http.StatusOK, resp.Code)
+		var updated Task
+		decode(resp, &updated)
+		require.Equal(t, []string{"carol"}, updated.Assignees)
+		require.Equal(t, http.StatusBadRequest, request(http.MethodPut, task+"/assignees", `{"assignees": ["dave"]}`, tokens["alice"]).Code)
+		require.Equal(t, http.StatusNotFound, request(http.MethodGet, task, "", tokens["bob"]).Code)
+		require.Equal(t, http.StatusOK, request(http.MethodGet, task, "", tokens["carol"]).Code)
+	})
+
+	t.Run("Get my tasks", func(t *testing.T) {
+		var tasks []Task
+		decode(request(http.MethodGet, "/me/tasks", "", tokens["alice"]), &tasks)
+		require.Len(t, tasks, 1)
+		decode(request(http.MethodGet, "/me/tasks?role=assignee", "", tokens["alice"]), &tasks)
+		require.Empty(t, tasks)
+		decode(request(http.MethodGet, "/me/tasks?role=assignee", "", tokens["carol"]), &tasks)
+		require.Len(t, tasks, 1)
+		decode(request(http.MethodGet, "/me/tasks", "", tokens["bob"]), &tasks)
+		require.Empty(t, tasks)
+
+		require.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/me/tasks?role=watcher", "", tokens["alice"]).Code)
+		require.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/me/tasks", "", "").Code)
+	})
+
+	t.Run("Stream only the tasks the caller can read", func(t *testing.T) {
+		server := httptest.NewServer(r)
+		defer server.Close()
+		open := `{"title": "Open task", "description": "Open to everyone", "due_date": "` + getFutureDate(10) + `", "priority": "low"}`
+		require.Equal(t, http.StatusCreated, request(http.MethodPost, "/tasks", open, "").Code)
+
+		stream := func(token string) string {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/tasks/events", nil)
+			req.Header.Set("Last-Event-ID", "0")
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			reader := bufio.NewReader(resp.Body)
+			var data []string
+			for {
+				line, err := reader.ReadString('\n')
+				require.NoError(t, err)
+				if strings.HasPrefix(line, "data: ") {
+					data = append(data, line)
+				}
+				if strings.Contains(line, `"title":"Open task"`) {
+					return strings.Join(data, "")
+				}
+			}
+		}
+		require.Contains(t, stream(tokens["alice"]), "Owned task")
+		require.Contains(t, stream(tokens["carol"]), "Renamed by bob")
+		require.NotContains(t, stream(tokens["bob"]), `"assignees":["carol"]`)
+		require.NotContains(t, stream(""), "Renamed by bob")
+		require.NotContains(t, stream(""), "Owned task")
+	})
+
+	t.Run("Answer forbidden tasks with 403", func(t *testing.T) {
+		task := "/tasks/" + strconv.Itoa(id)
+		require.Equal(t, http.StatusBadRequest, request(http.MethodPut, "/admin/authorization", `{"policy": "hidden"}`, "").Code)
+		resp := request(http.MethodPut, "/admin/authorization", `{"policy": "forbidden"}`, "")
+		require.Equal(t, http.StatusOK, resp.Code)
+		var policy AuthorizationPolicy
+		decode(request(http.MethodGet, "/admin/authorization", "", ""), &policy)
+		require.Equal(t, PolicyForbidden, policy.Policy)
+
+		require.Equal(t, http.StatusForbidden, request(http.MethodGet, task, "", tokens["bob"]).Code)
+		require.Equal(t, http.StatusForbidden, request(http.MethodGet, task+"/history", "", tokens["bob"]).Code)
+		require.Equal(t, http.StatusUnauthorized, request(http.MethodGet, task, "", "").Code)
+		require.Equal(t, http.StatusUnauthorized, request(http.MethodDelete, task, "", "").Code)
+		require.Equal(t, http.StatusOK, request(http.MethodDelete, task, "", tokens["alice"]).Code)
+	})
+
+	t.Run("Tasks without an owner are open to everyone", func(t *testing.T) {
+		other := createTaskForTest(t, r)
+		require.Equal(t, http.StatusOK, request(http.MethodGet, "/tasks/"+strconv.Itoa(other), "", tokens["carol"]).Code)
+		require.Equal(t, http.StatusOK, request(http.MethodDelete, "/tasks/"+strconv.Itoa(other), "", tokens["bob"]).Code)
+	})
+}
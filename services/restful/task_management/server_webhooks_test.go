@@ -0,0 +1,48 @@
+package task_management
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/restful/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskWebhooks(t *testing.T) {
+	r := setupTestServer()
+	receiver := &webhooks.Receiver{Secret: "playpi-test-secret"}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	request := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := request(http.MethodPost, "/webhooks", `{"url": "`+server.URL+`", "events": ["task.completed"], "secret": "playpi-test-secret"}`)
+	require.Equal(t, http.StatusCreated, resp.Code)
+
+	t.Run("Completing a task is delivered", func(t *testing.T) {
+		request(http.MethodPost, "/tasks", `{"title": "Write report", "due_date": "`+getFutureDate(3)+`", "priority": "high"}`)
+		request(http.MethodPut, "/tasks/1/complete", "")
+
+		require.Eventually(t, func() bool { return len(receiver.Received()) == 1 }, 2*time.Second, 5*time.Millisecond)
+		received := receiver.Received()[0]
+		require.Equal(t, "task.completed", received.Event)
+		require.True(t, received.SignatureValid)
+		require.Contains(t, string(received.Body), `"title":"Write report"`)
+	})
+
+	t.Run("Validation Error - Unknown event", func(t *testing.T) {
+		resp := request(http.MethodPost, "/webhooks", `{"url": "`+server.URL+`", "events": ["item.created"]}`)
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "events must only contain: task.created, task.updated, task.completed, task.deleted")
+	})
+}
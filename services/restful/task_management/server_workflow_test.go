@@ -0,0 +1,125 @@
+package task_management
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskLifecycle(t *testing.T) {
+	r := setupTestServer()
+	id := createTaskForTest(t, r)
+
+	transition := func(action string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPut, "/tasks/"+strconv.Itoa(id)+"/"+action, nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	update := func(status string) *httptest.ResponseRecorder {
+		payload := `{"title": "Lifecycle", "due_date": "` + getFutureDate(30) + `", "priority": "low", "status": "` + status + `"}`
+		req, _ := http.NewRequest(http.MethodPut, "/tasks/"+strconv.Itoa(id), bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+	decode := func(resp *httptest.ResponseRecorder) Task {
+		var task Task
+		err := json.Unmarshal(resp.Body.Bytes(), &task)
+		require.NoError(t, err)
+		return task
+	}
+
+	t.Run("Move a task through its lifecycle", func(t *testing.T) {
+		for _, step := range []struct{ action, status string }{
+			{"start", StatusInProgress},
+			{"block", StatusBlocked},
+			{"start", StatusInProgress},
+			{"complete", StatusCompleted},
+			{"archive", StatusArchived},
+			{"reopen", StatusPending},
+		} {
+			resp := transition(step.action)
+			require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+			require.Equal(t, step.status, decode(resp).Status)
+		}
+	})
+
+	t.Run("Every transition is recorded with its time", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/tasks/"+strconv.Itoa(id), nil)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		history := decode(resp).StatusHistory
+		require.Len(t, history, 6)
+		require.Equal(t, StatusChange{From: StatusPending, To: StatusInProgress, At: history[0].At}, history[0])
+		require.Equal(t, StatusChange{From: StatusArchived, To: StatusPending, At: history[5].At}, history[5])
+		require.False(t, history[0].At.IsZero())
+	})
+
+	t.Run("Change the status with an update", func(t *testing.T) {
+		resp := update(StatusInProgress)
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, StatusInProgress, decode(resp).Status)
+
+		resp = update("")
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.Equal(t, StatusInProgress, decode(resp).Status)
+		require.Len(t, decode(resp).StatusHistory, 7)
+	})
+
+	t.Run("Conflict - Transition not allowed", func(t *testing.T) {
+		resp := transition("reopen")
+		require.Equal(t, http.StatusConflict, resp.Code)
+
+		var response struct {
+			Error              string   `json:"error"`
+			AllowedTransitions []string `json:"allowed_transitions"`
+		}
+		err := json.Unmarshal(resp.Body.Bytes(), &response)
+		require.NoError(t, err)
+		require.Equal(t, "task is in_progress and cannot move to pending", response.Error)
+		require.Equal(t, []string{StatusBlocked, StatusCompleted}, response.AllowedTransitions)
+
+		resp = update(StatusArchived)
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "task is in_progress and cannot move to archived")
+	})
+
+	t.Run("Conflict - Completing a completed task", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, transition("complete").Code)
+
+		resp := transition("complete")
+		require.Equal(t, http.StatusConflict, resp.Code)
+		require.Contains(t, resp.Body.String(), "task is completed and cannot move to completed")
+	})
+
+	t.Run("Complete a blocked task", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, transition("reopen").Code)
+		require.Equal(t, http.StatusOK, transition("block").Code)
+
+		resp := transition("complete")
+		require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+		require.Equal(t, StatusCompleted, decode(resp).Status)
+	})
+
+	t.Run("Validation Error - Unknown status", func(t *testing.T) {
+		resp := update("done")
+
+		require.Equal(t, http.StatusBadRequest, resp.Code)
+		require.Contains(t, resp.Body.String(), "status must be one of: pending, in_progress, blocked, completed, archived")
+	})
+
+	t.Run("Task not found", func(t *testing.T) {
+		id = 999
+		resp := transition("start")
+
+		require.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
@@ -2,25 +2,155 @@ package task_management
 
 import (
 	"errors"
-	"sort"
+	"fmt"
+	"sync"
 	"time"
+
+	"github.com/abhivaikar/playpi/services/clock"
+	"github.com/abhivaikar/playpi/services/restful/sse"
+	"github.com/abhivaikar/playpi/services/restful/webhooks"
+	"github.com/abhivaikar/playpi/services/tenancy"
+	"github.com/gin-gonic/gin"
 )
 
 type Task struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	DueDate     string    `json:"due_date"`
-	Priority    string    `json:"priority"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	Due         bool      `json:"due"`
+	ID          int       `json:"id" xml:"id"`
+	Title       string    `json:"title" xml:"title"`
+	Description string    `json:"description" xml:"description"`
+	DueDate     string    `json:"due_date" xml:"due_date"`
+	Priority    string    `json:"priority" xml:"priority"`
+	Status      string    `json:"status" xml:"status"`
+	CreatedAt   time.Time `json:"created_at" xml:"created_at"`
+	Due         bool      `json:"due" xml:"due"`
+
+	// ParentID is the task this task is a subtask of
+	ParentID *int `json:"parent_id,omitempty" xml:"parent_id,omitempty" csv:"-"`
+	// BlockedBy lists the tasks that must be completed before this task can be
+	BlockedBy []int `json:"blocked_by" xml:"blocked_by>id" csv:"-"`
+
+	// RRule makes the task recur: completing it creates the next occurrence
+	RRule string `json:"rrule,omitempty" xml:"rrule,omitempty" csv:"-"`
+	// Recurrence places a recurring task in its series
+	Recurrence *Recurrence `json:"recurrence,omitempty" xml:"recurrence,omitempty" csv:"-"`
+
+	// BoardID, ColumnID and Position place the task on a board, Position counting from 0 at the top of the column
+	BoardID  *int `json:"board_id,omitempty" xml:"board_id,omitempty" csv:"-"`
+	ColumnID *int `json:"column_id,omitempty" xml:"column_id,omitempty" csv:"-"`
+	Position *int `json:"position,omitempty" xml:"position,omitempty" csv:"-"`
+
+	// Owner is the authenticated user who created the task. Tasks without an
+	// owner are open to every caller.
+	Owner string `json:"owner,omitempty" xml:"owner,omitempty" csv:"-"`
+	// Assignees can read and change the task like its owner
+	Assignees []string `json:"assignees" xml:"assignees>user" csv:"-"`
+
+	// TrackedSeconds totals the stopped time entries of the task
+	TrackedSeconds int64 `json:"tracked_seconds" xml:"tracked_seconds" csv:"-"`
+
+	// StatusHistory records every transition of the task, oldest first
+	StatusHistory []StatusChange `json:"status_history" xml:"status_history>change" csv:"-"`
+
+	// DeletedAt is only set on tasks in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty" xml:"deleted_at,omitempty" csv:"-"`
+}
+
+// copyTask returns a task that does not share its recurrence with the stored
+// one, which completing the task changes. Tasks leaving the sandbox are copied,
+// as they are encoded after sb.mu is released.
+func copyTask(task Task) Task {
+	if task.Recurrence != nil {
+		recurrence := *task.Recurrence
+		task.Recurrence = &recurrence
+	}
+	return task
+}
+
+var (
+	errTaskNotFound = errors.New("task not found")
+	errNoTasks      = errors.New("no tasks created")
+)
+
+// tenant is everything a tenant owns: its tasks, its trash, its projects and
+// boards, the comments, the history and the time entries of its tasks, its
+// users and its authorization policy, its webhook subscriptions, its change
+// feed and its clock
+type tenant struct {
+	mu                 sync.Mutex
+	tasks              []Task
+	trash              []Task
+	taskIDCounter      int
+	projects           []Project
+	boards             []Board
+	projectIDCounter   int
+	boardIDCounter     int
+	columnIDCounter    int
+	comments           []Comment
+	history            []Activity
+	commentIDCounter   int
+	activityIDCounter  int
+	timeEntries        []TimeEntry
+	timeEntryIDCounter int
+	users              []User
+	policy             string
+	dispatcher         *webhooks.Dispatcher
+	feed               *sse.Feed
+	pendingEvents      []event
+	clock              *clock.Clock
+}
+
+// sandbox is a tenant as seen by a request. now is the time the request
+// happens at: the time of the tenant's clock, unless the request overrides it.
+// user is the authenticated user of the request, and actor the user named by
+// its bearer token or its X-User header, if any.
+type sandbox struct {
+	*tenant
+	now   func() time.Time
+	user  string
+	actor string
 }
 
-var tasks []Task
-var taskIDCounter int
+// sandboxes holds every tenant. Tenants start without any task.
+var sandboxes = tenancy.NewRegistry(newTenant, (*tenant).close)
 
-func validateTask(task Task) error {
+func newTenant() *tenant {
+	sb := &tenant{
+		dispatcher: webhooks.NewDispatcher(webhookEvents...),
+		feed:       sse.NewFeed(),
+		clock:      clock.New(),
+		policy:     PolicyNotFound,
+	}
+	sb.dispatcher.SetClock(sb.clock.Now)
+	return sb
+}
+
+// close stops the deliveries of a discarded tenant and disconnects its clients
+func (sb *tenant) close() {
+	sb.dispatcher.Reset()
+	sb.feed.Reset()
+}
+
+// sandbox returns a view of the tenant at the time of its clock
+func (sb *tenant) sandbox() *sandbox {
+	return &sandbox{tenant: sb, now: sb.clock.Now}
+}
+
+// sandboxOf returns the sandbox of the tenant of a request, at the time set
+// by its X-PlayPI-Now header if there is one, acting as its authenticated
+// user or its X-User
+func sandboxOf(c *gin.Context) *sandbox {
+	sb := tenancy.From[*tenant](c).sandbox()
+	if override, ok := clock.Override(c); ok {
+		sb.now = func() time.Time { return override }
+	}
+	sb.user = c.GetString(userKey)
+	sb.actor = sb.user
+	if sb.actor == "" {
+		sb.actor = c.GetHeader(HeaderUser)
+	}
+	return sb
+}
+
+func validateTask(task Task, now time.Time) error {
 	if len(task.Title) < 3 || len(task.Title) > 100 {
 		return errors.New("title must be between 3 and 100 characters")
 	}
@@ -34,96 +164,206 @@ func validateTask(task Task) error {
 	if err != nil {
 		return errors.New("due date must follow the format YYYY-MM-DD")
 	}
-	if dueDate.Before(time.Now()) {
+	if dueDate.Before(now) {
 		return errors.New("due date cannot be in the past")
 	}
 	return nil
 }
 
-func CreateTask(newTask Task) (Task, error) {
-	if err := validateTask(newTask); err != nil {
+func (sb *sandbox) CreateTask(newTask Task) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	task, err := sb.createTask(newTask)
+	return copyTask(task), err
+}
+
+// createTask validates and adds a task. The caller must hold sb.mu.
+func (sb *sandbox) createTask(newTask Task) (Task, error) {
+	if err := validateTask(newTask, sb.now()); err != nil {
 		return Task{}, err
 	}
-	taskIDCounter++
-	newTask.ID = taskIDCounter
-	newTask.Status = "pending"
-	newTask.CreatedAt = time.Now()
-	tasks = append(tasks, newTask)
+	rrule, err := normalizeRRule(newTask.RRule)
+	if err != nil {
+		return Task{}, err
+	}
+	if newTask.ParentID != nil {
+		if index, err := sb.findTaskIndex(*newTask.ParentID); err != nil || !sb.canRead(sb.tasks[index]) {
+			return Task{}, fmt.Errorf("parent task %d does not exist", *newTask.ParentID)
+		}
+	}
+	assignees := []string{}
+	if len(newTask.Assignees) > 0 {
+		if sb.user == "" {
+			return Task{}, errUnownedTask
+		}
+		if assignees, err = sb.validateAssignees(newTask.Assignees); err != nil {
+			return Task{}, err
+		}
+	}
+	sb.taskIDCounter++
+	newTask.ID = sb.taskIDCounter
+	newTask.Status = StatusPending
+	newTask.BlockedBy = []int{}
+	newTask.Owner = sb.user
+	newTask.Assignees = assignees
+	newTask.RRule = rrule
+	newTask.Recurrence = nil
+	if rrule != "" {
+		newTask.Recurrence = &Recurrence{SeriesStart: newTask.DueDate, Number: 1}
+	}
+	// Tasks are put on boards with PATCH /tasks/:id/position
+	newTask.BoardID, newTask.ColumnID, newTask.Position = nil, nil, nil
+	newTask.StatusHistory = []StatusChange{}
+	newTask.TrackedSeconds = 0
+	newTask.CreatedAt = sb.now()
+	sb.tasks = append(sb.tasks, newTask)
+	sb.record(newTask.ID, ActionCreated, diffTasks(Task{}, newTask))
+	sb.emit(EventTaskCreated, newTask)
 	return newTask, nil
 }
 
-func GetTasks() ([]Task, error) {
+// GetTasks returns the tasks selected by a query, in its order. The tasks in
+// the trash are only considered when the query includes deleted tasks.
+func (sb *sandbox) GetTasks(query TaskQuery) ([]Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	tasks := append([]Task(nil), sb.tasks...)
+	if query.IncludeDeleted {
+		sb.purgeTrash()
+		tasks = append(tasks, sb.trash...)
+	}
 	if len(tasks) == 0 {
-		return nil, errors.New("no tasks created")
+		return nil, errNoTasks
 	}
 
-	for i := range tasks {
-		tasks[i].Due = isTaskDue(tasks[i].DueDate)
+	selected := []Task{}
+	now := sb.now()
+	for _, task := range tasks {
+		task.Due = isTaskDue(task.DueDate, now)
+		if sb.canRead(task) && query.matches(task) {
+			selected = append(selected, copyTask(task))
+		}
 	}
-	sort.Slice(tasks, func(i, j int) bool {
-		return tasks[i].DueDate < tasks[j].DueDate
-	})
-	return tasks, nil
+	query.sortTasks(selected)
+	return selected, nil
 }
 
-func GetTaskByID(id int) (Task, error) {
+// GetTaskByID returns a task, also looking in the trash when includeDeleted is set
+func (sb *sandbox) GetTaskByID(id int, includeDeleted bool) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	tasks := sb.tasks
+	if includeDeleted {
+		sb.purgeTrash()
+		tasks = append(append([]Task(nil), sb.tasks...), sb.trash...)
+	}
 	if len(tasks) == 0 {
-		return Task{}, errors.New("no tasks created")
+		return Task{}, errNoTasks
 	}
 
 	for _, task := range tasks {
 		if task.ID == id {
-			task.Due = isTaskDue(task.DueDate)
-			return task, nil
+			if err := sb.authorize(task, accessRead); err != nil {
+				return Task{}, err
+			}
+			task.Due = isTaskDue(task.DueDate, sb.now())
+			return copyTask(task), nil
 		}
 	}
-	return Task{}, errors.New("task not found")
+	return Task{}, errTaskNotFound
 }
 
-func UpdateTask(id int, updatedTask Task) (Task, error) {
-	for i, task := range tasks {
+// UpdateTask replaces the fields of a task. A status other than the current
+// one must be a transition the lifecycle allows; an empty status keeps it.
+func (sb *sandbox) UpdateTask(id int, updatedTask Task) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	for i := range sb.tasks {
+		task := &sb.tasks[i]
 		if task.ID == id {
-			if err := validateTask(updatedTask); err != nil {
+			if err := sb.authorize(*task, accessUpdate); err != nil {
 				return Task{}, err
 			}
-			tasks[i].Title = updatedTask.Title
-			tasks[i].Description = updatedTask.Description
-			tasks[i].DueDate = updatedTask.DueDate
-			tasks[i].Priority = updatedTask.Priority
-			tasks[i].Status = updatedTask.Status
-			return tasks[i], nil
+			if err := validateTask(updatedTask, sb.now()); err != nil {
+				return Task{}, err
+			}
+			if err := validateStatus(updatedTask.Status); err != nil {
+				return Task{}, err
+			}
+			rrule, err := normalizeRRule(updatedTask.RRule)
+			if err != nil {
+				return Task{}, err
+			}
+			statusChanged := updatedTask.Status != "" && updatedTask.Status != task.Status
+			if statusChanged {
+				if err := sb.checkMove(*task, updatedTask.Status); err != nil {
+					return Task{}, err
+				}
+			}
+			before := *task
+			task.Title = updatedTask.Title
+			task.Description = updatedTask.Description
+			task.DueDate = updatedTask.DueDate
+			task.Priority = updatedTask.Priority
+			if rrule != task.RRule {
+				// A new rule starts a new series at the current due date
+				task.RRule = rrule
+				task.Recurrence = nil
+				if rrule != "" {
+					task.Recurrence = &Recurrence{SeriesStart: task.DueDate, Number: 1}
+				}
+			}
+			if changes := diffTasks(before, *task); statusChanged || len(changes) > 0 {
+				if statusChanged {
+					changes = append(changes, FieldChange{Field: "status", Before: before.Status, After: updatedTask.Status})
+				}
+				sb.record(id, ActionUpdated, changes)
+			}
+			if statusChanged {
+				sb.moveTask(task, updatedTask.Status)
+				// Completing a recurring task may have grown sb.tasks
+				task = &sb.tasks[i]
+			} else {
+				sb.emit(EventTaskUpdated, *task)
+			}
+			task.Due = isTaskDue(task.DueDate, sb.now())
+			return copyTask(*task), nil
 		}
 	}
-	return Task{}, errors.New("task not found")
+	return Task{}, errTaskNotFound
 }
 
-func DeleteTask(id int) error {
-	for i, task := range tasks {
-		if task.ID == id {
-			tasks = append(tasks[:i], tasks[i+1:]...)
-			return nil
-		}
-	}
-	return errors.New("task not found")
-}
+// DeleteTask moves a task to the trash, where it can be restored until it is purged
+func (sb *sandbox) DeleteTask(id int) error {
+	sb.mu.Lock()
+	defer sb.unlock()
 
-func MarkTaskAsCompleted(id int) (Task, error) {
-	for i, task := range tasks {
+	for i, task := range sb.tasks {
 		if task.ID == id {
-			if task.Status == "completed" {
-				return Task{}, errors.New("task is already marked as completed")
+			if err := sb.authorize(task, accessOwner); err != nil {
+				return err
 			}
-			tasks[i].Status = "completed"
-			return tasks[i], nil
+			sb.tasks = append(sb.tasks[:i], sb.tasks[i+1:]...)
+			sb.leaveColumn(&task)
+			sb.stopTimers(&task)
+			sb.trashTask(task)
+			sb.record(id, ActionDeleted, []FieldChange{{Field: "deleted_at", After: sb.trash[len(sb.trash)-1].DeletedAt.Format(time.RFC3339)}})
+			sb.emit(EventTaskDeleted, task)
+			return nil
 		}
 	}
-	return Task{}, errors.New("task not found")
+	return errTaskNotFound
 }
 
-func isTaskDue(dueDate string) bool {
+// isTaskDue reports whether the due date of a task has passed at a time
+func isTaskDue(dueDate string, now time.Time) bool {
 	taskDueDate, err := time.Parse("2006-01-02", dueDate)
 	if err != nil {
 		return false
 	}
-	return taskDueDate.Before(time.Now())
+	return taskDueDate.Before(now)
 }
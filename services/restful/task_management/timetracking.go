@@ -0,0 +1,514 @@
+package task_management
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Sources of time entries
+const (
+	SourceTimer  = "timer"
+	SourceManual = "manual"
+)
+
+// Groupings of time reports
+const (
+	GroupByTask    = "task"
+	GroupByProject = "project"
+	GroupByDay     = "day"
+)
+
+// MaxTimeEntryDuration is the longest a manual time entry can be
+const MaxTimeEntryDuration = 24 * time.Hour
+
+// MaxTimeEntryNoteLength is the longest the note of a time entry can be, in characters
+const MaxTimeEntryNoteLength = 200
+
+var (
+	errTimeEntryNotFound  = errors.New("time entry not found")
+	errTimeEntryUser      = errors.New("time tracking needs a bearer token or an X-User header naming the user")
+	errTimeEntryAuthor    = errors.New("only the user of a time entry can change or delete it")
+	errTimerRunning       = errors.New("a timer is already running")
+	errTimerNotRunning    = errors.New("no timer is running on this task")
+	errNoTimer            = errors.New("no timer is running")
+	errTimeEntryOverlap   = errors.New("time entries cannot overlap")
+	errTimeEntryRunning   = errors.New("running timers are stopped with POST /tasks/{id}/timer/stop")
+	errTimerClosedTask    = errors.New("timers cannot be started on completed or archived tasks")
+	errTimeEntryStart     = errors.New("start is required")
+	errTimeEntryEnd       = errors.New("time entries need either an end or a duration_minutes, but not both")
+	errTimeEntryOrder     = errors.New("end must be after start")
+	errTimeEntryFuture    = errors.New("time entries cannot end in the future")
+	errTimeEntryTooLong   = fmt.Errorf("time entries cannot be longer than %d hours", int(MaxTimeEntryDuration.Hours()))
+	errTimeEntryNote      = fmt.Errorf("note cannot exceed %d characters", MaxTimeEntryNoteLength)
+	errInvalidGroupBy     = fmt.Errorf("group_by must be one of: %s, %s, %s", GroupByTask, GroupByProject, GroupByDay)
+	errInvalidReportRange = errors.New("from must not be after to")
+)
+
+// TimeEntry is time a user spent on a task, tracked with a timer or entered by hand
+type TimeEntry struct {
+	ID     int       `json:"id" xml:"id"`
+	TaskID int       `json:"task_id" xml:"task_id"`
+	User   string    `json:"user" xml:"user"`
+	Source string    `json:"source" xml:"source"`
+	Note   string    `json:"note" xml:"note"`
+	Start  time.Time `json:"start" xml:"start"`
+	// End is not set while the timer of the entry is running
+	End *time.Time `json:"end,omitempty" xml:"end,omitempty"`
+	// DurationSeconds is the time between start and end, or until now while the timer is running
+	DurationSeconds int64 `json:"duration_seconds" xml:"duration_seconds"`
+	Running         bool  `json:"running" xml:"running"`
+}
+
+// TimeEntryRequest is the payload of POST /tasks/:id/time-entries and PUT
+// /tasks/:id/time-entries/:entry_id. An entry ends at end, or duration_minutes after start.
+type TimeEntryRequest struct {
+	Start           *time.Time `json:"start" xml:"start"`
+	End             *time.Time `json:"end" xml:"end"`
+	DurationMinutes *int       `json:"duration_minutes" xml:"duration_minutes"`
+	Note            string     `json:"note" xml:"note"`
+}
+
+// TimeReportQuery selects and groups the time entries of GET /reports/time
+type TimeReportQuery struct {
+	GroupBy string
+	// From and To are inclusive bounds on the days, formatted as YYYY-MM-DD in UTC
+	From string
+	To   string
+	User string
+}
+
+// TimeReport totals the time entries selected by a query
+type TimeReport struct {
+	GroupBy      string      `json:"group_by" xml:"group_by"`
+	From         string      `json:"from,omitempty" xml:"from,omitempty"`
+	To           string      `json:"to,omitempty" xml:"to,omitempty"`
+	TotalSeconds int64       `json:"total_seconds" xml:"total_seconds"`
+	Groups       []TimeTotal `json:"groups" xml:"groups>group"`
+}
+
+// TimeTotal is the time spent on a task, on the tasks of a project, or on a
+// day. Tasks that are not on a board are totalled without a project.
+type TimeTotal struct {
+	TaskID    *int   `json:"task_id,omitempty" xml:"task_id,omitempty"`
+	ProjectID *int   `json:"project_id,omitempty" xml:"project_id,omitempty"`
+	Day       string `json:"day,omitempty" xml:"day,omitempty"`
+	// Name is the title of the task or the name of the project
+	Name    string `json:"name,omitempty" xml:"name,omitempty"`
+	Seconds int64  `json:"seconds" xml:"seconds"`
+	Entries int    `json:"entries" xml:"entries"`
+}
+
+// ParseTimeReportQuery reads the query parameters of GET /reports/time. The
+// entries are grouped by task unless group_by says otherwise.
+func ParseTimeReportQuery(values url.Values) (TimeReportQuery, error) {
+	query := TimeReportQuery{GroupBy: values.Get("group_by"), User: values.Get("user")}
+	switch query.GroupBy {
+	case "":
+		query.GroupBy = GroupByTask
+	case GroupByTask, GroupByProject, GroupByDay:
+	default:
+		return TimeReportQuery{}, errInvalidGroupBy
+	}
+	for _, bound := range []struct {
+		name  string
+		value *string
+	}{{"from", &query.From}, {"to", &query.To}} {
+		value := values.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return TimeReportQuery{}, fmt.Errorf("%s must follow the format YYYY-MM-DD", bound.name)
+		}
+		*bound.value = value
+	}
+	// Dates are formatted as YYYY-MM-DD and compare as strings
+	if query.From != "" && query.To != "" && query.From > query.To {
+		return TimeReportQuery{}, errInvalidReportRange
+	}
+	return query, nil
+}
+
+// StartTimer starts a timer on a task for the user of the request, who cannot have another timer running
+func (sb *sandbox) StartTimer(taskID int) (TimeEntry, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	task, err := sb.findTrackableTask(taskID)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	if task.Status == StatusCompleted || task.Status == StatusArchived {
+		return TimeEntry{}, errTimerClosedTask
+	}
+	if running := sb.runningTimer(sb.actor); running != nil {
+		return TimeEntry{}, fmt.Errorf("%w on task %d", errTimerRunning, running.TaskID)
+	}
+	sb.timeEntryIDCounter++
+	entry := TimeEntry{ID: sb.timeEntryIDCounter, TaskID: taskID, User: sb.actor, Source: SourceTimer, Start: sb.now()}
+	sb.timeEntries = append(sb.timeEntries, entry)
+	return sb.viewEntry(entry), nil
+}
+
+// StopTimer stops the timer of the user of the request on a task
+func (sb *sandbox) StopTimer(taskID int) (TimeEntry, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.findTrackableTask(taskID); err != nil {
+		return TimeEntry{}, err
+	}
+	running := sb.runningTimer(sb.actor)
+	if running == nil || running.TaskID != taskID {
+		return TimeEntry{}, errTimerNotRunning
+	}
+	end := sb.now()
+	running.End = &end
+	sb.updateTrackedTime(taskID)
+	return sb.viewEntry(*running), nil
+}
+
+// GetMyTimer returns the running timer of the user of the request
+func (sb *sandbox) GetMyTimer() (TimeEntry, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if sb.actor == "" {
+		return TimeEntry{}, errTimeEntryUser
+	}
+	running := sb.runningTimer(sb.actor)
+	if running == nil {
+		return TimeEntry{}, errNoTimer
+	}
+	return sb.viewEntry(*running), nil
+}
+
+// AddTimeEntry records time the user of the request spent on a task
+func (sb *sandbox) AddTimeEntry(taskID int, request TimeEntryRequest) (TimeEntry, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.findTrackableTask(taskID); err != nil {
+		return TimeEntry{}, err
+	}
+	start, end, err := sb.validateTimeEntry(request)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	if err := sb.checkOverlap(sb.actor, start, end, 0); err != nil {
+		return TimeEntry{}, err
+	}
+	sb.timeEntryIDCounter++
+	entry := TimeEntry{ID: sb.timeEntryIDCounter, TaskID: taskID, User: sb.actor, Source: SourceManual, Note: request.Note, Start: start, End: &end}
+	sb.timeEntries = append(sb.timeEntries, entry)
+	sb.updateTrackedTime(taskID)
+	return sb.viewEntry(entry), nil
+}
+
+// GetTimeEntries returns the time entries of an active or deleted task, the
+// earliest first, only those of a user when user is set
+func (sb *sandbox) GetTimeEntries(taskID int, user string) ([]TimeEntry, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	task := sb.findAnyTask(taskID)
+	if task == nil {
+		return nil, errTaskNotFound
+	}
+	if err := sb.authorize(*task, accessRead); err != nil {
+		return nil, err
+	}
+	entries := []TimeEntry{}
+	for _, entry := range sb.timeEntries {
+		if entry.TaskID == taskID && (user == "" || entry.User == user) {
+			entries = append(entries, sb.viewEntry(entry))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Start.Before(entries[j].Start) })
+	return entries, nil
+}
+
+// UpdateTimeEntry replaces the times and the note of a stopped time entry of the user of the request
+func (sb *sandbox) UpdateTimeEntry(taskID int, entryID int, request TimeEntryRequest) (TimeEntry, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	entry, err := sb.findOwnTimeEntry(taskID, entryID)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	if entry.End == nil {
+		return TimeEntry{}, errTimeEntryRunning
+	}
+	start, end, err := sb.validateTimeEntry(request)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	if err := sb.checkOverlap(sb.actor, start, end, entryID); err != nil {
+		return TimeEntry{}, err
+	}
+	entry.Start, entry.End, entry.Note = start, &end, request.Note
+	sb.updateTrackedTime(taskID)
+	return sb.viewEntry(*entry), nil
+}
+
+// DeleteTimeEntry removes a time entry of the user of the request, discarding it if its timer is running
+func (sb *sandbox) DeleteTimeEntry(taskID int, entryID int) error {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if _, err := sb.findOwnTimeEntry(taskID, entryID); err != nil {
+		return err
+	}
+	for i := range sb.timeEntries {
+		if sb.timeEntries[i].ID == entryID {
+			sb.timeEntries = append(sb.timeEntries[:i], sb.timeEntries[i+1:]...)
+			break
+		}
+	}
+	sb.updateTrackedTime(taskID)
+	return nil
+}
+
+// GetTimeReport totals the time entries of the tasks the caller can read,
+// counting running timers until now. Entries spanning several days are split
+// at midnight UTC, both to group them by day and to select them by day.
+func (sb *sandbox) GetTimeReport(query TimeReportQuery) TimeReport {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	sb.purgeTrash()
+	var from, to time.Time
+	if query.From != "" {
+		from, _ = time.Parse("2006-01-02", query.From)
+	}
+	if query.To != "" {
+		to, _ = time.Parse("2006-01-02", query.To)
+		to = to.AddDate(0, 0, 1)
+	}
+
+	report := TimeReport{GroupBy: query.GroupBy, From: query.From, To: query.To, Groups: []TimeTotal{}}
+	groups := map[string]*TimeTotal{}
+	entries := map[string]map[int]bool{}
+	var keys []string
+	for _, entry := range sb.timeEntries {
+		task := sb.findAnyTask(entry.TaskID)
+		if task == nil || !sb.canRead(*task) || (query.User != "" && entry.User != query.User) {
+			continue
+		}
+		end := sb.now()
+		if entry.End != nil {
+			end = *entry.End
+		}
+		for _, span := range splitByDay(entry.Start.UTC(), end.UTC()) {
+			if (!from.IsZero() && span.start.Before(from)) || (!to.IsZero() && !span.start.Before(to)) {
+				continue
+			}
+			var key string
+			var total TimeTotal
+			switch query.GroupBy {
+			case GroupByDay:
+				key = span.start.Format("2006-01-02")
+				total.Day = key
+			case GroupByProject:
+				key = "~"
+				if project := sb.projectOf(*task); project != nil {
+					// Sorts the projects by ID, then the tasks without a project
+					key = fmt.Sprintf("%010d", project.ID)
+					total.ProjectID, total.Name = &project.ID, project.Name
+				}
+			default:
+				key = fmt.Sprintf("%010d", task.ID)
+				total.TaskID, total.Name = &task.ID, task.Title
+			}
+			if groups[key] == nil {
+				groups[key], entries[key] = &total, map[int]bool{}
+				keys = append(keys, key)
+			}
+			seconds := int64(span.end.Sub(span.start) / time.Second)
+			groups[key].Seconds += seconds
+			report.TotalSeconds += seconds
+			entries[key][entry.ID] = true
+		}
+	}
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		groups[key].Entries = len(entries[key])
+		report.Groups = append(report.Groups, *groups[key])
+	}
+	return report
+}
+
+// timeSpan is a part of a time entry within a single day
+type timeSpan struct {
+	start, end time.Time
+}
+
+// splitByDay splits the time between start and end at every midnight
+func splitByDay(start, end time.Time) []timeSpan {
+	spans := []timeSpan{}
+	for start.Before(end) {
+		midnight := time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+		if midnight.After(end) {
+			midnight = end
+		}
+		spans = append(spans, timeSpan{start: start, end: midnight})
+		start = midnight
+	}
+	return spans
+}
+
+// validateTimeEntry returns the start and the end of a manual time entry. The caller must hold sb.mu.
+func (sb *sandbox) validateTimeEntry(request TimeEntryRequest) (time.Time, time.Time, error) {
+	if request.Start == nil {
+		return time.Time{}, time.Time{}, errTimeEntryStart
+	}
+	if (request.End == nil) == (request.DurationMinutes == nil) {
+		return time.Time{}, time.Time{}, errTimeEntryEnd
+	}
+	start := *request.Start
+	var end time.Time
+	if request.End != nil {
+		end = *request.End
+	} else {
+		end = start.Add(time.Duration(*request.DurationMinutes) * time.Minute)
+	}
+	switch {
+	case !end.After(start):
+		return time.Time{}, time.Time{}, errTimeEntryOrder
+	case end.Sub(start) > MaxTimeEntryDuration:
+		return time.Time{}, time.Time{}, errTimeEntryTooLong
+	case end.After(sb.now()):
+		return time.Time{}, time.Time{}, errTimeEntryFuture
+	case len([]rune(request.Note)) > MaxTimeEntryNoteLength:
+		return time.Time{}, time.Time{}, errTimeEntryNote
+	}
+	return start, end, nil
+}
+
+// checkOverlap checks that the time between start and end does not overlap
+// another time entry of a user, running timers lasting until now. The entry
+// being changed, if any, is left out. The caller must hold sb.mu.
+func (sb *sandbox) checkOverlap(user string, start, end time.Time, entryID int) error {
+	for _, entry := range sb.timeEntries {
+		if entry.User != user || entry.ID == entryID {
+			continue
+		}
+		entryEnd := sb.now()
+		if entry.End != nil {
+			entryEnd = *entry.End
+		}
+		if start.Before(entryEnd) && entry.Start.Before(end) {
+			return fmt.Errorf("%w: the entry overlaps time entry %d on task %d", errTimeEntryOverlap, entry.ID, entry.TaskID)
+		}
+	}
+	return nil
+}
+
+// findTrackableTask returns an active task the user of the request can track
+// time on. The caller must hold sb.mu.
+func (sb *sandbox) findTrackableTask(taskID int) (*Task, error) {
+	index, err := sb.findTaskIndex(taskID)
+	if err != nil {
+		return nil, err
+	}
+	task := &sb.tasks[index]
+	if err := sb.authorize(*task, accessUpdate); err != nil {
+		return nil, err
+	}
+	if sb.actor == "" {
+		return nil, errTimeEntryUser
+	}
+	return task, nil
+}
+
+// findOwnTimeEntry returns a time entry of an active task tracked by the user
+// of the request. The caller must hold sb.mu.
+func (sb *sandbox) findOwnTimeEntry(taskID int, entryID int) (*TimeEntry, error) {
+	index, err := sb.findTaskIndex(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := sb.authorize(sb.tasks[index], accessRead); err != nil {
+		return nil, err
+	}
+	for i := range sb.timeEntries {
+		if sb.timeEntries[i].ID == entryID && sb.timeEntries[i].TaskID == taskID {
+			if sb.actor == "" {
+				return nil, errTimeEntryUser
+			}
+			if sb.timeEntries[i].User != sb.actor {
+				return nil, errTimeEntryAuthor
+			}
+			return &sb.timeEntries[i], nil
+		}
+	}
+	return nil, errTimeEntryNotFound
+}
+
+// runningTimer returns the running timer of a user, or nil. The caller must hold sb.mu.
+func (sb *sandbox) runningTimer(user string) *TimeEntry {
+	for i := range sb.timeEntries {
+		if sb.timeEntries[i].User == user && sb.timeEntries[i].End == nil {
+			return &sb.timeEntries[i]
+		}
+	}
+	return nil
+}
+
+// stopTimers stops every timer running on a task, as when it is deleted. The caller must hold sb.mu.
+func (sb *sandbox) stopTimers(task *Task) {
+	end := sb.now()
+	for i := range sb.timeEntries {
+		if sb.timeEntries[i].TaskID == task.ID && sb.timeEntries[i].End == nil {
+			sb.timeEntries[i].End = &end
+		}
+	}
+	task.TrackedSeconds = sb.trackedSeconds(task.ID)
+}
+
+// updateTrackedTime updates the time tracked on an active task. The caller must hold sb.mu.
+func (sb *sandbox) updateTrackedTime(taskID int) {
+	if index, err := sb.findTaskIndex(taskID); err == nil {
+		sb.tasks[index].TrackedSeconds = sb.trackedSeconds(taskID)
+	}
+}
+
+// trackedSeconds totals the stopped time entries of a task. The caller must hold sb.mu.
+func (sb *sandbox) trackedSeconds(taskID int) int64 {
+	var seconds int64
+	for _, entry := range sb.timeEntries {
+		if entry.TaskID == taskID && entry.End != nil {
+			seconds += int64(entry.End.Sub(entry.Start) / time.Second)
+		}
+	}
+	return seconds
+}
+
+// projectOf returns the project of the board a task is on, or nil. The caller must hold sb.mu.
+func (sb *sandbox) projectOf(task Task) *Project {
+	if task.BoardID == nil {
+		return nil
+	}
+	board, err := sb.findBoard(*task.BoardID)
+	if err != nil {
+		return nil
+	}
+	project, _ := sb.findProject(board.ProjectID)
+	return project
+}
+
+// viewEntry returns a time entry with its duration up to date. The caller must hold sb.mu.
+func (sb *sandbox) viewEntry(entry TimeEntry) TimeEntry {
+	end := sb.now()
+	if entry.End != nil {
+		end = *entry.End
+	}
+	entry.Running = entry.End == nil
+	entry.DurationSeconds = int64(end.Sub(entry.Start) / time.Second)
+	return entry
+}
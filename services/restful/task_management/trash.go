@@ -0,0 +1,87 @@
+package task_management
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// TrashRetention is how long deleted tasks stay in the trash before they are purged
+var TrashRetention = 7 * 24 * time.Hour
+
+var errTaskNotInTrash = errors.New("task is not in the trash")
+
+// GetTrash returns the deleted tasks that have not been purged yet, the most recently deleted first
+func (sb *sandbox) GetTrash() []Task {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	sb.purgeTrash()
+	trash := sb.readable(sb.trash)
+	sort.SliceStable(trash, func(i, j int) bool { return trash[i].DeletedAt.After(*trash[j].DeletedAt) })
+	for i := range trash {
+		trash[i].Due = isTaskDue(trash[i].DueDate, sb.now())
+	}
+	return trash
+}
+
+// RestoreTask moves a deleted task back to the active tasks
+func (sb *sandbox) RestoreTask(id int) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	sb.purgeTrash()
+	for i, task := range sb.trash {
+		if task.ID == id {
+			if err := sb.authorize(task, accessOwner); err != nil {
+				return Task{}, err
+			}
+			sb.record(id, ActionRestored, []FieldChange{{Field: "deleted_at", Before: task.DeletedAt.Format(time.RFC3339)}})
+			task.DeletedAt = nil
+			sb.trash = append(sb.trash[:i], sb.trash[i+1:]...)
+			sb.rejoinColumn(&task)
+			sb.tasks = append(sb.tasks, task)
+			sb.emit(EventTaskRestored, task)
+			task.Due = isTaskDue(task.DueDate, sb.now())
+			return copyTask(task), nil
+		}
+	}
+	for _, task := range sb.tasks {
+		if task.ID == id {
+			if err := sb.authorize(task, accessRead); err != nil {
+				return Task{}, err
+			}
+			return Task{}, errTaskNotInTrash
+		}
+	}
+	return Task{}, errTaskNotFound
+}
+
+// trashTask moves a deleted task to the trash. The caller must hold sb.mu.
+func (sb *sandbox) trashTask(task Task) {
+	deletedAt := sb.now()
+	task.DeletedAt = &deletedAt
+	sb.trash = append(sb.trash, task)
+}
+
+// purgeTrash permanently removes the tasks that have been in the trash for
+// longer than TrashRetention, along with the relations to them. It goes by the
+// tenant's clock, so a request with an X-PlayPI-Now header cannot purge tasks
+// early. The caller must hold sb.mu.
+func (sb *tenant) purgeTrash() {
+	current := sb.clock.Now()
+	kept := sb.trash[:0]
+	purged := map[int]bool{}
+	for _, task := range sb.trash {
+		if current.Sub(*task.DeletedAt) < TrashRetention {
+			kept = append(kept, task)
+		} else {
+			purged[task.ID] = true
+		}
+	}
+	sb.trash = kept
+	if len(purged) > 0 {
+		sb.forgetTasks(purged)
+		sb.forgetHistory(purged)
+	}
+}
@@ -0,0 +1,322 @@
+package task_management
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/abhivaikar/playpi/services/tenancy"
+	"github.com/gin-gonic/gin"
+)
+
+// Policies for the tasks a caller is not allowed to read
+const (
+	// PolicyNotFound answers 404 Not Found for the tasks a caller cannot read,
+	// as if they did not exist, and 403 Forbidden for the tasks a caller can
+	// read but not change
+	PolicyNotFound = "not_found"
+	// PolicyForbidden answers 403 Forbidden, or 401 Unauthorized to
+	// unauthenticated callers, for every task a caller is not allowed to use
+	PolicyForbidden = "forbidden"
+)
+
+// Actions on a task that authorize checks
+const (
+	accessRead   = "read"
+	accessUpdate = "update"
+	accessOwner  = "owner"
+)
+
+// userKey is the key of the authenticated user in the gin context
+const userKey = "task_management.user"
+
+var (
+	errUserExists      = errors.New("user already exists")
+	errInvalidUsername = errors.New("username must be 1 to 64 letters, digits, dots, dashes or underscores")
+	errInvalidToken    = errors.New("invalid token")
+	errAuthRequired    = errors.New("authentication required")
+	errUserMismatch    = errors.New("X-User must match the authenticated user")
+	errInvalidPolicy   = fmt.Errorf("policy must be one of: %s, %s", PolicyNotFound, PolicyForbidden)
+	errInvalidRole     = errors.New("role must be one of: owner, assignee")
+	errUnownedTask     = errors.New("only tasks created by an authenticated user can have assignees")
+)
+
+// ForbiddenError rejects an action on a task the caller is not allowed to take
+type ForbiddenError struct {
+	Action string
+}
+
+func (e *ForbiddenError) Error() string {
+	switch e.Action {
+	case accessRead:
+		return "only the owner and the assignees of the task can read it"
+	case accessUpdate:
+		return "only the owner and the assignees of the task can change it"
+	}
+	return "only the owner of the task can do this"
+}
+
+// User is a caller of the API. Token authenticates its requests and is only returned when the user is created.
+type User struct {
+	Username  string    `json:"username" xml:"username"`
+	Token     string    `json:"token,omitempty" xml:"token,omitempty"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+}
+
+// AuthorizationPolicy is the payload of GET and PUT /admin/authorization
+type AuthorizationPolicy struct {
+	Policy string `json:"policy" xml:"policy"`
+}
+
+// AssigneesRequest is the payload of PUT /tasks/:id/assignees
+type AssigneesRequest struct {
+	Assignees []string `json:"assignees" xml:"assignees>user"`
+}
+
+// authMiddleware authenticates the requests carrying an Authorization: Bearer
+// token and rejects invalid X-User headers. An authenticated request acts as
+// its user, and its X-User header, if any, must name the same user.
+func authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.GetHeader(HeaderUser)
+		if user != "" && !validUser.MatchString(user) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "X-User must be 1 to 64 letters, digits, dots, dashes or underscores"})
+			return
+		}
+		authorization := c.GetHeader("Authorization")
+		if authorization == "" {
+			c.Next()
+			return
+		}
+		token, found := strings.CutPrefix(authorization, "Bearer ")
+		username, ok := "", false
+		if found {
+			username, ok = tenancy.From[*tenant](c).authenticate(token)
+		}
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="playpi"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": errInvalidToken.Error()})
+			return
+		}
+		if user != "" && user != username {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": errUserMismatch.Error()})
+			return
+		}
+		c.Set(userKey, username)
+		c.Next()
+	}
+}
+
+// authenticate returns the user a token belongs to
+func (sb *tenant) authenticate(token string) (string, bool) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	for _, user := range sb.users {
+		if token != "" && user.Token == token {
+			return user.Username, true
+		}
+	}
+	return "", false
+}
+
+// CreateUser registers a user and returns it with the token authenticating its requests
+func (sb *sandbox) CreateUser(username string) (User, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if !validUser.MatchString(username) {
+		return User{}, errInvalidUsername
+	}
+	if sb.findUser(username) != nil {
+		return User{}, errUserExists
+	}
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return User{}, err
+	}
+	user := User{Username: username, Token: hex.EncodeToString(secret), CreatedAt: sb.now()}
+	sb.users = append(sb.users, user)
+	return user, nil
+}
+
+// GetUsers returns the users, without their tokens, in the order they were created
+func (sb *sandbox) GetUsers() []User {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	users := []User{}
+	for _, user := range sb.users {
+		user.Token = ""
+		users = append(users, user)
+	}
+	return users
+}
+
+// GetMe returns the authenticated user of the request, without its token
+func (sb *sandbox) GetMe() (User, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if sb.user == "" {
+		return User{}, errAuthRequired
+	}
+	user := *sb.findUser(sb.user)
+	user.Token = ""
+	return user, nil
+}
+
+// GetMyTasks returns the tasks the authenticated user owns or is assigned to,
+// selected and ordered by a query. A role of owner or assignee only returns
+// the tasks the user owns, or is assigned to.
+func (sb *sandbox) GetMyTasks(role string, query TaskQuery) ([]Task, error) {
+	if sb.user == "" {
+		return nil, errAuthRequired
+	}
+	switch role {
+	case "":
+	case "owner":
+		query.Owner = sb.user
+	case "assignee":
+		query.Assignee = sb.user
+	default:
+		return nil, errInvalidRole
+	}
+
+	tasks, err := sb.GetTasks(query)
+	if err != nil {
+		return nil, err
+	}
+	mine := []Task{}
+	for _, task := range tasks {
+		if task.Owner == sb.user || containsUser(task.Assignees, sb.user) {
+			mine = append(mine, task)
+		}
+	}
+	return mine, nil
+}
+
+// GetPolicy returns how the tenant answers requests for tasks a caller is not allowed to use
+func (sb *sandbox) GetPolicy() AuthorizationPolicy {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	return AuthorizationPolicy{Policy: sb.policy}
+}
+
+// SetPolicy changes how the tenant answers requests for tasks a caller is not allowed to use
+func (sb *sandbox) SetPolicy(policy AuthorizationPolicy) (AuthorizationPolicy, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	if policy.Policy != PolicyNotFound && policy.Policy != PolicyForbidden {
+		return AuthorizationPolicy{}, errInvalidPolicy
+	}
+	sb.policy = policy.Policy
+	return policy, nil
+}
+
+// SetAssignees replaces the assignees of a task. Only its owner can change them.
+func (sb *sandbox) SetAssignees(id int, request AssigneesRequest) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	index, err := sb.findTaskIndex(id)
+	if err != nil {
+		return Task{}, err
+	}
+	task := &sb.tasks[index]
+	if err := sb.authorize(*task, accessOwner); err != nil {
+		return Task{}, err
+	}
+	if task.Owner == "" {
+		return Task{}, errUnownedTask
+	}
+	assignees, err := sb.validateAssignees(request.Assignees)
+	if err != nil {
+		return Task{}, err
+	}
+	if before, after := strings.Join(task.Assignees, ","), strings.Join(assignees, ","); before != after {
+		sb.record(id, ActionUpdated, []FieldChange{{Field: "assignees", Before: before, After: after}})
+	}
+	task.Assignees = assignees
+	sb.emit(EventTaskUpdated, *task)
+	task.Due = isTaskDue(task.DueDate, sb.now())
+	return copyTask(*task), nil
+}
+
+// validateAssignees checks that every assignee is a user and drops duplicates. The caller must hold sb.mu.
+func (sb *sandbox) validateAssignees(usernames []string) ([]string, error) {
+	assignees := []string{}
+	for _, username := range usernames {
+		if sb.findUser(username) == nil {
+			return nil, fmt.Errorf("user %s does not exist", username)
+		}
+		if !containsUser(assignees, username) {
+			assignees = append(assignees, username)
+		}
+	}
+	return assignees, nil
+}
+
+// authorize checks that the caller can take an action on a task. Tasks
+// without an owner, created by unauthenticated callers, are open to every
+// caller. Owned tasks can be read and changed by their owner and assignees,
+// and only their owner can delete them, restore them and change their
+// assignees. The caller must hold sb.mu.
+func (sb *sandbox) authorize(task Task, action string) error {
+	if task.Owner == "" {
+		return nil
+	}
+	canRead := readableBy(task, sb.user)
+	if action == accessOwner && task.Owner == sb.user || action != accessOwner && canRead {
+		return nil
+	}
+	if !canRead && sb.policy == PolicyNotFound {
+		return errTaskNotFound
+	}
+	if sb.user == "" {
+		return errAuthRequired
+	}
+	return &ForbiddenError{Action: action}
+}
+
+// readableBy reports whether a user can read a task: tasks without an owner are
+// open to everyone, the others to their owner and assignees.
+func readableBy(task Task, user string) bool {
+	return task.Owner == "" || user != "" && (task.Owner == user || containsUser(task.Assignees, user))
+}
+
+// canRead reports whether the caller can read a task. The caller must hold sb.mu.
+func (sb *sandbox) canRead(task Task) bool {
+	return sb.authorize(task, accessRead) == nil
+}
+
+// readable returns the tasks the caller can read. The caller must hold sb.mu.
+func (sb *sandbox) readable(tasks []Task) []Task {
+	visible := []Task{}
+	for _, task := range tasks {
+		if sb.canRead(task) {
+			visible = append(visible, copyTask(task))
+		}
+	}
+	return visible
+}
+
+// findUser returns a user, or nil. The caller must hold sb.mu.
+func (sb *sandbox) findUser(username string) *User {
+	for i := range sb.users {
+		if sb.users[i].Username == username {
+			return &sb.users[i]
+		}
+	}
+	return nil
+}
+
+func containsUser(usernames []string, username string) bool {
+	return indexOf(usernames, username) >= 0
+}
@@ -0,0 +1,154 @@
+package task_management
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Task statuses
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusBlocked    = "blocked"
+	StatusCompleted  = "completed"
+	StatusArchived   = "archived"
+)
+
+// statuses lists every task status in the order of the lifecycle
+var statuses = []string{StatusPending, StatusInProgress, StatusBlocked, StatusCompleted, StatusArchived}
+
+// taskTransitions lists the statuses a task can move to from each status.
+// Completed and archived tasks are reopened by moving them back to pending.
+var taskTransitions = map[string][]string{
+	StatusPending:    {StatusInProgress, StatusBlocked, StatusCompleted},
+	StatusInProgress: {StatusBlocked, StatusCompleted},
+	StatusBlocked:    {StatusInProgress, StatusCompleted},
+	StatusCompleted:  {StatusArchived, StatusPending},
+	StatusArchived:   {StatusPending},
+}
+
+var errInvalidStatus = fmt.Errorf("status must be one of: %s", strings.Join(statuses, ", "))
+
+// StatusChange records a transition of a task and when it happened
+type StatusChange struct {
+	From string    `json:"from" xml:"from"`
+	To   string    `json:"to" xml:"to"`
+	At   time.Time `json:"at" xml:"at"`
+}
+
+// TransitionError rejects a move that the lifecycle does not allow and lists
+// the statuses the task can move to instead
+type TransitionError struct {
+	From    string
+	To      string
+	Allowed []string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("task is %s and cannot move to %s", e.From, e.To)
+}
+
+// StartTask moves a pending or blocked task to in_progress
+func (sb *sandbox) StartTask(id int) (Task, error) {
+	return sb.transitionTask(id, StatusInProgress)
+}
+
+// BlockTask moves a pending or in progress task to blocked
+func (sb *sandbox) BlockTask(id int) (Task, error) {
+	return sb.transitionTask(id, StatusBlocked)
+}
+
+// MarkTaskAsCompleted moves a pending, in progress or blocked task to completed
+func (sb *sandbox) MarkTaskAsCompleted(id int) (Task, error) {
+	return sb.transitionTask(id, StatusCompleted)
+}
+
+// ArchiveTask moves a completed task to archived
+func (sb *sandbox) ArchiveTask(id int) (Task, error) {
+	return sb.transitionTask(id, StatusArchived)
+}
+
+// ReopenTask moves a completed or archived task back to pending
+func (sb *sandbox) ReopenTask(id int) (Task, error) {
+	return sb.transitionTask(id, StatusPending)
+}
+
+func (sb *sandbox) transitionTask(id int, status string) (Task, error) {
+	sb.mu.Lock()
+	defer sb.unlock()
+
+	for i := range sb.tasks {
+		if sb.tasks[i].ID == id {
+			if err := sb.authorize(sb.tasks[i], accessUpdate); err != nil {
+				return Task{}, err
+			}
+			if err := sb.checkMove(sb.tasks[i], status); err != nil {
+				return Task{}, err
+			}
+			sb.record(id, ActionUpdated, []FieldChange{{Field: "status", Before: sb.tasks[i].Status, After: status}})
+			sb.moveTask(&sb.tasks[i], status)
+			sb.tasks[i].Due = isTaskDue(sb.tasks[i].DueDate, sb.now())
+			return copyTask(sb.tasks[i]), nil
+		}
+	}
+	return Task{}, errTaskNotFound
+}
+
+// moveTask changes the status of a task, records the transition and emits
+// its events. Completing a recurring task creates its next occurrence. The
+// caller must hold sb.mu and have checked the transition, and must not use
+// pointers into sb.tasks afterwards, as it may grow.
+func (sb *sandbox) moveTask(task *Task, status string) {
+	task.StatusHistory = append(task.StatusHistory, StatusChange{From: task.Status, To: status, At: sb.now()})
+	task.Status = status
+	var next *Task
+	if status == StatusCompleted {
+		next = sb.nextOccurrence(task)
+	}
+	sb.emit(EventTaskUpdated, *task)
+	if status == StatusCompleted {
+		sb.emit(EventTaskCompleted, *task)
+	}
+	if next != nil {
+		sb.tasks = append(sb.tasks, *next)
+		sb.record(next.ID, ActionCreated, diffTasks(Task{}, *next))
+		sb.emit(EventTaskCreated, *next)
+	}
+}
+
+// checkMove checks that a task can move to a status: the lifecycle must allow
+// it, and a task cannot be completed while it is blocked by open tasks. The
+// caller must hold sb.mu.
+func (sb *sandbox) checkMove(task Task, status string) error {
+	if err := checkTransition(task.Status, status); err != nil {
+		return err
+	}
+	if status == StatusCompleted {
+		return sb.checkBlockers(task)
+	}
+	return nil
+}
+
+// checkTransition returns a TransitionError when a task cannot move between two statuses
+func checkTransition(from, to string) error {
+	for _, status := range taskTransitions[from] {
+		if status == to {
+			return nil
+		}
+	}
+	return &TransitionError{From: from, To: to, Allowed: append([]string{}, taskTransitions[from]...)}
+}
+
+// validateStatus checks the status of a task update. An empty status keeps the current one.
+func validateStatus(status string) error {
+	if status == "" {
+		return nil
+	}
+	for _, known := range statuses {
+		if status == known {
+			return nil
+		}
+	}
+	return errInvalidStatus
+}
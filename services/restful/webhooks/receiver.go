@@ -0,0 +1,81 @@
+package webhooks
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Received is a delivery that reached a Receiver
+type Received struct {
+	Event          string
+	Delivery       string
+	Timestamp      string
+	Signature      string
+	SignatureValid bool
+	Body           []byte
+	ReceivedAt     time.Time
+}
+
+// Receiver is a minimal webhook receiver for local testing. It verifies the
+// signature of every delivery when a secret is set, keeps what it received and
+// answers with Status, so that failing receivers and retries can be simulated.
+type Receiver struct {
+	Secret string
+	// Status is the status code returned to the sender. Zero means 200 OK.
+	Status int
+	// OnReceive, when set, is called for every delivery
+	OnReceive func(Received)
+
+	mu       sync.Mutex
+	received []Received
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "webhooks must be sent with POST", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "could not read the body", http.StatusBadRequest)
+		return
+	}
+	received := Received{
+		Event:      req.Header.Get(HeaderEvent),
+		Delivery:   req.Header.Get(HeaderDelivery),
+		Timestamp:  req.Header.Get(HeaderTimestamp),
+		Signature:  req.Header.Get(HeaderSignature),
+		Body:       body,
+		ReceivedAt: time.Now().UTC(),
+	}
+	received.SignatureValid = r.Secret != "" && Verify(r.Secret, received.Timestamp, body, received.Signature)
+
+	r.mu.Lock()
+	r.received = append(r.received, received)
+	status := r.Status
+	r.mu.Unlock()
+
+	if r.OnReceive != nil {
+		r.OnReceive(received)
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+}
+
+// SetStatus changes the status code returned for the next deliveries
+func (r *Receiver) SetStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Status = status
+}
+
+// Received returns the deliveries received so far, oldest first
+func (r *Receiver) Received() []Received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Received(nil), r.received...)
+}
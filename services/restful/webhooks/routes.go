@@ -0,0 +1,150 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/abhivaikar/playpi/services/negotiation"
+	"github.com/abhivaikar/playpi/services/restful/idempotency"
+	"github.com/gin-gonic/gin"
+)
+
+// Register adds the /webhooks endpoints to the router. dispatcherOf returns
+// the dispatcher serving a request, so that every tenant of a playground
+// manages its own subscriptions. Creating a subscription honours the
+// Idempotency-Key header of idempotencyKeys.
+func Register(r *gin.Engine, idempotencyKeys *idempotency.Store, dispatcherOf func(c *gin.Context) *Dispatcher) {
+	// POST /webhooks - Subscribe a URL to events
+	r.POST("/webhooks", idempotencyKeys.Middleware(), func(c *gin.Context) {
+		d := dispatcherOf(c)
+		var subscription Subscription
+		if err := negotiation.Bind(c, &subscription); err != nil {
+			negotiation.Render(c, http.StatusBadRequest, gin.H{"error": "invalid input format"})
+			return
+		}
+		created, err := d.Subscribe(subscription)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Location", fmt.Sprintf("/webhooks/%d", created.ID))
+		negotiation.Render(c, http.StatusCreated, created)
+	})
+
+	// GET /webhooks - List the subscriptions
+	r.GET("/webhooks", func(c *gin.Context) {
+		d := dispatcherOf(c)
+		negotiation.Render(c, http.StatusOK, d.Subscriptions())
+	})
+
+	// GET /webhooks/:id - Get a subscription
+	r.GET("/webhooks/:id", func(c *gin.Context) {
+		d := dispatcherOf(c)
+		id, err := parseParam(c, "id", "invalid webhook ID")
+		if err != nil {
+			return
+		}
+		subscription, err := d.Subscription(id)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, subscription)
+	})
+
+	// DELETE /webhooks/:id - Remove a subscription and its delivery log
+	r.DELETE("/webhooks/:id", func(c *gin.Context) {
+		d := dispatcherOf(c)
+		id, err := parseParam(c, "id", "invalid webhook ID")
+		if err != nil {
+			return
+		}
+		if err := d.Unsubscribe(id); err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, gin.H{"message": "webhook deleted"})
+	})
+
+	// GET /webhooks/:id/deliveries - Get the delivery log of a subscription
+	r.GET("/webhooks/:id/deliveries", func(c *gin.Context) {
+		d := dispatcherOf(c)
+		id, err := parseParam(c, "id", "invalid webhook ID")
+		if err != nil {
+			return
+		}
+		deliveries, err := d.Deliveries(id, c.Query("status"))
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, deliveries)
+	})
+
+	// GET /webhooks/:id/deliveries/:delivery_id - Get a delivery with its attempts
+	r.GET("/webhooks/:id/deliveries/:delivery_id", func(c *gin.Context) {
+		d := dispatcherOf(c)
+		id, deliveryID, err := parseDeliveryParams(c)
+		if err != nil {
+			return
+		}
+		delivery, err := d.Delivery(id, deliveryID)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		negotiation.Render(c, http.StatusOK, delivery)
+	})
+
+	// POST /webhooks/:id/deliveries/:delivery_id/redeliver - Send a delivery again
+	r.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", func(c *gin.Context) {
+		d := dispatcherOf(c)
+		id, deliveryID, err := parseDeliveryParams(c)
+		if err != nil {
+			return
+		}
+		delivery, err := d.Redeliver(id, deliveryID)
+		if err != nil {
+			negotiation.Render(c, statusForError(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Location", fmt.Sprintf("/webhooks/%d/deliveries/%d", id, deliveryID))
+		negotiation.Render(c, http.StatusAccepted, delivery)
+	})
+}
+
+// parseParam reads a numeric path parameter, rendering message as a 400 when it is invalid
+func parseParam(c *gin.Context, name string, message string) (int, error) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		negotiation.Render(c, http.StatusBadRequest, gin.H{"error": message})
+		return 0, err
+	}
+	return id, nil
+}
+
+func parseDeliveryParams(c *gin.Context) (int, int, error) {
+	id, err := parseParam(c, "id", "invalid webhook ID")
+	if err != nil {
+		return 0, 0, err
+	}
+	deliveryID, err := parseParam(c, "delivery_id", "invalid delivery ID")
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, deliveryID, nil
+}
+
+// statusForError maps the errors of the dispatcher to HTTP statuses
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, ErrSubscriptionNotFound), errors.Is(err, ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrDeliveryPending), errors.Is(err, ErrTooManySubscriptions):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
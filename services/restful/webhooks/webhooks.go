@@ -0,0 +1,496 @@
+// Package webhooks delivers the events of the RESTful playgrounds to
+// subscribed URLs. Every delivery is signed with HMAC-SHA256 using the secret
+// of its subscription, retried with exponential backoff when the receiver
+// fails, and kept in a delivery log that can be inspected and redelivered.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-PlayPI-Event"
+	HeaderDelivery  = "X-PlayPI-Delivery"
+	HeaderTimestamp = "X-PlayPI-Timestamp"
+	HeaderSignature = "X-PlayPI-Signature"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// MaxAttempts is how many times a delivery is tried before it is marked as failed
+const MaxAttempts = 5
+
+// MaxSubscriptions is the maximum number of subscriptions a playground keeps
+const MaxSubscriptions = 20
+
+// MaxDeliveries is how many deliveries are kept in the log of a subscription.
+// The oldest ones are dropped first.
+const MaxDeliveries = 1000
+
+// RetryBaseDelay is the wait before the first retry. It doubles after every failed attempt.
+var RetryBaseDelay = time.Second
+
+// AttemptTimeout is how long a receiver has to answer a delivery
+var AttemptTimeout = 5 * time.Second
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("delivery not found")
+	ErrDeliveryPending      = errors.New("delivery is still being attempted")
+	ErrTooManySubscriptions = fmt.Errorf("too many webhooks: at most %d can be registered", MaxSubscriptions)
+)
+
+// Subscription sends the listed events to a URL. Its secret is only returned
+// when the subscription is created.
+type Subscription struct {
+	ID        int       `json:"id" xml:"id"`
+	URL       string    `json:"url" xml:"url"`
+	Events    []string  `json:"events" xml:"events>event"`
+	Secret    string    `json:"secret,omitempty" xml:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+}
+
+// Attempt is a single try of a delivery
+type Attempt struct {
+	Number     int       `json:"number" xml:"number"`
+	StatusCode int       `json:"status_code,omitempty" xml:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" xml:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms" xml:"duration_ms"`
+	At         time.Time `json:"at" xml:"at"`
+}
+
+// Delivery is an event sent, or being sent, to a subscription
+type Delivery struct {
+	ID             int             `json:"id" xml:"id"`
+	SubscriptionID int             `json:"webhook_id" xml:"webhook_id"`
+	Event          string          `json:"event" xml:"event"`
+	Status         string          `json:"status" xml:"status"`
+	Payload        json.RawMessage `json:"payload" xml:"payload"`
+	Attempts       []Attempt       `json:"attempts" xml:"attempts>attempt"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty" xml:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at" xml:"created_at"`
+}
+
+// envelope is the body of a delivery
+type envelope struct {
+	ID        int         `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher keeps the subscriptions and the delivery log of a playground
+type Dispatcher struct {
+	mu                 sync.Mutex
+	events             []string
+	client             *http.Client
+	ctx                context.Context
+	cancel             context.CancelFunc
+	subscriptions      []Subscription
+	nextSubscriptionID int
+	deliveries         []*Delivery
+	nextDeliveryID     int
+	now                func() time.Time
+}
+
+// NewDispatcher creates a dispatcher that accepts subscriptions to the given event types
+func NewDispatcher(events ...string) *Dispatcher {
+	d := &Dispatcher{events: events, client: &http.Client{}, now: time.Now}
+	d.Reset()
+	return d
+}
+
+// SetClock makes the dispatcher timestamp subscriptions, deliveries, attempts
+// and signatures with now instead of the wall clock
+func (d *Dispatcher) SetClock(now func() time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.now = now
+}
+
+// Reset removes every subscription and delivery and stops pending retries
+func (d *Dispatcher) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	d.subscriptions = []Subscription{}
+	d.nextSubscriptionID = 1
+	d.deliveries = []*Delivery{}
+	d.nextDeliveryID = 1
+}
+
+// Events returns the event types that can be subscribed to
+func (d *Dispatcher) Events() []string {
+	return append([]string(nil), d.events...)
+}
+
+func (d *Dispatcher) validate(subscription Subscription) error {
+	target, err := url.Parse(subscription.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if len(subscription.Events) == 0 {
+		return errors.New("events must contain at least one event type")
+	}
+	for _, event := range subscription.Events {
+		if !contains(d.events, event) {
+			return fmt.Errorf("events must only contain: %s", strings.Join(d.events, ", "))
+		}
+	}
+	if subscription.Secret != "" && (len(subscription.Secret) < 16 || len(subscription.Secret) > 100) {
+		return errors.New("secret must be between 16 and 100 characters")
+	}
+	return nil
+}
+
+// Subscribe registers a subscription. A secret is generated when none is given.
+func (d *Dispatcher) Subscribe(subscription Subscription) (*Subscription, error) {
+	if err := d.validate(subscription); err != nil {
+		return nil, err
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = generateSecret()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.subscriptions) >= MaxSubscriptions {
+		return nil, ErrTooManySubscriptions
+	}
+	subscription.ID = d.nextSubscriptionID
+	subscription.Events = dedupe(subscription.Events)
+	subscription.CreatedAt = d.now().UTC()
+	d.nextSubscriptionID++
+	d.subscriptions = append(d.subscriptions, subscription)
+	return &subscription, nil
+}
+
+// Subscriptions returns every subscription, without its secret
+func (d *Dispatcher) Subscriptions() []Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make([]Subscription, 0, len(d.subscriptions))
+	for _, subscription := range d.subscriptions {
+		subscription.Secret = ""
+		result = append(result, subscription)
+	}
+	return result
+}
+
+// Subscription returns a single subscription, without its secret
+func (d *Dispatcher) Subscription(id int) (*Subscription, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	index, err := d.findSubscriptionIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	subscription := d.subscriptions[index]
+	subscription.Secret = ""
+	return &subscription, nil
+}
+
+// Unsubscribe removes a subscription along with its delivery log. Pending retries are dropped.
+func (d *Dispatcher) Unsubscribe(id int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	index, err := d.findSubscriptionIndex(id)
+	if err != nil {
+		return err
+	}
+	d.subscriptions = append(d.subscriptions[:index], d.subscriptions[index+1:]...)
+	kept := d.deliveries[:0]
+	for _, delivery := range d.deliveries {
+		if delivery.SubscriptionID != id {
+			kept = append(kept, delivery)
+		}
+	}
+	d.deliveries = kept
+	return nil
+}
+
+// Publish sends an event to every subscription that listens to it. Deliveries happen in the background.
+func (d *Dispatcher) Publish(event string, data interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, subscription := range d.subscriptions {
+		if !contains(subscription.Events, event) {
+			continue
+		}
+		createdAt := d.now().UTC()
+		payload, err := json.Marshal(envelope{ID: d.nextDeliveryID, Event: event, CreatedAt: createdAt, Data: data})
+		if err != nil {
+			continue
+		}
+		delivery := &Delivery{
+			ID:             d.nextDeliveryID,
+			SubscriptionID: subscription.ID,
+			Event:          event,
+			Status:         DeliveryPending,
+			Payload:        payload,
+			Attempts:       []Attempt{},
+			CreatedAt:      createdAt,
+		}
+		d.nextDeliveryID++
+		d.deliveries = append(d.deliveries, delivery)
+		d.trimDeliveries(subscription.ID)
+		go d.deliver(d.ctx, delivery)
+	}
+}
+
+// trimDeliveries drops the oldest deliveries of a subscription beyond
+// MaxDeliveries. Dropped deliveries that are still being attempted finish
+// without being logged. The caller must hold d.mu.
+func (d *Dispatcher) trimDeliveries(subscriptionID int) {
+	count := 0
+	for _, delivery := range d.deliveries {
+		if delivery.SubscriptionID == subscriptionID {
+			count++
+		}
+	}
+	if count <= MaxDeliveries {
+		return
+	}
+	kept := d.deliveries[:0]
+	for _, delivery := range d.deliveries {
+		if delivery.SubscriptionID == subscriptionID && count > MaxDeliveries {
+			count--
+			continue
+		}
+		kept = append(kept, delivery)
+	}
+	d.deliveries = kept
+}
+
+// Deliveries returns the delivery log of a subscription, optionally filtered by status, most recent last
+func (d *Dispatcher) Deliveries(subscriptionID int, status string) ([]Delivery, error) {
+	if status != "" && status != DeliveryPending && status != DeliverySucceeded && status != DeliveryFailed {
+		return nil, errors.New("status must be one of: pending, succeeded, failed")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := d.findSubscriptionIndex(subscriptionID); err != nil {
+		return nil, err
+	}
+	result := []Delivery{}
+	for _, delivery := range d.deliveries {
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			result = append(result, copyDelivery(delivery))
+		}
+	}
+	return result, nil
+}
+
+// Delivery returns a single delivery of a subscription
+func (d *Dispatcher) Delivery(subscriptionID int, id int) (*Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delivery, err := d.findDelivery(subscriptionID, id)
+	if err != nil {
+		return nil, err
+	}
+	copied := copyDelivery(delivery)
+	return &copied, nil
+}
+
+// Redeliver sends a finished delivery again with the same payload and delivery ID, starting a new round of attempts
+func (d *Dispatcher) Redeliver(subscriptionID int, id int) (*Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delivery, err := d.findDelivery(subscriptionID, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == DeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+	delivery.Status = DeliveryPending
+	delivery.NextAttemptAt = nil
+	go d.deliver(d.ctx, delivery)
+	copied := copyDelivery(delivery)
+	return &copied, nil
+}
+
+// deliver attempts a delivery until the receiver answers with a 2xx status or
+// MaxAttempts is reached, waiting RetryBaseDelay, then twice as long, between attempts
+func (d *Dispatcher) deliver(ctx context.Context, delivery *Delivery) {
+	for attempt := 1; ; attempt++ {
+		d.mu.Lock()
+		index, err := d.findSubscriptionIndex(delivery.SubscriptionID)
+		if err != nil || ctx.Err() != nil {
+			d.mu.Unlock()
+			return
+		}
+		subscription := d.subscriptions[index]
+		payload := delivery.Payload
+		at := d.now()
+		d.mu.Unlock()
+
+		result := d.send(ctx, subscription, delivery.ID, delivery.Event, payload, at)
+
+		d.mu.Lock()
+		if ctx.Err() != nil {
+			d.mu.Unlock()
+			return
+		}
+		result.Number = len(delivery.Attempts) + 1
+		delivery.Attempts = append(delivery.Attempts, result)
+		if result.Error == "" {
+			delivery.Status = DeliverySucceeded
+			delivery.NextAttemptAt = nil
+			d.mu.Unlock()
+			return
+		}
+		if attempt >= MaxAttempts {
+			delivery.Status = DeliveryFailed
+			delivery.NextAttemptAt = nil
+			d.mu.Unlock()
+			return
+		}
+		delay := RetryBaseDelay << (attempt - 1)
+		next := d.now().UTC().Add(delay)
+		delivery.NextAttemptAt = &next
+		d.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// send makes a single signed request to the receiver, timestamped at
+func (d *Dispatcher) send(ctx context.Context, subscription Subscription, id int, event string, payload []byte, at time.Time) Attempt {
+	started := time.Now()
+	result := Attempt{At: at.UTC()}
+
+	ctx, cancel := context.WithTimeout(ctx, AttemptTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PlayPI-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(id))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	result.DurationMS = time.Since(started).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result.Error = fmt.Sprintf("receiver answered with status %d", resp.StatusCode)
+	}
+	return result
+}
+
+// Sign computes the X-PlayPI-Signature header: the hex encoded HMAC-SHA256 of
+// the timestamp, a dot and the body, keyed with the subscription secret
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of the timestamp and body
+func Verify(secret string, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+func (d *Dispatcher) findSubscriptionIndex(id int) (int, error) {
+	for i, subscription := range d.subscriptions {
+		if subscription.ID == id {
+			return i, nil
+		}
+	}
+	return -1, ErrSubscriptionNotFound
+}
+
+func (d *Dispatcher) findDelivery(subscriptionID int, id int) (*Delivery, error) {
+	if _, err := d.findSubscriptionIndex(subscriptionID); err != nil {
+		return nil, err
+	}
+	for _, delivery := range d.deliveries {
+		if delivery.ID == id && delivery.SubscriptionID == subscriptionID {
+			return delivery, nil
+		}
+	}
+	return nil, ErrDeliveryNotFound
+}
+
+// copyDelivery copies a delivery so that it can be read after the lock is released
+func copyDelivery(delivery *Delivery) Delivery {
+	copied := *delivery
+	copied.Attempts = append([]Attempt{}, delivery.Attempts...)
+	return copied
+}
+
+func generateSecret() string {
+	secret := make([]byte, 24)
+	rand.Read(secret)
+	return "whsec_" + hex.EncodeToString(secret)
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(values []string) []string {
+	result := []string{}
+	for _, value := range values {
+		if !contains(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/abhivaikar/playpi/services/negotiation"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/metadata"
+)
+
+// Keys of the tenant and its sandbox in the gin context
+const (
+	tenantKey  = "tenancy.tenant"
+	sandboxKey = "tenancy.sandbox"
+)
+
+// FromRequest resolves the tenant of an HTTP request from its X-Tenant-ID or X-API-Key header
+func FromRequest(r *http.Request) (string, error) {
+	return Resolve(r.Header.Get(HeaderTenantID), r.Header.Get(HeaderAPIKey))
+}
+
+// FromContext resolves the tenant of a gRPC call from its x-tenant-id or x-api-key metadata
+func FromContext(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(strings.ToLower(key)); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	return Resolve(first(HeaderTenantID), first(HeaderAPIKey))
+}
+
+// StatusForError returns the HTTP status for an error of Resolve or Registry.Get
+func StatusForError(err error) int {
+	if errors.Is(err, ErrTooManyTenants) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+// Middleware resolves the tenant of every request and makes its sandbox
+// available to the handlers with From. The tenant is echoed in the
+// X-Tenant-ID response header.
+func Middleware[T any](registry *Registry[T]) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, err := FromRequest(c.Request)
+		if err != nil {
+			reject(c, err)
+			return
+		}
+		state, err := registry.Get(tenant)
+		if err != nil {
+			reject(c, err)
+			return
+		}
+		c.Set(tenantKey, tenant)
+		c.Set(sandboxKey, state)
+		c.Header(HeaderTenantID, tenant)
+		c.Next()
+	}
+}
+
+func reject(c *gin.Context, err error) {
+	c.Abort()
+	negotiation.Render(c, StatusForError(err), gin.H{"error": err.Error()})
+}
+
+// From returns the sandbox of the tenant of a request that went through Middleware
+func From[T any](c *gin.Context) T {
+	return c.MustGet(sandboxKey).(T)
+}
+
+// ID returns the tenant of a request that went through Middleware
+func ID(c *gin.Context) string {
+	return c.GetString(tenantKey)
+}
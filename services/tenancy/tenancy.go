@@ -0,0 +1,134 @@
+// Package tenancy gives every user of a shared PlayPI instance their own
+// sandbox. Requests name their tenant with the X-Tenant-ID header (x-tenant-id
+// metadata for gRPC) or an API key; the first request of a tenant creates a
+// freshly seeded sandbox, and sandboxes that stay idle are discarded.
+// Requests without a tenant share the default sandbox.
+package tenancy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Request headers naming the tenant. gRPC uses the lowercase equivalents as metadata keys.
+const (
+	HeaderTenantID = "X-Tenant-ID"
+	HeaderAPIKey   = "X-API-Key"
+)
+
+// DefaultTenant owns the sandbox of requests that do not name a tenant. It is never discarded.
+const DefaultTenant = "default"
+
+// MaxTenants is how many tenants, besides the default one, can have a sandbox at the same time
+var MaxTenants = 100
+
+// IdleTimeout is how long a sandbox is kept after the last request of its tenant
+var IdleTimeout = 30 * time.Minute
+
+var (
+	ErrInvalidTenantID = errors.New("tenant ID must be 1 to 64 letters, digits, dashes or underscores")
+	ErrTooManyTenants  = errors.New("too many tenants")
+)
+
+var validTenantID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Resolve returns the tenant named by a tenant ID or, when there is none, by
+// an API key. The key itself is never used as the tenant ID, only a digest of it.
+func Resolve(tenantID, apiKey string) (string, error) {
+	switch {
+	case tenantID != "":
+		if !validTenantID.MatchString(tenantID) {
+			return "", ErrInvalidTenantID
+		}
+		return tenantID, nil
+	case apiKey != "":
+		digest := sha256.Sum256([]byte(apiKey))
+		return "key-" + hex.EncodeToString(digest[:8]), nil
+	}
+	return DefaultTenant, nil
+}
+
+// sandbox is the state of a tenant and the last time it was used
+type sandbox[T any] struct {
+	state    T
+	lastUsed time.Time
+}
+
+// Registry creates, keeps and discards the sandboxes of the tenants
+type Registry[T any] struct {
+	mu        sync.Mutex
+	create    func() T
+	discard   func(T)
+	sandboxes map[string]*sandbox[T]
+	now       func() time.Time
+}
+
+// NewRegistry creates a registry building sandboxes with create. discard,
+// when not nil, is called for every sandbox that is discarded, so that it can
+// stop its background work.
+func NewRegistry[T any](create func() T, discard func(T)) *Registry[T] {
+	return &Registry[T]{
+		create:    create,
+		discard:   discard,
+		sandboxes: make(map[string]*sandbox[T]),
+		now:       time.Now,
+	}
+}
+
+// Get returns the sandbox of a tenant, creating it on first use
+func (r *Registry[T]) Get(tenant string) (T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.expire()
+	existing, ok := r.sandboxes[tenant]
+	if !ok {
+		if tenant != DefaultTenant && r.countTenants() >= MaxTenants {
+			var zero T
+			return zero, fmt.Errorf("%w: at most %d can be active at the same time", ErrTooManyTenants, MaxTenants)
+		}
+		existing = &sandbox[T]{state: r.create()}
+		r.sandboxes[tenant] = existing
+	}
+	existing.lastUsed = r.now()
+	return existing.state, nil
+}
+
+// Reset discards every sandbox, including the default one
+func (r *Registry[T]) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tenant, existing := range r.sandboxes {
+		r.remove(tenant, existing)
+	}
+}
+
+// expire discards the sandboxes that have been idle for longer than IdleTimeout. The caller must hold r.mu.
+func (r *Registry[T]) expire() {
+	for tenant, existing := range r.sandboxes {
+		if tenant != DefaultTenant && r.now().Sub(existing.lastUsed) > IdleTimeout {
+			r.remove(tenant, existing)
+		}
+	}
+}
+
+func (r *Registry[T]) remove(tenant string, existing *sandbox[T]) {
+	delete(r.sandboxes, tenant)
+	if r.discard != nil {
+		r.discard(existing.state)
+	}
+}
+
+func (r *Registry[T]) countTenants() int {
+	count := len(r.sandboxes)
+	if _, ok := r.sandboxes[DefaultTenant]; ok {
+		count--
+	}
+	return count
+}